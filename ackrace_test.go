@@ -0,0 +1,30 @@
+package timeoutqueue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPTOAckWhileExpiring(t *testing.T) {
+	probes := 0
+	pm := NewPTOManager(0, func(interface{}, int) { probes++ })
+	p := pm.Arm("path", time.Hour)
+	fired := p.gen
+	// the PTO fires but before expired takes the lock the path is acked
+	assert.True(t, p.Ack())
+	acked := p.handle
+	p.expired(fired)
+	assert.Equal(t, 0, probes)
+	assert.Equal(t, 0, p.Probes())
+	assert.True(t, acked == p.handle, "the Ack's PTO was replaced")
+
+	// closing cancels the only PTO that is armed
+	assert.True(t, p.Close())
+	for _, tq := range pm.queues.queues {
+		if tq != nil {
+			assert.Equal(t, 0, tq.Len())
+		}
+	}
+}
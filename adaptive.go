@@ -0,0 +1,158 @@
+package timeoutqueue
+
+import (
+	"sync"
+	"time"
+)
+
+// rto is the retransmission timeout estimator from RFC 6298.
+type rto struct {
+	srtt, rttvar time.Duration
+	sampled      bool
+}
+
+func (e *rto) add(sample time.Duration) {
+	if !e.sampled {
+		e.srtt = sample
+		e.rttvar = sample / 2
+		e.sampled = true
+		return
+	}
+	diff := e.srtt - sample
+	if diff < 0 {
+		diff = -diff
+	}
+	// alpha is 1/8 and beta is 1/4
+	e.rttvar += (diff - e.rttvar) / 4
+	e.srtt += (sample - e.srtt) / 8
+}
+
+// timeout returns the estimated timeout kept between min and max, a max of zero
+// or less means there is no upper limit.
+func (e *rto) timeout(min, max time.Duration) time.Duration {
+	timeout := e.srtt + 4*e.rttvar
+	if timeout < min {
+		timeout = min
+	}
+	if max > 0 && timeout > max {
+		timeout = max
+	}
+	return timeout
+}
+
+type adaptive struct {
+	enabled  bool
+	min, max time.Duration
+	rto      rto
+}
+
+// SetAdaptive makes the queue tune it's own timeout from the latencies reported
+// to ObserveLatency, using the retransmission timeout estimator from TCP. The
+// timeout is kept between min and max, a max of zero or less means there is no
+// upper limit. As with SetTimeoutForNew, only actions added after the timeout
+// changes use it.
+func (tq *TimeoutQueue) SetAdaptive(min, max time.Duration) {
+	tq.mux.Lock()
+	tq.adaptive = adaptive{
+		enabled: true,
+		min:     min,
+		max:     max,
+	}
+	tq.mux.Unlock()
+}
+
+// ObserveLatency reports a latency sample, such as a measured round trip time.
+// It does nothing unless SetAdaptive has been called.
+func (tq *TimeoutQueue) ObserveLatency(d time.Duration) {
+	tq.mux.Lock()
+	a := &tq.adaptive
+	if a.enabled {
+		a.rto.add(d)
+		tq.timeout = a.rto.timeout(a.min, a.max)
+	}
+	tq.mux.Unlock()
+}
+
+// RTTEstimator estimates a timeout from round trip time samples the same way
+// SetAdaptive does, for callers that want to read the estimate or apply it to a
+// queue themselves. It is safe for concurrent use.
+type RTTEstimator struct {
+	mux       sync.Mutex
+	rto       rto
+	min, max  time.Duration
+	tq        *TimeoutQueue
+	threshold time.Duration
+	applied   time.Duration
+}
+
+// NewRTTEstimator returns an RTTEstimator with a recommended timeout kept between
+// min and max, a max of zero or less means there is no upper limit.
+func NewRTTEstimator(min, max time.Duration) *RTTEstimator {
+	return &RTTEstimator{
+		min: min,
+		max: max,
+	}
+}
+
+// Add a round trip time sample. If the estimator is bound to a queue and the
+// recommended timeout has moved more than the threshold away from the last
+// timeout applied, SetTimeout is called with the recommendation.
+func (e *RTTEstimator) Add(sample time.Duration) {
+	e.mux.Lock()
+	e.rto.add(sample)
+	tq := e.tq
+	timeout := e.rto.timeout(e.min, e.max)
+	drift := timeout - e.applied
+	if drift < 0 {
+		drift = -drift
+	}
+	if tq == nil || drift <= e.threshold {
+		e.mux.Unlock()
+		return
+	}
+	e.applied = timeout
+	e.mux.Unlock()
+	tq.SetTimeout(timeout)
+}
+
+// SRTT returns the smoothed round trip time.
+func (e *RTTEstimator) SRTT() time.Duration {
+	e.mux.Lock()
+	srtt := e.rto.srtt
+	e.mux.Unlock()
+	return srtt
+}
+
+// RTTVAR returns the round trip time variation.
+func (e *RTTEstimator) RTTVAR() time.Duration {
+	e.mux.Lock()
+	rttvar := e.rto.rttvar
+	e.mux.Unlock()
+	return rttvar
+}
+
+// Timeout returns the recommended timeout. Before any samples are added it is
+// min.
+func (e *RTTEstimator) Timeout() time.Duration {
+	e.mux.Lock()
+	timeout := e.rto.timeout(e.min, e.max)
+	e.mux.Unlock()
+	return timeout
+}
+
+// Bind the estimator to a queue. Because SetTimeout moves every pending
+// deadline, it is only called when the recommended timeout drifts more than
+// threshold from the timeout last applied, rather than on every sample. The
+// queue's current timeout is taken as the last applied. Passing nil unbinds the
+// estimator.
+func (e *RTTEstimator) Bind(tq *TimeoutQueue, threshold time.Duration) {
+	var applied time.Duration
+	if tq != nil {
+		applied = tq.Timeout()
+	}
+	e.mux.Lock()
+	e.tq = tq
+	e.threshold = threshold
+	e.applied = applied
+	e.mux.Unlock()
+}
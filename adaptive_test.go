@@ -0,0 +1,67 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptive(t *testing.T) {
+	tq := timeoutqueue.NewManual(time.Second, 10, nil)
+	tq.ObserveLatency(time.Millisecond)
+	assert.Equal(t, time.Second, tq.Timeout())
+
+	tq.SetAdaptive(time.Millisecond*200, time.Second*2)
+	// the first sample sets srtt to 100ms and rttvar to 50ms
+	tq.ObserveLatency(time.Millisecond * 100)
+	assert.Equal(t, time.Millisecond*300, tq.Timeout())
+
+	// a steady rtt decays the variance down to the minimum
+	for i := 0; i < 20; i++ {
+		tq.ObserveLatency(time.Millisecond * 100)
+	}
+	assert.Equal(t, time.Millisecond*200, tq.Timeout())
+
+	tq.ObserveLatency(time.Second * 10)
+	assert.Equal(t, time.Second*2, tq.Timeout())
+}
+
+func TestRTTEstimator(t *testing.T) {
+	e := timeoutqueue.NewRTTEstimator(time.Millisecond*10, 0)
+	assert.Equal(t, time.Millisecond*10, e.Timeout())
+
+	e.Add(time.Millisecond * 100)
+	assert.Equal(t, time.Millisecond*100, e.SRTT())
+	assert.Equal(t, time.Millisecond*50, e.RTTVAR())
+	assert.Equal(t, time.Millisecond*300, e.Timeout())
+
+	// srtt moves 1/8 and rttvar 1/4 of the way toward the new sample
+	e.Add(time.Millisecond * 180)
+	assert.Equal(t, time.Millisecond*110, e.SRTT())
+	assert.Equal(t, time.Millisecond*57500, e.RTTVAR()*1000)
+}
+
+func TestRTTEstimatorBind(t *testing.T) {
+	tq := timeoutqueue.NewManual(time.Millisecond*300, 10, nil)
+	e := timeoutqueue.NewRTTEstimator(0, 0)
+	e.Bind(tq, time.Millisecond*50)
+
+	// 300ms is the same as the current timeout
+	e.Add(time.Millisecond * 100)
+	assert.Equal(t, time.Millisecond*300, tq.Timeout())
+
+	// a small change is not applied
+	e.Add(time.Millisecond * 100)
+	assert.Equal(t, time.Millisecond*300, tq.Timeout())
+	assert.Equal(t, time.Millisecond*250, e.Timeout())
+
+	e.Add(time.Millisecond * 100)
+	assert.Equal(t, e.Timeout(), tq.Timeout())
+	applied := tq.Timeout()
+
+	e.Bind(nil, 0)
+	e.Add(time.Second)
+	assert.Equal(t, applied, tq.Timeout())
+}
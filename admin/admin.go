@@ -0,0 +1,133 @@
+// Package admin exposes the admin surface of a TimeoutQueue over net/rpc so
+// operators can list, cancel and flush timers in a long running node remotely.
+package admin
+
+import (
+	"fmt"
+	"net/rpc"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+)
+
+// Entry describes a pending timer. The ID is the timer's correlation ID
+// formatted with fmt.Sprint.
+type Entry struct {
+	ID        string
+	Remaining time.Duration
+	Paused    bool
+}
+
+// ListArgs are the arguments to Service.List. A Limit of zero or less lists
+// everything.
+type ListArgs struct {
+	Limit int
+}
+
+// Service is the RPC service for a single queue. Only string correlation IDs
+// can be canceled by key.
+type Service struct {
+	tq *timeoutqueue.TimeoutQueue
+}
+
+// Register the Service for tq on server under name.
+func Register(server *rpc.Server, name string, tq *timeoutqueue.TimeoutQueue) error {
+	return server.RegisterName(name, &Service{tq: tq})
+}
+
+// List the pending timers in the order they will fire, followed by the paused
+// timers.
+func (s *Service) List(args ListArgs, reply *[]Entry) error {
+	entries := s.tq.Export()
+	if args.Limit > 0 && len(entries) > args.Limit {
+		entries = entries[:args.Limit]
+	}
+	out := make([]Entry, len(entries))
+	for i, e := range entries {
+		out[i] = Entry{
+			Remaining: e.Remaining,
+			Paused:    e.Paused,
+		}
+		if e.ID != nil {
+			out[i].ID = fmt.Sprint(e.ID)
+		}
+	}
+	*reply = out
+	return nil
+}
+
+// Cancel every timer with the correlation ID key, replying with the number
+// canceled.
+func (s *Service) Cancel(key string, canceled *int) error {
+	*canceled = s.tq.CancelID(key)
+	return nil
+}
+
+// Flush every timer, replying with the number that were pending.
+func (s *Service) Flush(_ struct{}, flushed *int) error {
+	*flushed = s.tq.Len()
+	s.tq.Flush()
+	return nil
+}
+
+// Stats replies with the queue's Stats.
+func (s *Service) Stats(_ struct{}, stats *timeoutqueue.Stats) error {
+	*stats = s.tq.Stats()
+	return nil
+}
+
+// Client calls a Service.
+type Client struct {
+	c    *rpc.Client
+	name string
+}
+
+// NewClient returns a Client for the Service registered under name.
+func NewClient(c *rpc.Client, name string) *Client {
+	return &Client{
+		c:    c,
+		name: name,
+	}
+}
+
+// Dial connects to a Service registered under name on an rpc.Server at addr.
+func Dial(network, addr, name string) (*Client, error) {
+	c, err := rpc.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c, name), nil
+}
+
+// List calls Service.List.
+func (c *Client) List(limit int) ([]Entry, error) {
+	var entries []Entry
+	err := c.c.Call(c.name+".List", ListArgs{Limit: limit}, &entries)
+	return entries, err
+}
+
+// Cancel calls Service.Cancel.
+func (c *Client) Cancel(key string) (int, error) {
+	var canceled int
+	err := c.c.Call(c.name+".Cancel", key, &canceled)
+	return canceled, err
+}
+
+// Flush calls Service.Flush.
+func (c *Client) Flush() (int, error) {
+	var flushed int
+	err := c.c.Call(c.name+".Flush", struct{}{}, &flushed)
+	return flushed, err
+}
+
+// Stats calls Service.Stats.
+func (c *Client) Stats() (timeoutqueue.Stats, error) {
+	var stats timeoutqueue.Stats
+	err := c.c.Call(c.name+".Stats", struct{}{}, &stats)
+	return stats, err
+}
+
+// Close the connection.
+func (c *Client) Close() error {
+	return c.c.Close()
+}
@@ -0,0 +1,77 @@
+package admin_test
+
+import (
+	"net"
+	"net/rpc"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/admin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmin(t *testing.T) {
+	tq := timeoutqueue.NewManual(time.Minute, 10, nil)
+	tq.SetExecutor(timeoutqueue.Inline)
+	server := rpc.NewServer()
+	assert.NoError(t, admin.Register(server, "Timers", tq))
+
+	clientConn, serverConn := net.Pipe()
+	go server.ServeConn(serverConn)
+	c := admin.NewClient(rpc.NewClient(clientConn), "Timers")
+	defer c.Close()
+
+	nop := func(interface{}) {}
+	tq.AddWithID("a", nop)
+	tq.AddWithID("b", nop)
+	tq.AddWithID("b", nop)
+	tq.Add(func() {})
+
+	entries, err := c.List(2)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "a", entries[0].ID)
+	assert.True(t, entries[0].Remaining > 0)
+
+	canceled, err := c.Cancel("b")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, canceled)
+
+	flushed, err := c.Flush()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, flushed)
+
+	stats, err := c.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), stats.Added)
+	assert.Equal(t, uint64(2), stats.Canceled)
+	assert.Equal(t, uint64(2), stats.Fired)
+}
+
+func TestAdminPaused(t *testing.T) {
+	tq := timeoutqueue.NewManual(time.Minute, 10, nil)
+	server := rpc.NewServer()
+	assert.NoError(t, admin.Register(server, "Timers", tq))
+
+	clientConn, serverConn := net.Pipe()
+	go server.ServeConn(serverConn)
+	c := admin.NewClient(rpc.NewClient(clientConn), "Timers")
+	defer c.Close()
+
+	nop := func(interface{}) {}
+	tq.AddWithID("a", nop)
+	tq.AddWithID("b", nop).Pause()
+
+	entries, err := c.List(0)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.False(t, entries[0].Paused)
+	assert.Equal(t, "b", entries[1].ID)
+	assert.True(t, entries[1].Paused)
+
+	canceled, err := c.Cancel("b")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, canceled)
+	assert.Equal(t, 1, tq.Len())
+}
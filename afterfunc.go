@@ -0,0 +1,52 @@
+package timeoutqueue
+
+import (
+	"time"
+)
+
+// NewAfterFunc returns a TimeoutQueue that never starts a runner Go routine.
+// Instead it keeps a single timer from time.AfterFunc armed for the deadline at
+// the head of the queue, and the timer's func fires whatever is due, the same as
+// Tick, then arms the timer for the next deadline. Nothing is left running
+// between deadlines, which suits goroutine leak detectors and libraries that
+// must not keep background Go routines alive. Unless an Executor is set, the
+// actions are still called in their own Go routines. IsRunning is always false
+// for a queue from NewAfterFunc. With the timeoutqueuesingle tag the timer would
+// fire on another Go routine, so it is never armed and the queue must be driven
+// by calling Tick, the same as one from NewManual.
+func NewAfterFunc(timeout time.Duration, capacity int) *TimeoutQueue {
+	tq := New(timeout, capacity)
+	tq.afterFunc = true
+	return tq
+}
+
+// arm requires a mux lock. It sets the timer of a queue from NewAfterFunc for
+// the head of the queue, or stops it if there is nothing to wait for.
+func (tq *TimeoutQueue) arm() {
+	if tq.head == empty || tq.suspended {
+		if tq.timer != nil {
+			tq.timer.Stop()
+		}
+		return
+	}
+	now := tq.clockNow()
+	d := tq.deadline(tq.head).Sub(now)
+	if d <= 0 {
+		if end := tq.blackoutEnd(now); !end.IsZero() {
+			d = end.Sub(now)
+		}
+	}
+	if tq.timer == nil {
+		tq.timer = time.AfterFunc(d, tq.sweep)
+		return
+	}
+	tq.timer.Reset(d)
+}
+
+// sweep is called by the timer of a queue from NewAfterFunc.
+func (tq *TimeoutQueue) sweep() {
+	tq.Tick()
+	tq.mux.Lock()
+	tq.arm()
+	tq.mux.Unlock()
+}
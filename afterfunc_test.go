@@ -0,0 +1,44 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAfterFunc(t *testing.T) {
+	tq := timeoutqueue.NewAfterFunc(time.Millisecond*20, 10)
+	tq.SetExecutor(timeoutqueue.Inline)
+	fc := timeoutqueuetest.CountFires(tq)
+	ch := make(chan int, 3)
+	tq.Add(func() { ch <- 1 })
+	tq.Add(func() { ch <- 2 })
+	canceled := tq.Add(func() { ch <- 3 })
+	assert.True(t, canceled.Cancel())
+	assert.False(t, tq.IsRunning())
+	assert.Equal(t, 0, tq.Goroutines())
+
+	assert.True(t, fc.WaitForFires(2, time.Second))
+	assert.Equal(t, 1, <-ch)
+	assert.Equal(t, 2, <-ch)
+	assert.Equal(t, 0, tq.Goroutines())
+
+	// a shorter timeout re-arms the timer
+	tq.SetTimeout(time.Hour)
+	tq.Add(func() { ch <- 4 })
+	tq.SetTimeout(time.Millisecond)
+	assert.True(t, fc.WaitForFires(3, time.Second))
+	assert.Equal(t, 4, <-ch)
+
+	tq.SetTimeout(time.Hour)
+	tq.Add(func() { ch <- 5 })
+	tq.Close()
+	assert.Equal(t, 5, <-ch)
+	assert.Equal(t, 0, tq.Goroutines())
+	assert.NoError(t, tq.Validate())
+}
@@ -0,0 +1,49 @@
+package timeoutqueue
+
+// SetAuditSize keeps the last size Events in memory so the recent history of
+// the queue can be inspected with RecentEvents without a Subscription. Changing
+// the size discards the Events kept so far and a size of zero or less stops
+// keeping them.
+func (tq *TimeoutQueue) SetAuditSize(size int) {
+	tq.mux.Lock()
+	tq.audit = eventRing{}
+	if size > 0 {
+		tq.audit.events = make([]Event, size)
+	}
+	tq.mux.Unlock()
+}
+
+// RecentEvents returns the Events kept since SetAuditSize, oldest first.
+func (tq *TimeoutQueue) RecentEvents() []Event {
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	return tq.audit.get()
+}
+
+// eventRing is a fixed size ring of the most recent Events.
+type eventRing struct {
+	events []Event
+	next   int
+	full   bool
+}
+
+func (r *eventRing) add(e Event) {
+	if r.events == nil {
+		return
+	}
+	r.events[r.next] = e
+	r.next++
+	if r.next == len(r.events) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+func (r *eventRing) get() []Event {
+	if !r.full {
+		return append([]Event(nil), r.events[:r.next]...)
+	}
+	out := make([]Event, 0, len(r.events))
+	out = append(out, r.events[r.next:]...)
+	return append(out, r.events[:r.next]...)
+}
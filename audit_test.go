@@ -0,0 +1,33 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentEvents(t *testing.T) {
+	tq := timeoutqueue.New(time.Second, 10)
+	assert.Empty(t, tq.RecentEvents())
+	tq.SetAuditSize(3)
+
+	a := tq.AddWithID("a", func(interface{}) {})
+	tq.AddWithID("b", func(interface{}) {})
+	a.Reset()
+	a.Cancel()
+	tq.Flush()
+
+	var got []string
+	for _, e := range tq.RecentEvents() {
+		assert.False(t, e.Time.IsZero())
+		got = append(got, e.Type.String()+" "+e.ID.(string))
+	}
+	assert.Equal(t, []string{"Reset a", "Canceled a", "Fired b"}, got)
+
+	tq.SetAuditSize(0)
+	tq.Add(func() {})
+	assert.Empty(t, tq.RecentEvents())
+	tq.Flush()
+}
@@ -0,0 +1,83 @@
+package timeoutqueue
+
+import (
+	"sort"
+	"time"
+)
+
+// blackout is a window from start until end in which nothing fires.
+type blackout struct {
+	start, end time.Time
+}
+
+// AddBlackout defers firing from start until end, such as during a coordinated
+// migration. Anything that becomes due inside the window fires as soon as it
+// ends. It's lateness is still measured from it's deadline, so a TimedAction
+// and the Stats drift see how long it was held, but the late threshold, see
+// SetLateThreshold, is measured from the end of the window so deferred actions
+// are not skipped. Flush, Drain and Close are not affected by blackouts.
+// Windows that have ended are discarded. It returns false if end is not after
+// start.
+func (tq *TimeoutQueue) AddBlackout(start, end time.Time) bool {
+	if !end.After(start) {
+		return false
+	}
+	tq.mux.Lock()
+	tq.blackouts = append(tq.blackouts, blackout{start: start, end: end})
+	sort.Slice(tq.blackouts, func(i, j int) bool {
+		return tq.blackouts[i].start.Before(tq.blackouts[j].start)
+	})
+	tq.mux.Unlock()
+	return true
+}
+
+// ClearBlackouts removes every blackout window. Anything held by a window that
+// was in effect fires straight away, or the next time Tick is called.
+func (tq *TimeoutQueue) ClearBlackouts() {
+	tq.mux.Lock()
+	tq.blackouts = nil
+	tq.rearm()
+	tq.mux.Unlock()
+}
+
+// blackoutEnd requires a mux lock. If now is inside a blackout window it
+// returns when the window ends, taking overlapping windows as one, otherwise it
+// returns the zero time. Windows that have ended are discarded.
+func (tq *TimeoutQueue) blackoutEnd(now time.Time) time.Time {
+	if len(tq.blackouts) == 0 {
+		return time.Time{}
+	}
+	keep := tq.blackouts[:0]
+	for _, b := range tq.blackouts {
+		if b.end.After(now) {
+			keep = append(keep, b)
+		}
+	}
+	tq.blackouts = keep
+	var end time.Time
+	for _, b := range tq.blackouts {
+		at := now
+		if !end.IsZero() {
+			at = end
+		}
+		if b.start.After(at) {
+			break
+		}
+		if b.end.After(at) {
+			end = b.end
+		}
+	}
+	if !end.IsZero() {
+		tq.lastBlackout = end
+	}
+	return end
+}
+
+// excused requires a mux lock. It returns how much of the lateness of a node
+// with the given deadline is due to the last blackout window it was held by.
+func (tq *TimeoutQueue) excused(deadline time.Time) time.Duration {
+	if tq.lastBlackout.After(deadline) {
+		return tq.lastBlackout.Sub(deadline)
+	}
+	return 0
+}
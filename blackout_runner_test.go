@@ -0,0 +1,29 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlackoutRunner(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond, 10)
+	now := time.Now()
+	tq.AddBlackout(now, now.Add(time.Hour))
+	ch := make(chan int, 1)
+	tq.Add(func() { ch <- 1 })
+	time.Sleep(time.Millisecond * 20)
+	assert.Len(t, ch, 0)
+
+	tq.ClearBlackouts()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("action was not fired after the blackout was cleared")
+	}
+	tq.Close()
+}
@@ -0,0 +1,38 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlackout(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	tq.SetExecutor(timeoutqueue.Inline)
+	tq.SetLateThreshold(time.Millisecond*100, nil)
+	start := clock.Now()
+	assert.False(t, tq.AddBlackout(start, start))
+	assert.True(t, tq.AddBlackout(start.Add(time.Millisecond*500), start.Add(time.Second*2)))
+	assert.True(t, tq.AddBlackout(start.Add(time.Second*2), start.Add(time.Second*3)))
+
+	var late []time.Duration
+	record := func(deadline, fired time.Time) {
+		late = append(late, fired.Sub(deadline))
+	}
+	tq.AddTimed(record)
+	clock.Advance(time.Second * 2)
+	assert.Equal(t, 0, tq.Tick())
+	clock.Advance(time.Second)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, []time.Duration{time.Second * 2}, late)
+
+	// once past the window the late threshold applies again
+	tq.AddTimed(record)
+	clock.Advance(time.Second * 2)
+	assert.Equal(t, 0, tq.Tick())
+	assert.Equal(t, 0, tq.Len())
+}
@@ -0,0 +1,21 @@
+package timeoutqueue
+
+import (
+	"time"
+)
+
+// SlowAction is called when a TimeoutAction takes longer than the budget set
+// by SetBudget. It receives the correlation ID of the TimeoutAction, see
+// AddWithID, and how long it took.
+type SlowAction func(id interface{}, took time.Duration)
+
+// SetBudget sets how long a TimeoutAction is expected to take. Any action that
+// takes longer is reported by calling onSlow from the same Go routine once the
+// action returns. This includes actions called by Flush. A budget of zero or
+// less stops the reporting.
+func (tq *TimeoutQueue) SetBudget(budget time.Duration, onSlow SlowAction) {
+	tq.mux.Lock()
+	tq.dispatcher.budget = budget
+	tq.dispatcher.onSlow = onSlow
+	tq.mux.Unlock()
+}
@@ -0,0 +1,34 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+	tq := timeoutqueue.New(time.Second, 10)
+	var slow []interface{}
+	var took time.Duration
+	tq.SetBudget(time.Millisecond, func(id interface{}, d time.Duration) {
+		slow = append(slow, id)
+		took = d
+	})
+
+	tq.AddWithID("fast", func(interface{}) {})
+	tq.AddWithID("slow", func(interface{}) {
+		time.Sleep(time.Millisecond * 3)
+	})
+	tq.Flush()
+	assert.Equal(t, []interface{}{"slow"}, slow)
+	assert.True(t, took >= time.Millisecond*3)
+
+	tq.SetBudget(0, nil)
+	tq.AddWithID("slow", func(interface{}) {
+		time.Sleep(time.Millisecond * 3)
+	})
+	tq.Flush()
+	assert.Len(t, slow, 1)
+}
@@ -0,0 +1,89 @@
+package timeoutqueue
+
+import (
+	"sync"
+)
+
+// Stage is one step of a Chain. The Action is called after the Queue's timeout.
+type Stage struct {
+	Queue  *TimeoutQueue
+	Action TimeoutAction
+}
+
+// Chain is a single logical item with several stages of timeout, for instance
+// warn after 5 seconds, retry after 10 and abort after 30. Each stage is added
+// to it's own queue when the Chain is created, so the stages are timed from
+// then. Canceling the Chain tears down every stage that has not yet fired.
+type Chain struct {
+	mux      sync.Mutex
+	handles  []Handle
+	fired    []bool
+	canceled bool
+}
+
+// NewChain adds every stage to it's queue and returns the Chain.
+func NewChain(stages ...Stage) *Chain {
+	c := &Chain{
+		handles: make([]Handle, len(stages)),
+		fired:   make([]bool, len(stages)),
+	}
+	for i, s := range stages {
+		// the stage may fire from within Add, so it is added without the lock
+		h := s.Queue.AddHandle(c.stage(i, s.Action))
+		c.mux.Lock()
+		if !c.fired[i] {
+			c.handles[i] = h
+		}
+		c.mux.Unlock()
+	}
+	return c
+}
+
+func (c *Chain) stage(i int, action TimeoutAction) TimeoutAction {
+	return func() {
+		c.mux.Lock()
+		if c.canceled {
+			c.mux.Unlock()
+			return
+		}
+		c.handles[i] = Handle{}
+		c.fired[i] = true
+		c.mux.Unlock()
+		action()
+	}
+}
+
+// Cancel tears down all the stages that have not fired and returns how many
+// there were. Once Cancel returns no further stage will start, though one that
+// started before may still be running. Completing the item the Chain tracks and
+// abandoning it are both done with Cancel.
+func (c *Chain) Cancel() int {
+	var canceled int
+	c.mux.Lock()
+	if !c.canceled {
+		c.canceled = true
+		for i, h := range c.handles {
+			if h.Cancel() {
+				canceled++
+			}
+			c.handles[i] = Handle{}
+		}
+	}
+	c.mux.Unlock()
+	return canceled
+}
+
+// Remaining returns the number of stages that have not fired.
+func (c *Chain) Remaining() int {
+	var remaining int
+	c.mux.Lock()
+	if !c.canceled {
+		for _, h := range c.handles {
+			if h != (Handle{}) {
+				remaining++
+			}
+		}
+	}
+	c.mux.Unlock()
+	return remaining
+}
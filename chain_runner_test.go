@@ -0,0 +1,46 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeout"
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain(t *testing.T) {
+	warn := timeoutqueue.New(time.Millisecond*2, 10)
+	abort := timeoutqueue.New(time.Millisecond*20, 10)
+	ch := make(chan string, 2)
+
+	c := timeoutqueue.NewChain(
+		timeoutqueue.Stage{Queue: warn, Action: func() { ch <- "warn" }},
+		timeoutqueue.Stage{Queue: abort, Action: func() { ch <- "abort" }},
+	)
+	assert.Equal(t, 2, c.Remaining())
+	assert.NoError(t, timeout.After(10, func() {
+		assert.Equal(t, "warn", <-ch)
+	}))
+	assert.Equal(t, 1, c.Remaining())
+	assert.Equal(t, 1, c.Cancel())
+	assert.Equal(t, 0, c.Remaining())
+	assert.Equal(t, 0, c.Cancel())
+	select {
+	case s := <-ch:
+		t.Error("unexpected stage " + s)
+	case <-time.After(time.Millisecond * 30):
+	}
+
+	c = timeoutqueue.NewChain(
+		timeoutqueue.Stage{Queue: warn, Action: func() { ch <- "warn" }},
+		timeoutqueue.Stage{Queue: abort, Action: func() { ch <- "abort" }},
+	)
+	assert.NoError(t, timeout.After(40, func() {
+		assert.Equal(t, "warn", <-ch)
+		assert.Equal(t, "abort", <-ch)
+	}))
+	assert.Equal(t, 0, c.Cancel())
+}
@@ -0,0 +1,25 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainImmediate(t *testing.T) {
+	// a closed queue calls the stage from within Add
+	closed := timeoutqueue.New(time.Hour, 1)
+	closed.SetExecutor(timeoutqueue.Inline)
+	closed.Close()
+	later := timeoutqueue.NewManual(time.Hour, 1, nil)
+	var stages []string
+	c := timeoutqueue.NewChain(
+		timeoutqueue.Stage{Queue: closed, Action: func() { stages = append(stages, "now") }},
+		timeoutqueue.Stage{Queue: later, Action: func() { stages = append(stages, "later") }},
+	)
+	assert.Equal(t, []string{"now"}, stages)
+	assert.Equal(t, 1, c.Remaining())
+	assert.Equal(t, 1, c.Cancel())
+}
@@ -0,0 +1,90 @@
+package timeoutqueue
+
+import (
+	"time"
+)
+
+// MaxClasses is the most durations that can be passed to NewClasses.
+const MaxClasses = 256
+
+// NewClasses returns a TimeoutQueue with a small set of fixed duration classes,
+// for instance 100ms, 1s and 30s, sharing one runner and one pool of nodes.
+// Class 0 is the queue's timeout and uses the first duration, it is what Add and
+// every other method that takes no class uses and it can be changed with
+// SetTimeout. The other classes keep their durations for the life of the queue
+// and are added with AddClass. Each class remembers it's latest entry so adding
+// to a class does not have to search past the entries of longer classes to find
+// it's place. It panics if there are no durations or more than MaxClasses.
+func NewClasses(capacity int, durations ...time.Duration) *TimeoutQueue {
+	if len(durations) == 0 || len(durations) > MaxClasses {
+		panic("timeoutqueue: NewClasses requires between 1 and MaxClasses durations")
+	}
+	tq := New(durations[0], capacity)
+	tq.classes = append([]time.Duration(nil), durations[1:]...)
+	tq.classTail = make([]uint32, len(durations))
+	for i := range tq.classTail {
+		tq.classTail[i] = empty
+	}
+	return tq
+}
+
+// AddClass adds a TimeoutAction that times out after the duration of the
+// class. Resetting it's Token uses the same duration and SetTimeout does not
+// move it unless the class is 0. It panics if the class was not passed to
+// NewClasses.
+func (tq *TimeoutQueue) AddClass(class int, action TimeoutAction) Token {
+	if class < 0 || class > len(tq.classes) {
+		panic("timeoutqueue: class out of range")
+	}
+	tq.mux.Lock()
+	return tq.addNode(action, tq.hookID(), uint8(class), false)
+}
+
+// Classes returns the number of duration classes, which is 1 for a queue not
+// created with NewClasses.
+func (tq *TimeoutQueue) Classes() int {
+	return len(tq.classes) + 1
+}
+
+// classTimeout requires a mux lock.
+func (tq *TimeoutQueue) classTimeout(class uint8) time.Duration {
+	if class == 0 {
+		return tq.timeout
+	}
+	return tq.classes[class-1]
+}
+
+// linkClass requires a mux lock. It links a node into the list the same as
+// link, but for a queue with classes it searches forward from the last node
+// inserted in the same class, which is usually close to the right place.
+func (tq *TimeoutQueue) linkClass(nodeIdx uint32) {
+	if tq.classTail == nil {
+		tq.link(nodeIdx)
+		return
+	}
+	n := &tq.nodes[nodeIdx]
+	hint := tq.classTail[n.class]
+	tq.classTail[n.class] = nodeIdx
+	// the hint is only used if it is still in the list, in the same class and
+	// not after the node, otherwise it has been freed and possibly reused, and
+	// it is not paused
+	if hint == empty || hint == nodeIdx || int(hint) >= len(tq.nodes) || tq.isPaused(hint) {
+		tq.link(nodeIdx)
+		return
+	}
+	if h := tq.nodes[hint]; h.action == nil || h.class != n.class || h.timeout.After(n.timeout) {
+		tq.link(nodeIdx)
+		return
+	}
+	next := tq.nodes[hint].next
+	for next != empty && !tq.nodes[next].timeout.After(n.timeout) {
+		next = tq.nodes[next].next
+	}
+	if next == empty {
+		n.next = empty
+		n.prev = tq.tail
+		tq.add(nodeIdx)
+		return
+	}
+	tq.insertBefore(nodeIdx, next)
+}
@@ -0,0 +1,113 @@
+package timeoutqueue
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// jumpClock is the system clock plus an offset, so it can jump forward the way
+// the wall clock does after a system sleep.
+type jumpClock struct {
+	mux    sync.Mutex
+	offset time.Duration
+}
+
+func (c *jumpClock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return time.Now().Add(c.offset)
+}
+
+func (c *jumpClock) jump(d time.Duration) {
+	c.mux.Lock()
+	c.offset += d
+	c.mux.Unlock()
+}
+
+func TestClasses(t *testing.T) {
+	tq := NewClasses(10, time.Second, time.Millisecond*100, time.Second*30)
+	clock := &jumpClock{}
+	tq.clock = clock
+	tq.manual = true
+	tq.SetExecutor(Inline)
+	assert.Equal(t, 3, tq.Classes())
+	assert.Equal(t, 1, New(time.Second, 1).Classes())
+	assert.Equal(t, 1, NewManual(time.Second, 10, clock).Classes())
+
+	var fired []int
+	action := func(i int) TimeoutAction {
+		return func() { fired = append(fired, i) }
+	}
+	tq.SetTimeout(time.Hour)
+	long := tq.AddClass(2, action(2))
+	short := tq.AddClass(1, action(1))
+	tq.AddClass(1, action(3))
+	tq.Add(action(0))
+	assert.NoError(t, tq.Validate())
+
+	clock.jump(time.Millisecond * 50)
+	assert.True(t, short.Reset())
+	assert.NoError(t, tq.Validate())
+	clock.jump(time.Millisecond * 50)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, []int{3}, fired)
+	clock.jump(time.Millisecond * 50)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, []int{3, 1}, fired)
+
+	// SetTimeout only moves class 0
+	tq.SetTimeout(time.Millisecond)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, []int{3, 1, 0}, fired)
+	assert.True(t, long.Cancel())
+	assert.NoError(t, tq.Validate())
+
+	assert.Panics(t, func() { tq.AddClass(3, action(4)) })
+	assert.Panics(t, func() { NewClasses(1) })
+}
+
+func TestMergeClassesAndPaused(t *testing.T) {
+	clock := &jumpClock{}
+	a := NewClasses(10, time.Second, time.Minute)
+	b := NewClasses(10, time.Second, time.Minute, time.Hour)
+	for _, tq := range []*TimeoutQueue{a, b} {
+		tq.clock = clock
+		tq.manual = true
+		tq.SetExecutor(Inline)
+	}
+	var fired []int
+	action := func(i int) TimeoutAction {
+		return func() { fired = append(fired, i) }
+	}
+	b.AddClass(1, action(1))
+	b.AddClass(2, action(2))
+	b.Add(action(3)).Pause()
+	b.AddPinned(action(4))
+	assert.Equal(t, 4, a.Merge(b))
+	assert.NoError(t, a.Validate())
+	assert.Equal(t, 0, b.Len())
+	assert.Equal(t, 4, a.Len())
+	assert.Equal(t, uint8(1), a.nodes[a.nodes[a.head].next].class)
+
+	// neither the class entries nor the pinned one move with the timeout, and
+	// the class a does not have is kept pinned in class 0
+	a.SetTimeout(time.Hour * 2)
+	clock.jump(time.Second)
+	assert.Equal(t, 1, a.Tick())
+	clock.jump(time.Minute)
+	assert.Equal(t, 1, a.Tick())
+	clock.jump(time.Hour)
+	assert.Equal(t, 1, a.Tick())
+	assert.Equal(t, []int{4, 1, 2}, fired)
+
+	// the paused entry is still paused
+	clock.jump(time.Hour * 2)
+	assert.Equal(t, 0, a.Tick())
+	assert.Equal(t, 1, a.Len())
+	a.Flush()
+	assert.Equal(t, []int{4, 1, 2, 3}, fired)
+	assert.NoError(t, a.Validate())
+}
@@ -0,0 +1,135 @@
+package timeoutqueue
+
+import (
+	"time"
+)
+
+// Clock provides the current time to a TimeoutQueue.
+type Clock interface {
+	Now() time.Time
+}
+
+// NewManual returns a TimeoutQueue that never starts a runner. Instead the
+// caller drives it by calling Tick, which makes it possible to control exactly
+// when TimeoutActions fire. If clock is nil, the system clock is used.
+func NewManual(timeout time.Duration, capacity int, clock Clock) *TimeoutQueue {
+	tq := New(timeout, capacity)
+	tq.clock = clock
+	tq.manual = true
+	return tq
+}
+
+// Tick calls every TimeoutAction whose deadline has passed and returns the
+// number called. The actions go through the Executor, so unless one is set
+// they run in their own Go routines. Tick is how a queue from NewManual is
+// driven but it can be called on any queue.
+func (tq *TimeoutQueue) Tick() int {
+	var fired int
+	for {
+		tq.mux.Lock()
+		if tq.head == empty || tq.suspended {
+			tq.mux.Unlock()
+			return fired
+		}
+		now := tq.refreshNow()
+		late := now.Sub(tq.deadline(tq.head))
+		if late < 0 || !tq.blackoutEnd(now).IsZero() {
+			tq.mux.Unlock()
+			return fired
+		}
+		if tq.fire(late) {
+			fired++
+		}
+	}
+}
+
+// SetCoarseClock trades timing accuracy for throughput. When precision is
+// greater than zero, the runner caches the current time and refreshes it at
+// least once every precision; while the runner is active Add and Reset compute
+// deadlines from the cached time instead of calling time.Now. TimeoutActions
+// may then fire up to precision late. Passing zero restores the default of
+// reading the time on every call.
+func (tq *TimeoutQueue) SetCoarseClock(precision time.Duration) {
+	tq.mux.Lock()
+	tq.coarse = precision
+	tq.mux.Unlock()
+}
+
+// now requires a mux lock. It returns the time the queue was suspended if it is
+// suspended or the cached time if the coarse clock is enabled and is being
+// refreshed by the runner.
+func (tq *TimeoutQueue) now() time.Time {
+	if tq.suspended {
+		return tq.suspendedAt
+	}
+	if tq.coarse > 0 && tq.running != 0 {
+		return tq.cachedNow
+	}
+	return tq.refreshNow()
+}
+
+// SetResolution rounds every deadline up to a multiple of resolution, so
+// everything added within the same interval shares a deadline. The runner then
+// wakes once per interval and fires everything due together, and as deadlines
+// only ever match or follow the tail of the queue, adding is cheaper too.
+// TimeoutActions may fire up to resolution late. It only applies to deadlines
+// set after it is called; passing zero turns it off.
+func (tq *TimeoutQueue) SetResolution(resolution time.Duration) {
+	tq.mux.Lock()
+	tq.resolution = resolution
+	tq.mux.Unlock()
+}
+
+// deadlineAfter requires a mux lock. It returns the deadline for something
+// added or reset now with the given timeout.
+func (tq *TimeoutQueue) deadlineAfter(timeout time.Duration) time.Time {
+	deadline := tq.now().Add(timeout)
+	if tq.resolution > 0 {
+		if rounded := deadline.Truncate(tq.resolution); rounded.Before(deadline) {
+			return rounded.Add(tq.resolution)
+		}
+	}
+	return deadline
+}
+
+// deadline requires a mux lock. It returns the deadline of a node in the list.
+func (tq *TimeoutQueue) deadline(nodeIdx uint32) time.Time {
+	return tq.nodes[nodeIdx].timeout.Add(tq.shift)
+}
+
+// setDeadline requires a mux lock. It sets the deadline of a node before it is
+// linked into the list.
+func (tq *TimeoutQueue) setDeadline(nodeIdx uint32, deadline time.Time) {
+	tq.nodes[nodeIdx].timeout = deadline.Add(-tq.shift)
+}
+
+// nodeAt requires a mux lock. It returns a copy of a node in the list with it's
+// deadline in place of it's timeout, to be dispatched once the node is freed.
+func (tq *TimeoutQueue) nodeAt(nodeIdx uint32) node {
+	n := tq.nodes[nodeIdx]
+	n.timeout = n.timeout.Add(tq.shift)
+	return n
+}
+
+// refreshNow requires a mux lock. It reads the time and updates the cache.
+func (tq *TimeoutQueue) refreshNow() time.Time {
+	tq.cachedNow = tq.clockNow()
+	return tq.cachedNow
+}
+
+// clockNow requires a mux lock.
+func (tq *TimeoutQueue) clockNow() time.Time {
+	if tq.clock != nil {
+		return tq.clock.Now()
+	}
+	return time.Now()
+}
+
+// sleepFor requires a mux lock. It limits how long the runner will sleep so the
+// cached time is refreshed often enough.
+func (tq *TimeoutQueue) sleepFor(d time.Duration) time.Duration {
+	if tq.coarse > 0 && d > tq.coarse {
+		return tq.coarse
+	}
+	return d
+}
@@ -0,0 +1,58 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTick(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	ch := make(chan int, 2)
+	tq.SetExecutor(timeoutqueue.ExecutorFunc(func(action func()) {
+		action()
+	}))
+
+	tq.Add(getAction(ch, 1))
+	clock.Advance(time.Millisecond)
+	tq.Add(getAction(ch, 2))
+
+	clock.Advance(time.Millisecond * 999)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, 1, <-ch)
+	assert.Equal(t, 0, tq.Tick())
+	assert.Equal(t, 1, tq.Len())
+
+	clock.Advance(time.Millisecond)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, 2, <-ch)
+}
+
+func TestResolution(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Unix(100, 0))
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	tq.SetResolution(time.Millisecond * 10)
+	tq.SetExecutor(timeoutqueue.Inline)
+	var fired int
+	action := func() { fired++ }
+
+	// all three round up to 101.010s
+	clock.Advance(time.Millisecond)
+	tq.Add(action)
+	clock.Advance(time.Millisecond * 5)
+	tq.Add(action)
+	clock.Advance(time.Millisecond * 4)
+	tq.Add(action)
+	// 101.010s is already a multiple of the resolution
+	tq.Add(action)
+
+	clock.Set(time.Unix(101, int64(time.Millisecond*9)))
+	assert.Equal(t, 0, tq.Tick())
+	clock.Set(time.Unix(101, int64(time.Millisecond*10)))
+	assert.Equal(t, 4, tq.Tick())
+	assert.Equal(t, 4, fired)
+}
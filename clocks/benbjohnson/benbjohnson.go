@@ -0,0 +1,44 @@
+// Package benbjohnson drives a TimeoutQueue from a github.com/benbjohnson/clock
+// Clock, so projects already using it's Mock in their tests can use it to
+// control the queue as well.
+package benbjohnson
+
+import (
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/dist-ribut-us/timeoutqueue"
+)
+
+// a clock.Clock can be used directly as a timeoutqueue.Clock
+var _ timeoutqueue.Clock = clock.Clock(nil)
+
+// New returns a manual TimeoutQueue that reads the time from c and is driven by
+// a ticker from c, see Drive.
+func New(timeout time.Duration, capacity int, c clock.Clock, resolution time.Duration) (*timeoutqueue.TimeoutQueue, func()) {
+	tq := timeoutqueue.NewManual(timeout, capacity, c)
+	return tq, Drive(tq, c, resolution)
+}
+
+// Drive calls Tick on tq every resolution of c's time until the returned func
+// is called. With a Mock, TimeoutActions fire once the clock is moved past their
+// deadline, but Tick is called from a Go routine so they may fire shortly after
+// Add or Set returns. tq should use c as it's Clock.
+func Drive(tq *timeoutqueue.TimeoutQueue, c clock.Clock, resolution time.Duration) func() {
+	ticker := c.Ticker(resolution)
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				tq.Tick()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(stop)
+	}
+}
@@ -0,0 +1,35 @@
+package benbjohnson_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/dist-ribut-us/timeoutqueue/clocks/benbjohnson"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrive(t *testing.T) {
+	c := clock.NewMock()
+	tq, stop := benbjohnson.New(time.Minute, 10, c, time.Second)
+	defer stop()
+	ch := make(chan bool, 1)
+	tq.Add(func() {
+		ch <- true
+	})
+
+	c.Add(time.Second * 30)
+	select {
+	case <-ch:
+		t.Error("fired early")
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	c.Add(time.Second * 31)
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Error("did not fire")
+	}
+	assert.Equal(t, 0, tq.Len())
+}
@@ -0,0 +1,44 @@
+// Package clockwork drives a TimeoutQueue from a github.com/jonboulle/clockwork
+// Clock, so projects already using clockwork's FakeClock in their tests can use
+// it to control the queue as well.
+package clockwork
+
+import (
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/jonboulle/clockwork"
+)
+
+// a clockwork.Clock can be used directly as a timeoutqueue.Clock
+var _ timeoutqueue.Clock = clockwork.Clock(nil)
+
+// New returns a manual TimeoutQueue that reads the time from c and is driven by
+// a ticker from c, see Drive.
+func New(timeout time.Duration, capacity int, c clockwork.Clock, resolution time.Duration) (*timeoutqueue.TimeoutQueue, func()) {
+	tq := timeoutqueue.NewManual(timeout, capacity, c)
+	return tq, Drive(tq, c, resolution)
+}
+
+// Drive calls Tick on tq every resolution of c's time until the returned func
+// is called. With a FakeClock, TimeoutActions fire once the clock is advanced
+// past their deadline, but Tick is called from a Go routine so they may fire
+// shortly after Advance returns. tq should use c as it's Clock.
+func Drive(tq *timeoutqueue.TimeoutQueue, c clockwork.Clock, resolution time.Duration) func() {
+	ticker := c.NewTicker(resolution)
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.Chan():
+				tq.Tick()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(stop)
+	}
+}
@@ -0,0 +1,35 @@
+package clockwork_test
+
+import (
+	"testing"
+	"time"
+
+	tqclockwork "github.com/dist-ribut-us/timeoutqueue/clocks/clockwork"
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrive(t *testing.T) {
+	c := clockwork.NewFakeClock()
+	tq, stop := tqclockwork.New(time.Minute, 10, c, time.Second)
+	defer stop()
+	ch := make(chan bool, 1)
+	tq.Add(func() {
+		ch <- true
+	})
+
+	c.Advance(time.Second * 30)
+	select {
+	case <-ch:
+		t.Error("fired early")
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	c.Advance(time.Second * 31)
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Error("did not fire")
+	}
+	assert.Equal(t, 0, tq.Len())
+}
@@ -0,0 +1,70 @@
+package timeoutqueue
+
+import (
+	"time"
+)
+
+// Clone returns an independent queue holding everything pending in tq with the
+// same deadlines; paused actions are paused in the clone too. The clone has the
+// same timeout, Clock, Executor and policies but none of the Subscriptions,
+// pressure callbacks or Stats. If rebind is nil the clone calls the same actions
+// as tq, otherwise each entry in the clone calls the CorrelatedAction rebind
+// returns for it's correlation ID; rebind is called with tq locked, so it may
+// not call methods on tq. The Tokens from tq do not work on the clone.
+func (tq *TimeoutQueue) Clone(rebind func(id interface{}) CorrelatedAction) *TimeoutQueue {
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	c := New(tq.timeout, tq.pending)
+	c.hook = tq.hook
+	c.rebind = tq.rebind
+	c.dispatcher = tq.dispatcher
+	c.coarse = tq.coarse
+	c.clock = tq.clock
+	c.manual = tq.manual
+	c.afterFunc = tq.afterFunc
+	c.lateThreshold = tq.lateThreshold
+	c.discontinuity = tq.discontinuity
+	c.adaptive = tq.adaptive
+	c.resolution = tq.resolution
+	c.linger = tq.linger
+	c.blackouts = append([]blackout(nil), tq.blackouts...)
+	c.classes = tq.classes
+	if tq.classTail != nil {
+		c.classTail = make([]uint32, len(tq.classTail))
+		for i := range c.classTail {
+			c.classTail[i] = empty
+		}
+	}
+
+	c.mux.Lock()
+	for cur := tq.head; cur != empty; cur = tq.nodes[cur].next {
+		n := tq.nodes[cur]
+		action := n.action
+		if rebind != nil {
+			action = rebind(n.id)
+		}
+		h := c.insertClass(action, n.id, n.class, tq.deadline(cur))
+		if n.pinned {
+			c.pin(h.nodeIdx)
+		}
+	}
+	for idx, remaining := range tq.paused {
+		n := tq.nodes[idx]
+		action := n.action
+		if rebind != nil {
+			action = rebind(n.id)
+		}
+		h := c.insertClass(action, n.id, n.class, c.now().Add(remaining))
+		if n.pinned {
+			c.pin(h.nodeIdx)
+		}
+		c.remove(h.nodeIdx)
+		if c.paused == nil {
+			c.paused = make(map[uint32]time.Duration)
+		}
+		c.paused[h.nodeIdx] = remaining
+	}
+	c.rearm()
+	c.mux.Unlock()
+	return c
+}
@@ -0,0 +1,40 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClone(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	tq.SetExecutor(timeoutqueue.Inline)
+
+	var fired []string
+	tq.Add(func() { fired = append(fired, "shared") })
+	clock.Advance(time.Millisecond * 500)
+	tq.AddWithID("b", func(id interface{}) { fired = append(fired, "original") })
+
+	shared := tq.Clone(nil)
+	rebound := tq.Clone(func(id interface{}) timeoutqueue.CorrelatedAction {
+		return func(id interface{}) { fired = append(fired, "rebound") }
+	})
+	assert.Equal(t, 2, shared.Len())
+	assert.Equal(t, time.Second, rebound.Timeout())
+
+	tq.Flush()
+	fired = nil
+	clock.Advance(time.Millisecond * 500)
+	assert.Equal(t, 1, shared.Tick())
+	assert.Equal(t, 1, rebound.Tick())
+	assert.Equal(t, []string{"shared", "rebound"}, fired)
+
+	clock.Advance(time.Millisecond * 500)
+	assert.Equal(t, 1, shared.Tick())
+	assert.Equal(t, 1, rebound.Tick())
+	assert.Equal(t, []string{"shared", "rebound", "original", "rebound"}, fired)
+}
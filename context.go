@@ -0,0 +1,105 @@
+package timeoutqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithTimeoutCtx is a cheaper context.WithTimeout using the queue's timeout.
+// Instead of a runtime timer per context, every context shares the queue's
+// pooled nodes and runner. The context is done when the timeout expires, with
+// the error context.DeadlineExceeded, when the returned CancelFunc is called
+// or when parent is done. As with context.WithTimeout, the CancelFunc should
+// always be called once the context is finished with, which frees it's place
+// in the queue. A full queue from NewFixed follows it's FullPolicy, and if that
+// rejects the context it falls back to context.WithDeadline.
+func (tq *TimeoutQueue) WithTimeoutCtx(parent context.Context) (context.Context, context.CancelFunc) {
+	c := &queueCtx{
+		parent: parent,
+		done:   make(chan struct{}),
+	}
+	tq.mux.Lock()
+	c.deadline = tq.deadlineAfter(tq.timeout)
+	if tq.timeout <= 0 || tq.closed {
+		tq.mux.Unlock()
+		c.cancel(context.DeadlineExceeded)
+	} else {
+		ev, ok := tq.room(nil)
+		if !ok {
+			tq.mux.Unlock()
+			return context.WithDeadline(parent, c.deadline)
+		}
+		c.handle = tq.insert(TimeoutAction(c.expire), nil, c.deadline)
+		tq.rearm()
+		tq.mux.Unlock()
+		ev.dispatch(tq)
+	}
+	if d, ok := parent.Deadline(); ok && d.Before(c.deadline) {
+		c.deadline = d
+	}
+	stop := context.AfterFunc(parent, func() {
+		c.cancel(parent.Err())
+	})
+	c.mux.Lock()
+	c.stop = stop
+	canceled := c.err != nil
+	c.mux.Unlock()
+	if canceled {
+		// canceled before stop was set, so cancel could not call it
+		stop()
+	}
+	return c, func() {
+		c.cancel(context.Canceled)
+	}
+}
+
+// queueCtx is the context returned by WithTimeoutCtx.
+type queueCtx struct {
+	parent   context.Context
+	deadline time.Time
+	handle   Handle
+	done     chan struct{}
+	mux      sync.Mutex
+	err      error
+	stop     func() bool
+}
+
+func (c *queueCtx) expire() {
+	c.cancel(context.DeadlineExceeded)
+}
+
+func (c *queueCtx) cancel(err error) {
+	c.mux.Lock()
+	if c.err != nil {
+		c.mux.Unlock()
+		return
+	}
+	c.err = err
+	close(c.done)
+	stop := c.stop
+	c.mux.Unlock()
+	c.handle.Cancel()
+	if stop != nil {
+		stop()
+	}
+}
+
+func (c *queueCtx) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *queueCtx) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *queueCtx) Err() error {
+	c.mux.Lock()
+	err := c.err
+	c.mux.Unlock()
+	return err
+}
+
+func (c *queueCtx) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,62 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey struct{}
+
+func TestWithTimeoutCtx(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*10, 10)
+	parent := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	start := time.Now()
+	ctx, cancel := tq.WithTimeoutCtx(parent)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.True(t, deadline.After(start))
+	assert.Equal(t, "value", ctx.Value(ctxKey{}))
+	assert.NoError(t, ctx.Err())
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Error("context did not time out")
+	}
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+
+	ctx, cancel = tq.WithTimeoutCtx(parent)
+	assert.Equal(t, 1, tq.Len())
+	cancel()
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.Equal(t, 0, tq.Len())
+	cancel()
+}
+
+func TestWithTimeoutCtxParent(t *testing.T) {
+	tq := timeoutqueue.New(time.Hour, 10)
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := tq.WithTimeoutCtx(parent)
+	defer cancel()
+	cancelParent()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Error("context was not canceled with it's parent")
+	}
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.Equal(t, 0, tq.Len())
+
+	// a parent that is already done
+	ctx, cancel = tq.WithTimeoutCtx(parent)
+	defer cancel()
+	<-ctx.Done()
+	assert.Equal(t, 0, tq.Len())
+}
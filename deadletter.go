@@ -0,0 +1,112 @@
+package timeoutqueue
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DeadLetter is a record of a TimeoutAction that failed, see SetDeadLetter.
+type DeadLetter struct {
+	// ID is the correlation ID of the action, see AddWithID.
+	ID interface{}
+	// Deadline is when the action was due and Fired when it was dispatched.
+	Deadline time.Time
+	Fired    time.Time
+	Reason   Reason
+	// Err describes the failure. For an action that panicked it is a
+	// *PanicError.
+	Err error
+}
+
+// PanicError is the error in a DeadLetter for an action that panicked.
+type PanicError struct {
+	Value interface{}
+}
+
+// Error fulfills error.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("timeoutqueue: action panicked: %v", e.Value)
+}
+
+// DeadLetterAction receives a DeadLetter for every TimeoutAction that fails.
+type DeadLetterAction func(DeadLetter)
+
+// SetDeadLetter recovers any TimeoutAction that panics and passes a record of it
+// to onDead instead of crashing the program. It is called from the Go routine
+// that ran the action once it has panicked. Passing nil stops recovering panics.
+func (tq *TimeoutQueue) SetDeadLetter(onDead DeadLetterAction) {
+	tq.mux.Lock()
+	tq.dispatcher.onDead = onDead
+	tq.mux.Unlock()
+}
+
+// recoverDead must be deferred. If the action on n panicked it sends a
+// DeadLetter to the dispatcher's onDead.
+func (d dispatcher) recoverDead(n node, fired time.Time, reason Reason) {
+	if r := recover(); r != nil {
+		d.onDead(DeadLetter{
+			ID:       n.id,
+			Deadline: n.timeout,
+			Fired:    fired,
+			Reason:   reason,
+			Err:      &PanicError{Value: r},
+		})
+	}
+}
+
+// DeadLetterBuffer keeps the most recent DeadLetters for later inspection. Its
+// Add method is a DeadLetterAction, and can be passed to SetDeadLetter. When it
+// is full the oldest DeadLetter is dropped.
+type DeadLetterBuffer struct {
+	mux     sync.Mutex
+	letters []DeadLetter
+	next    int
+	full    bool
+	dropped uint64
+}
+
+// NewDeadLetterBuffer returns a DeadLetterBuffer that holds up to size
+// DeadLetters. It panics if size is less than one.
+func NewDeadLetterBuffer(size int) *DeadLetterBuffer {
+	if size < 1 {
+		panic("timeoutqueue: DeadLetterBuffer size must be at least one")
+	}
+	return &DeadLetterBuffer{
+		letters: make([]DeadLetter, size),
+	}
+}
+
+// Add a DeadLetter to the buffer.
+func (b *DeadLetterBuffer) Add(dl DeadLetter) {
+	b.mux.Lock()
+	if b.full {
+		b.dropped++
+	}
+	b.letters[b.next] = dl
+	b.next++
+	if b.next == len(b.letters) {
+		b.next = 0
+		b.full = true
+	}
+	b.mux.Unlock()
+}
+
+// Letters returns the DeadLetters in the buffer, oldest first.
+func (b *DeadLetterBuffer) Letters() []DeadLetter {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if !b.full {
+		return append([]DeadLetter(nil), b.letters[:b.next]...)
+	}
+	out := make([]DeadLetter, 0, len(b.letters))
+	out = append(out, b.letters[b.next:]...)
+	return append(out, b.letters[:b.next]...)
+}
+
+// Dropped returns how many DeadLetters were dropped to make room for newer ones.
+func (b *DeadLetterBuffer) Dropped() uint64 {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.dropped
+}
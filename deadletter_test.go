@@ -0,0 +1,36 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetter(t *testing.T) {
+	tq := timeoutqueue.New(time.Second, 10)
+	tq.SetExecutor(timeoutqueue.Inline)
+	buf := timeoutqueue.NewDeadLetterBuffer(2)
+	tq.SetDeadLetter(buf.Add)
+
+	tq.AddWithID("a", func(interface{}) { panic("boom") })
+	tq.AddWithID("b", func(interface{}) {})
+	tq.Flush()
+	letters := buf.Letters()
+	if assert.Len(t, letters, 1) {
+		assert.Equal(t, "a", letters[0].ID)
+		assert.Equal(t, timeoutqueue.ReasonFlush, letters[0].Reason)
+		assert.Equal(t, "timeoutqueue: action panicked: boom", letters[0].Err.Error())
+	}
+
+	for _, id := range []string{"c", "d"} {
+		tq.AddWithID(id, func(interface{}) { panic(id) })
+	}
+	tq.Drain()
+	letters = buf.Letters()
+	assert.Len(t, letters, 2)
+	assert.Equal(t, "c", letters[0].ID)
+	assert.Equal(t, "d", letters[1].ID)
+	assert.Equal(t, uint64(1), buf.Dropped())
+}
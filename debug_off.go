@@ -0,0 +1,5 @@
+//go:build !timeoutqueuedebug
+
+package timeoutqueue
+
+const debug = false
@@ -0,0 +1,77 @@
+package timeoutqueue
+
+import (
+	"time"
+)
+
+// DiscontinuityPolicy decides what happens to the queue when the runner wakes
+// much later than it meant to, as happens after a laptop sleeps or a VM is
+// migrated, and everything in the queue is suddenly overdue.
+type DiscontinuityPolicy uint8
+
+const (
+	// FireAll fires everything that is overdue, the same as with no policy.
+	FireAll DiscontinuityPolicy = iota
+	// DropAll cancels everything that is overdue, passing the correlation ID of
+	// each to the drop callback.
+	DropAll
+	// Rebase moves every deadline forward by the length of the discontinuity,
+	// so everything keeps the duration it had remaining before it.
+	Rebase
+)
+
+type discontinuity struct {
+	threshold time.Duration
+	policy    DiscontinuityPolicy
+	onDrop    func(id interface{})
+}
+
+// SetDiscontinuityPolicy applies policy whenever the runner wakes more than
+// threshold later than it meant to. With DropAll, onDrop is called through the
+// Executor for each action dropped if it is not nil. A threshold of zero or less
+// turns off detection. Only the runner detects discontinuities, a queue driven
+// by Tick is not affected.
+func (tq *TimeoutQueue) SetDiscontinuityPolicy(threshold time.Duration, policy DiscontinuityPolicy, onDrop func(id interface{})) {
+	tq.mux.Lock()
+	tq.discontinuity = discontinuity{
+		threshold: threshold,
+		policy:    policy,
+		onDrop:    onDrop,
+	}
+	tq.mux.Unlock()
+}
+
+// checkDiscontinuity requires a mux lock. The runner calls it with how much
+// later than expected it woke. If anything was dropped, it returns a func to
+// call the drop callbacks which must be called after unlocking.
+func (tq *TimeoutQueue) checkDiscontinuity(gap time.Duration, now time.Time) func() {
+	dc := tq.discontinuity
+	if dc.threshold <= 0 || gap <= dc.threshold {
+		return nil
+	}
+	switch dc.policy {
+	case DropAll:
+		var dropped []interface{}
+		for tq.head != empty && !tq.deadline(tq.head).After(now) {
+			dropped = append(dropped, tq.nodes[tq.head].id)
+			tq.emit(EventCanceled, tq.head)
+			tq.freeNode(tq.head)
+		}
+		if dc.onDrop == nil || len(dropped) == 0 {
+			return nil
+		}
+		d := tq.dispatcher
+		return func() {
+			for _, id := range dropped {
+				id := id
+				d.goFunc(func() {
+					dc.onDrop(id)
+				})
+			}
+		}
+	case Rebase:
+		tq.shift += gap
+		tq.publishNext()
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscontinuityPolicy(t *testing.T) {
+	for _, policy := range []DiscontinuityPolicy{FireAll, DropAll, Rebase} {
+		clock := &jumpClock{}
+		tq := New(time.Millisecond*20, 10)
+		tq.clock = clock
+		tq.SetExecutor(Inline)
+		fired := make(chan interface{}, 2)
+		dropped := make(chan interface{}, 2)
+		tq.SetDiscontinuityPolicy(time.Minute, policy, func(id interface{}) {
+			dropped <- id
+		})
+
+		action := func(id interface{}) { fired <- id }
+		tq.AddWithID(1, action)
+		tq.AddWithID(2, action)
+		time.Sleep(time.Millisecond * 5)
+		clock.jump(time.Hour)
+		time.Sleep(time.Millisecond * 30)
+
+		switch policy {
+		case FireAll:
+			assert.Len(t, fired, 2)
+			assert.Len(t, dropped, 0)
+		case DropAll:
+			assert.Len(t, fired, 0)
+			assert.Len(t, dropped, 2)
+			assert.Equal(t, 0, tq.Len())
+		case Rebase:
+			// the entries had 15ms left before the jump and keep it
+			assert.Len(t, dropped, 0)
+			select {
+			case <-fired:
+			case <-time.After(time.Millisecond * 200):
+				t.Error("rebased entries did not fire")
+			}
+		}
+	}
+}
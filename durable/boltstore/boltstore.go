@@ -0,0 +1,79 @@
+// Package boltstore is a durable.Store backed by a bbolt database.
+package boltstore
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/durable"
+	"go.etcd.io/bbolt"
+)
+
+// Store keeps Records in a bbolt bucket keyed by their deadline so they are
+// stored in deadline order.
+type Store struct {
+	db     *bbolt.DB
+	bucket []byte
+}
+
+// New returns a Store using the named bucket in db, creating it if needed.
+func New(db *bbolt.DB, bucket string) (*Store, error) {
+	s := &Store{
+		db:     db,
+		bucket: []byte(bucket),
+	}
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(s.bucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// key is the big endian deadline in Unix nanoseconds followed by the Record's
+// Key, so bbolt's byte ordering is deadline order.
+func key(r durable.Record) []byte {
+	k := make([]byte, 8, 8+len(r.Key))
+	binary.BigEndian.PutUint64(k, uint64(r.Deadline.UnixNano()))
+	return append(k, r.Key...)
+}
+
+// Load fulfills durable.Store.
+func (s *Store) Load(fn func(durable.Record) error) error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(s.bucket).Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			if len(k) < 8 {
+				continue
+			}
+			err := fn(durable.Record{
+				Deadline: time.Unix(0, int64(binary.BigEndian.Uint64(k))),
+				Key:      string(k[8:]),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Commit fulfills durable.Store.
+func (s *Store) Commit(put, del []durable.Record) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		for _, r := range del {
+			if err := b.Delete(key(r)); err != nil {
+				return err
+			}
+		}
+		for _, r := range put {
+			if err := b.Put(key(r), []byte{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
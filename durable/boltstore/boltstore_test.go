@@ -0,0 +1,38 @@
+package boltstore_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/durable"
+	"github.com/dist-ribut-us/timeoutqueue/durable/boltstore"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func TestStore(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "timers.db"), 0600, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	s, err := boltstore.New(db, "timers")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	now := time.Unix(1000, 0)
+	a := durable.Record{Deadline: now.Add(time.Second), Key: "a"}
+	b := durable.Record{Deadline: now, Key: "b"}
+	c := durable.Record{Deadline: now.Add(time.Second * 2), Key: "c"}
+	assert.NoError(t, s.Commit([]durable.Record{a, b, c}, nil))
+	assert.NoError(t, s.Commit(nil, []durable.Record{c, {Key: "missing"}}))
+
+	var loaded []durable.Record
+	assert.NoError(t, s.Load(func(r durable.Record) error {
+		loaded = append(loaded, r)
+		return nil
+	}))
+	assert.Equal(t, []durable.Record{b, a}, loaded)
+}
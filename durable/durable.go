@@ -0,0 +1,171 @@
+// Package durable mirrors the timers in a TimeoutQueue to a Store so they
+// survive a crash or restart. Timers are written before Add returns, but
+// canceled and fired timers are deleted lazily in batches, so after a crash a
+// timer may fire again; onFire must tolerate being called more than once for
+// the same key.
+package durable
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+)
+
+// Record is a timer as held by a Store. A Record is identified by both it's
+// Deadline and Key.
+type Record struct {
+	Deadline time.Time
+	Key      string
+}
+
+// Store is the storage backing a Queue.
+type Store interface {
+	// Load calls fn for every Record in the Store, ideally in Deadline order.
+	Load(fn func(Record) error) error
+	// Commit deletes and puts the Records in a single transaction. Deleting a
+	// Record that is not in the Store is not an error.
+	Commit(put, del []Record) error
+}
+
+// SyncBatch is the number of lazy deletes that are allowed to build up before
+// they are committed without waiting for an Add or Sync.
+const SyncBatch = 256
+
+// Queue is a TimeoutQueue with every timer mirrored to a Store.
+type Queue struct {
+	mux     sync.Mutex
+	tq      *timeoutqueue.TimeoutQueue
+	store   Store
+	onFire  func(key string)
+	deletes []Record
+	fire    timeoutqueue.CorrelatedAction
+}
+
+// Open a Queue on store, restoring every timer in it. Timers whose deadline
+// passed while the Queue was not open fire straight away. The onFire func is
+// called with the key of each timer that fires.
+func Open(store Store, timeout time.Duration, onFire func(key string)) (*Queue, error) {
+	q := &Queue{
+		tq:     timeoutqueue.New(timeout, 0),
+		store:  store,
+		onFire: onFire,
+	}
+	q.fire = q.fired
+
+	var entries []timeoutqueue.Entry
+	now := time.Now()
+	err := store.Load(func(r Record) error {
+		entries = append(entries, timeoutqueue.Entry{
+			ID:        r,
+			Remaining: r.Deadline.Sub(now),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	q.tq.Import(nil, entries, func(interface{}) timeoutqueue.CorrelatedAction {
+		return q.fire
+	})
+	return q, nil
+}
+
+func (q *Queue) fired(id interface{}) {
+	r := id.(Record)
+	q.onFire(r.Key)
+	q.lazyDelete(r)
+}
+
+func (q *Queue) lazyDelete(r Record) {
+	q.mux.Lock()
+	q.deletes = append(q.deletes, r)
+	if len(q.deletes) >= SyncBatch {
+		// on failure the deletes are kept for the next commit
+		q.commit(nil)
+	}
+	q.mux.Unlock()
+}
+
+// commit requires the Queue's mux lock.
+func (q *Queue) commit(put []Record) error {
+	err := q.store.Commit(put, q.deletes)
+	if err == nil {
+		q.deletes = q.deletes[:0]
+	}
+	return err
+}
+
+// Timer is a timer in a Queue.
+type Timer struct {
+	q      *Queue
+	handle timeoutqueue.Handle
+	record Record
+}
+
+// Add a timer for key, which is written to the Store before Add returns. If
+// the write fails, the timer is not added.
+func (q *Queue) Add(key string) (Timer, error) {
+	r := Record{
+		Deadline: time.Now().Add(q.tq.Timeout()).Round(0),
+		Key:      key,
+	}
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if err := q.commit([]Record{r}); err != nil {
+		return Timer{}, err
+	}
+	h := q.tq.Import(nil, []timeoutqueue.Entry{{
+		ID:        r,
+		Remaining: time.Until(r.Deadline),
+	}}, func(interface{}) timeoutqueue.CorrelatedAction {
+		return q.fire
+	})
+	return Timer{
+		q:      q,
+		handle: h[0],
+		record: r,
+	}, nil
+}
+
+// Cancel the timer. It is deleted from the Store lazily. The returned bool is
+// false if the timer had already fired or been canceled.
+func (t Timer) Cancel() bool {
+	if t.q == nil || !t.handle.Cancel() {
+		return false
+	}
+	t.q.lazyDelete(t.record)
+	return true
+}
+
+// Key returns the key the timer was added with.
+func (t Timer) Key() string {
+	return t.record.Key
+}
+
+// Deadline returns when the timer fires.
+func (t Timer) Deadline() time.Time {
+	return t.record.Deadline
+}
+
+// Sync commits any lazy deletes to the Store.
+func (q *Queue) Sync() error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if len(q.deletes) == 0 {
+		return nil
+	}
+	return q.commit(nil)
+}
+
+// Len returns the number of timers waiting to fire.
+func (q *Queue) Len() int {
+	return q.tq.Len()
+}
+
+// Close stops every timer without firing or deleting them, so they are
+// restored the next time the Store is opened, and syncs the lazy deletes.
+func (q *Queue) Close() error {
+	q.tq.CancelIf(func(timeoutqueue.Token) bool { return true })
+	return q.Sync()
+}
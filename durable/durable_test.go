@@ -0,0 +1,93 @@
+//go:build !timeoutqueuesingle
+
+package durable_test
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/durable"
+	"github.com/stretchr/testify/assert"
+)
+
+type memStore struct {
+	mux     sync.Mutex
+	records map[durable.Record]bool
+	commits int
+}
+
+func (m *memStore) Load(fn func(durable.Record) error) error {
+	m.mux.Lock()
+	var rs []durable.Record
+	for r := range m.records {
+		rs = append(rs, r)
+	}
+	m.mux.Unlock()
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Deadline.Before(rs[j].Deadline) })
+	for _, r := range rs {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memStore) Commit(put, del []durable.Record) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.commits++
+	for _, r := range del {
+		delete(m.records, r)
+	}
+	for _, r := range put {
+		m.records[r] = true
+	}
+	return nil
+}
+
+func (m *memStore) len() int {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return len(m.records)
+}
+
+func TestQueue(t *testing.T) {
+	store := &memStore{records: make(map[durable.Record]bool)}
+	fired := make(chan string, 10)
+	onFire := func(key string) { fired <- key }
+
+	q, err := durable.Open(store, time.Millisecond*20, onFire)
+	assert.NoError(t, err)
+	a, err := q.Add("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", a.Key())
+	b, err := q.Add("b")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, store.len())
+
+	assert.True(t, b.Cancel())
+	assert.False(t, b.Cancel())
+	assert.False(t, durable.Timer{}.Cancel())
+	// the cancel is lazy
+	assert.Equal(t, 2, store.len())
+	assert.NoError(t, q.Close())
+	assert.Equal(t, 1, store.len())
+	assert.Equal(t, 0, q.Len())
+
+	// a is restored when the store is opened again
+	q, err = durable.Open(store, time.Millisecond*20, onFire)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, q.Len())
+	select {
+	case key := <-fired:
+		assert.Equal(t, "a", key)
+	case <-time.After(time.Millisecond * 200):
+		t.Error("restored timer did not fire")
+	}
+	time.Sleep(time.Millisecond * 5)
+	assert.Equal(t, 1, store.len())
+	assert.NoError(t, q.Sync())
+	assert.Equal(t, 0, store.len())
+}
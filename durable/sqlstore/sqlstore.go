@@ -0,0 +1,148 @@
+// Package sqlstore is a durable.Store backed by a table in a relational
+// database through database/sql.
+package sqlstore
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/durable"
+)
+
+// DefaultBatch is the number of rows Load reads per query.
+const DefaultBatch = 1000
+
+// Store keeps Records in a table with a primary key on (deadline, timer_key),
+// so the table can be queried by deadline. The deadline is stored in Unix
+// nanoseconds.
+type Store struct {
+	db    *sql.DB
+	table string
+	batch int
+	// Placeholder returns the bind parameter for the nth argument of a query,
+	// counting from 1. It defaults to "?", use Dollar for databases like
+	// PostgreSQL.
+	Placeholder func(n int) string
+}
+
+// New returns a Store using table in db. The table name is placed in the
+// queries as is, so it must not come from an untrusted source.
+func New(db *sql.DB, table string) *Store {
+	return &Store{
+		db:          db,
+		table:       table,
+		batch:       DefaultBatch,
+		Placeholder: Question,
+	}
+}
+
+// Question is the Placeholder used by MySQL and SQLite.
+func Question(int) string {
+	return "?"
+}
+
+// Dollar is the Placeholder used by PostgreSQL.
+func Dollar(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// SetBatch sets the number of rows Load reads per query. Reading in batches
+// keeps a single query from holding a very large result set open.
+func (s *Store) SetBatch(batch int) {
+	if batch > 0 {
+		s.batch = batch
+	}
+}
+
+// CreateTable creates the table if it does not exist.
+func (s *Store) CreateTable() error {
+	_, err := s.db.Exec("CREATE TABLE IF NOT EXISTS " + s.table +
+		" (deadline BIGINT NOT NULL, timer_key VARCHAR(255) NOT NULL, PRIMARY KEY (deadline, timer_key))")
+	return err
+}
+
+// Load fulfills durable.Store. It pages through the table in deadline order.
+func (s *Store) Load(fn func(durable.Record) error) error {
+	first := "SELECT deadline, timer_key FROM " + s.table +
+		" ORDER BY deadline, timer_key LIMIT " + strconv.Itoa(s.batch)
+	next := "SELECT deadline, timer_key FROM " + s.table +
+		" WHERE deadline > " + s.Placeholder(1) +
+		" OR (deadline = " + s.Placeholder(2) + " AND timer_key > " + s.Placeholder(3) + ")" +
+		" ORDER BY deadline, timer_key LIMIT " + strconv.Itoa(s.batch)
+
+	var deadline int64
+	var key string
+	rows, err := s.db.Query(first)
+	for {
+		if err != nil {
+			return err
+		}
+		n := 0
+		for rows.Next() {
+			if err = rows.Scan(&deadline, &key); err != nil {
+				rows.Close()
+				return err
+			}
+			n++
+			err = fn(durable.Record{
+				Deadline: time.Unix(0, deadline),
+				Key:      key,
+			})
+			if err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		if err = rows.Close(); err != nil {
+			return err
+		}
+		if err = rows.Err(); err != nil || n < s.batch {
+			return err
+		}
+		rows, err = s.db.Query(next, deadline, deadline, key)
+	}
+}
+
+// Commit fulfills durable.Store.
+func (s *Store) Commit(put, del []durable.Record) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = s.commit(tx, put, del); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) commit(tx *sql.Tx, put, del []durable.Record) error {
+	if len(del) > 0 {
+		stmt, err := tx.Prepare("DELETE FROM " + s.table +
+			" WHERE deadline = " + s.Placeholder(1) + " AND timer_key = " + s.Placeholder(2))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, r := range del {
+			if _, err = stmt.Exec(r.Deadline.UnixNano(), r.Key); err != nil {
+				return err
+			}
+		}
+	}
+	if len(put) > 0 {
+		stmt, err := tx.Prepare("INSERT INTO " + s.table +
+			" (deadline, timer_key) VALUES (" + s.Placeholder(1) + ", " + s.Placeholder(2) + ")")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, r := range put {
+			if _, err = stmt.Exec(r.Deadline.UnixNano(), r.Key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package sqlstore_test
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/durable"
+	"github.com/dist-ribut-us/timeoutqueue/durable/sqlstore"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func TestStore(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	s := sqlstore.New(db, "timers")
+	assert.NoError(t, s.CreateTable())
+	s.SetBatch(2)
+
+	now := time.Unix(1000, 0)
+	var put []durable.Record
+	for i := 0; i < 5; i++ {
+		put = append(put, durable.Record{
+			Deadline: now.Add(time.Second * time.Duration(i/2)),
+			Key:      fmt.Sprint(i),
+		})
+	}
+	assert.NoError(t, s.Commit(put, nil))
+	assert.NoError(t, s.Commit(nil, []durable.Record{put[3], {Key: "missing"}}))
+	assert.Error(t, s.Commit([]durable.Record{put[0]}, nil))
+
+	var loaded []durable.Record
+	assert.NoError(t, s.Load(func(r durable.Record) error {
+		loaded = append(loaded, r)
+		return nil
+	}))
+	assert.Equal(t, []durable.Record{put[0], put[1], put[2], put[4]}, loaded)
+	assert.Equal(t, "$2", sqlstore.Dollar(2))
+}
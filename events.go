@@ -0,0 +1,158 @@
+package timeoutqueue
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// EventType identifies the kind of activity an Event describes.
+type EventType uint8
+
+// The types of Event that can be sent to a Subscription.
+const (
+	// EventAdded is sent when a TimeoutAction is added to the queue.
+	EventAdded EventType = iota
+	// EventFired is sent when a TimeoutAction is called, either because it
+	// timed out or because it was flushed.
+	EventFired
+	// EventCanceled is sent when a TimeoutAction is canceled.
+	EventCanceled
+	// EventReset is sent when a TimeoutAction's timeout is reset.
+	EventReset
+	// EventGrew is sent when the queue grows it's internal slice. The Token of
+	// the Event will be nil.
+	EventGrew
+	// EventLate is sent when a TimeoutAction is skipped because it was past
+	// the late threshold, see SetLateThreshold.
+	EventLate
+	// EventRejected is sent when a TimeoutAction is not added because a queue
+	// from NewFixed is full, see RejectWhenFull. The Token of the Event will be
+	// nil.
+	EventRejected
+
+	eventTypes = iota
+)
+
+var eventTypeNames = [...]string{
+	EventAdded:    "Added",
+	EventFired:    "Fired",
+	EventCanceled: "Canceled",
+	EventReset:    "Reset",
+	EventGrew:     "Grew",
+	EventLate:     "Late",
+	EventRejected: "Rejected",
+}
+
+func (et EventType) String() string {
+	if int(et) < len(eventTypeNames) {
+		return eventTypeNames[et]
+	}
+	return "Unknown"
+}
+
+// Event describes activity on a TimeoutQueue. ID is the correlation ID of the
+// TimeoutAction, see AddWithID.
+type Event struct {
+	Type  EventType
+	Token Token
+	ID    interface{}
+	Time  time.Time
+}
+
+// Subscription receives Events from a TimeoutQueue on C. Events are never
+// allowed to block the queue; if C is full the Event is dropped and counted.
+type Subscription struct {
+	// dropped is first to guarentee 64 bit alignment for atomic operations
+	dropped uint64
+	C       <-chan Event
+	ch      chan Event
+	tq      *TimeoutQueue
+}
+
+// Subscribe returns a Subscription that will receive Events from the queue. The
+// buffer sets the capacity of the Subscription's channel.
+func (tq *TimeoutQueue) Subscribe(buffer int) *Subscription {
+	ch := make(chan Event, buffer)
+	s := &Subscription{
+		C:  ch,
+		ch: ch,
+		tq: tq,
+	}
+	tq.mux.Lock()
+	tq.subs = append(tq.subs, s)
+	tq.mux.Unlock()
+	return s
+}
+
+// Dropped returns the number of Events that could not be sent because the
+// Subscription's channel was full.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close removes the Subscription from the queue and closes C. It is safe to
+// call Close more than once.
+func (s *Subscription) Close() {
+	tq := s.tq
+	tq.mux.Lock()
+	for i, sub := range tq.subs {
+		if sub == s {
+			copy(tq.subs[i:], tq.subs[i+1:])
+			tq.subs[len(tq.subs)-1] = nil
+			tq.subs = tq.subs[:len(tq.subs)-1]
+			close(s.ch)
+			break
+		}
+	}
+	tq.mux.Unlock()
+}
+
+// emit requires a mux lock, see the note on add. The Event's Token will be nil
+// if nodeIdx is empty, otherwise it is the token for the node's current action
+// so emit must be called before a node is freed.
+func (tq *TimeoutQueue) emit(et EventType, nodeIdx uint32) {
+	atomic.AddUint64(&tq.counters[et], 1)
+	if tq.tenants != nil && nodeIdx != empty && tq.nodes[nodeIdx].tenant != 0 {
+		tq.tenants.list[tq.nodes[nodeIdx].tenant-1].count(et)
+	}
+	if tq.tags != nil && nodeIdx != empty && tq.nodes[nodeIdx].tag != 0 {
+		tq.tags.stats[tq.nodes[nodeIdx].tag-1].count(et)
+	}
+	if len(tq.subs) == 0 && tq.audit.events == nil {
+		return
+	}
+	e := Event{
+		Type: et,
+		Time: tq.clockNow(),
+	}
+	if nodeIdx != empty {
+		e.Token = tq.token(nodeIdx)
+		e.ID = tq.nodes[nodeIdx].id
+	}
+	tq.send(e)
+}
+
+// emitID requires a mux lock. It is used for actions that were never assigned a
+// node.
+func (tq *TimeoutQueue) emitID(et EventType, id interface{}) {
+	atomic.AddUint64(&tq.counters[et], 1)
+	if len(tq.subs) == 0 && tq.audit.events == nil {
+		return
+	}
+	tq.send(Event{
+		Type: et,
+		ID:   id,
+		Time: tq.clockNow(),
+	})
+}
+
+func (tq *TimeoutQueue) send(e Event) {
+	tq.audit.add(e)
+	for _, s := range tq.subs {
+		select {
+		case s.ch <- e:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
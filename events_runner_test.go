@@ -0,0 +1,45 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeout"
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribe(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 1)
+	sub := tq.Subscribe(10)
+	ch := make(chan int)
+
+	tkn := tq.Add(getAction(ch, 1))
+	tq.Add(getAction(ch, 2))
+	assert.True(t, tkn.Reset())
+	assert.True(t, tkn.Cancel())
+	assert.NoError(t, timeout.After(20, ch))
+
+	expected := []timeoutqueue.EventType{
+		timeoutqueue.EventAdded,
+		timeoutqueue.EventGrew,
+		timeoutqueue.EventAdded,
+		timeoutqueue.EventReset,
+		timeoutqueue.EventCanceled,
+		timeoutqueue.EventFired,
+	}
+	for _, et := range expected {
+		e := <-sub.C
+		assert.Equal(t, et, e.Type, et.String())
+		if et == timeoutqueue.EventGrew {
+			assert.Nil(t, e.Token)
+		}
+	}
+
+	sub.Close()
+	sub.Close()
+	_, open := <-sub.C
+	assert.False(t, open)
+}
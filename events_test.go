@@ -0,0 +1,23 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionDropped(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
+	sub := tq.Subscribe(1)
+	defer sub.Close()
+
+	tkn := tq.Add(func() {})
+	assert.True(t, tkn.Cancel())
+	assert.Equal(t, uint64(1), sub.Dropped())
+
+	e := <-sub.C
+	assert.Equal(t, timeoutqueue.EventAdded, e.Type)
+	assert.Equal(t, tkn, e.Token)
+}
@@ -0,0 +1,148 @@
+package timeoutqueue
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Executor runs the TimeoutActions that fire from the queue. By default each
+// action is called in it's own Go routine, an Executor can instead route them
+// into a worker pool or run them synchronously in tests. Actions called from
+// Flush, FlushWhere or Close do not go through the Executor.
+type Executor interface {
+	Go(func())
+}
+
+// Inline is an Executor that calls actions directly from the Go routine that
+// fires them, usually the runner. It avoids both the Go routine and any
+// allocation per action, but a slow action delays everything behind it, so it
+// is only suitable for actions that do very little.
+var Inline Executor = inline{}
+
+type inline struct{}
+
+func (inline) Go(action func()) {
+	action()
+}
+
+// ExecutorFunc allows a func to be used as an Executor.
+type ExecutorFunc func(func())
+
+// Go fulfills Executor.
+func (fn ExecutorFunc) Go(action func()) {
+	fn(action)
+}
+
+// SetExecutor sets the Executor used to call TimeoutActions when they fire.
+// Passing nil restores the default of calling each in it's own Go routine.
+func (tq *TimeoutQueue) SetExecutor(exec Executor) {
+	tq.mux.Lock()
+	tq.dispatcher.exec = exec
+	tq.mux.Unlock()
+}
+
+// dispatcher holds everything needed to call an action. The queue's dispatcher
+// is copied while holding the mux lock so that actions can be dispatched after
+// unlocking.
+type dispatcher struct {
+	exec      Executor
+	onLate    LateAction
+	budget    time.Duration
+	onSlow    SlowAction
+	onDead    DeadLetterAction
+	listeners []fireListener
+}
+
+func (d dispatcher) dispatch(tq *TimeoutQueue, n node, fired time.Time, reason Reason) {
+	if d.exec == nil {
+		tq.goAction(func() {
+			d.call(n, fired, reason)
+		})
+		return
+	}
+	if d.exec == Inline {
+		d.call(n, fired, reason)
+		return
+	}
+	d.exec.Go(func() {
+		d.call(n, fired, reason)
+	})
+}
+
+// dispatchFired dispatches an action that was on node idx of tq, which must
+// have been counted as in flight, and counts it as landed once it returns.
+func (d dispatcher) dispatchFired(tq *TimeoutQueue, idx uint32, n node, fired time.Time, reason Reason) {
+	if d.exec == Inline {
+		d.callFired(tq, idx, n, fired, reason)
+		return
+	}
+	call := func() {
+		d.callFired(tq, idx, n, fired, reason)
+	}
+	if d.exec == nil {
+		tq.goAction(call)
+		return
+	}
+	d.exec.Go(call)
+}
+
+// callFired runs an action for dispatchFired in the current Go routine. It is
+// counted as landed even if it panics, as an Executor may recover the panic and
+// carry on, which would otherwise leave CancelAndWait waiting forever.
+func (d dispatcher) callFired(tq *TimeoutQueue, idx uint32, n node, fired time.Time, reason Reason) {
+	tq.executeStarted()
+	defer func() {
+		atomic.AddUint64(&tq.executing, ^uint64(0))
+		tq.landed(idx)
+	}()
+	d.call(n, fired, reason)
+}
+
+// executeStarted counts a fired action as executing, raising the maximum if
+// there have never been as many at once.
+func (tq *TimeoutQueue) executeStarted() {
+	cur := atomic.AddUint64(&tq.executing, 1)
+	for {
+		max := atomic.LoadUint64(&tq.maxExecuting)
+		if cur <= max || atomic.CompareAndSwapUint64(&tq.maxExecuting, max, cur) {
+			return
+		}
+	}
+}
+
+// landed records that an action fired from node idx has returned.
+func (tq *TimeoutQueue) landed(idx uint32) {
+	tq.mux.Lock()
+	tq.land(idx)
+	tq.mux.Unlock()
+}
+
+// land requires a mux lock. It is the same as landed.
+func (tq *TimeoutQueue) land(idx uint32) {
+	// the nodes may have been replaced by ReadFrom while the action ran
+	if int(idx) < len(tq.nodes) && tq.nodes[idx].inflight > 0 {
+		tq.nodes[idx].inflight--
+		if tq.nodes[idx].inflight == 0 && tq.landing != nil {
+			tq.landing.Broadcast()
+		}
+	}
+}
+
+// call runs the action in the current Go routine.
+func (d dispatcher) call(n node, fired time.Time, reason Reason) {
+	if d.onDead != nil {
+		defer d.recoverDead(n, fired, reason)
+	}
+	if len(d.listeners) > 0 {
+		defer d.notify(n, fired, reason)
+	}
+	if d.budget <= 0 || d.onSlow == nil {
+		n.call(fired, reason)
+		return
+	}
+	start := time.Now()
+	n.call(fired, reason)
+	if took := time.Since(start); took > d.budget {
+		d.onSlow(n.id, took)
+	}
+}
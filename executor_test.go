@@ -0,0 +1,55 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeout"
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutor(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*2, 10)
+	ch := make(chan int, 2)
+	pool := make(chan func(), 2)
+	tq.SetExecutor(timeoutqueue.ExecutorFunc(func(action func()) {
+		pool <- action
+	}))
+
+	tq.Add(getAction(ch, 1))
+	tq.AddWithID(2, func(id interface{}) {
+		ch <- id.(int)
+	})
+
+	assert.NoError(t, timeout.After(20, func() {
+		(<-pool)()
+		(<-pool)()
+	}))
+	assert.Equal(t, 1, <-ch)
+	assert.Equal(t, 2, <-ch)
+
+	tq.SetExecutor(nil)
+	tq.Add(getAction(ch, 3))
+	assert.NoError(t, timeout.After(20, func() {
+		assert.Equal(t, 3, <-ch)
+	}))
+}
+
+func TestExecutorRecovers(t *testing.T) {
+	tq := timeoutqueue.NewManual(time.Millisecond, 10, nil)
+	// an Executor that recovers panics so one bad action does not stop it
+	tq.SetExecutor(timeoutqueue.ExecutorFunc(func(action func()) {
+		defer func() { recover() }()
+		action()
+	}))
+	tkn := tq.Add(func() { panic("boom") })
+	time.Sleep(time.Millisecond * 2)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, uint64(0), tq.Stats().Executing)
+	assert.NoError(t, timeout.After(20, func() {
+		assert.False(t, tkn.CancelAndWait())
+	}))
+}
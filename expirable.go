@@ -0,0 +1,19 @@
+package timeoutqueue
+
+// Expirable is a value that can be added to the queue directly, see
+// AddExpirable.
+type Expirable interface {
+	OnTimeout()
+}
+
+// AddExpirable adds an Expirable to the queue, which has OnTimeout called when
+// it times out. A domain type can then be queued as it is, storing a single
+// interface value in the queue rather than a closure around the type. It panics
+// if e is nil.
+func (tq *TimeoutQueue) AddExpirable(e Expirable) Token {
+	if e == nil {
+		panic("timeoutqueue: nil Expirable")
+	}
+	tq.mux.Lock()
+	return tq.addAction(e, tq.hookID())
+}
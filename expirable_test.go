@@ -0,0 +1,28 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+type session struct {
+	expired bool
+}
+
+func (s *session) OnTimeout() {
+	s.expired = true
+}
+
+func TestExpirable(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	a, b := &session{}, &session{}
+	q.AddExpirable(a)
+	assert.True(t, q.AddExpirable(b).Cancel())
+	assert.Equal(t, 1, q.Advance(time.Second))
+	assert.True(t, a.expired)
+	assert.False(t, b.expired)
+	assert.Panics(t, func() { q.AddExpirable(nil) })
+}
@@ -0,0 +1,103 @@
+package timeoutqueue
+
+import (
+	"time"
+)
+
+// Entry describes a pending action by it's correlation ID and how long it had
+// left when it was exported. It is the minimal record needed to restore a queue
+// after a controlled restart. Paused is set for an action that was paused,
+// whose Remaining is frozen.
+type Entry struct {
+	ID        interface{}
+	Remaining time.Duration
+	Paused    bool
+}
+
+// Export returns an Entry for everything in the queue in the order they will
+// fire, followed by the paused actions. The actions themselves are not
+// exported, so the correlation IDs need to carry enough to rebuild them, see
+// AddWithID.
+func (tq *TimeoutQueue) Export() []Entry {
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	now := tq.clockNow()
+	entries := make([]Entry, 0, tq.pending)
+	for cur := tq.head; cur != empty; cur = tq.nodes[cur].next {
+		entries = append(entries, Entry{
+			ID:        tq.nodes[cur].id,
+			Remaining: tq.deadline(cur).Sub(now),
+		})
+	}
+	for _, cur := range tq.pausedNodes() {
+		entries = append(entries, Entry{
+			ID:        tq.nodes[cur].id,
+			Remaining: tq.paused[cur],
+			Paused:    true,
+		})
+	}
+	return entries
+}
+
+// Expiring describes a pending action that is about to time out, see
+// ListExpiringWithin.
+type Expiring struct {
+	Token     Token
+	ID        interface{}
+	Remaining time.Duration
+}
+
+// ListExpiringWithin returns everything in the queue that will time out within d
+// in the order they will fire. As the queue is kept in deadline order it only
+// walks as far as the first action beyond d. Paused actions are not included.
+func (tq *TimeoutQueue) ListExpiringWithin(d time.Duration) []Expiring {
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	now := tq.clockNow()
+	until := now.Add(d)
+	var out []Expiring
+	for cur := tq.head; cur != empty && !tq.deadline(cur).After(until); cur = tq.nodes[cur].next {
+		out = append(out, Expiring{
+			Token:     tq.token(cur),
+			ID:        tq.nodes[cur].id,
+			Remaining: tq.deadline(cur).Sub(now),
+		})
+	}
+	return out
+}
+
+// Import adds an action for each Entry that fires after the Entry's remaining
+// duration instead of the queue's timeout, and is paused if the Entry is. The
+// action for each is returned by bind, which is called with the queue locked,
+// so it may not call methods on the queue. The Handles are appended to handles
+// in the same order as entries. A full queue from NewFixed follows it's
+// FullPolicy, and the Handle of an Entry it rejects fails to Cancel or Reset.
+func (tq *TimeoutQueue) Import(handles []Handle, entries []Entry, bind func(id interface{}) CorrelatedAction) []Handle {
+	if len(entries) == 0 {
+		return handles
+	}
+	tq.mux.Lock()
+	now := tq.clockNow()
+	var out []evicted
+	for _, e := range entries {
+		ev, ok := tq.room(e.ID)
+		if !ok {
+			handles = append(handles, Handle{})
+			continue
+		}
+		if ev.n.action != nil {
+			out = append(out, ev)
+		}
+		h := tq.insert(bind(e.ID), e.ID, now.Add(e.Remaining))
+		if e.Paused {
+			tq.pause(h.nodeIdx, e.Remaining)
+		}
+		handles = append(handles, h)
+	}
+	tq.rearm()
+	tq.mux.Unlock()
+	for _, ev := range out {
+		ev.dispatch(tq)
+	}
+	return handles
+}
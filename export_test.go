@@ -0,0 +1,65 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImport(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	nop := func(interface{}) {}
+	tq.AddWithID("a", nop)
+	clock.Advance(time.Millisecond * 300)
+	tq.AddWithID("b", nop)
+	clock.Advance(time.Millisecond * 200)
+
+	entries := tq.Export()
+	assert.Equal(t, []timeoutqueue.Entry{
+		{ID: "a", Remaining: time.Millisecond * 500},
+		{ID: "b", Remaining: time.Millisecond * 800},
+	}, entries)
+
+	restored := timeoutqueue.NewManual(time.Second, 10, clock)
+	restored.SetExecutor(timeoutqueue.Inline)
+	var fired []interface{}
+	bind := func(id interface{}) timeoutqueue.CorrelatedAction {
+		return func(id interface{}) { fired = append(fired, id) }
+	}
+	restored.AddWithID("c", bind("c"))
+	clock.Advance(time.Millisecond * 100)
+	handles := restored.Import(nil, entries, bind)
+	assert.Len(t, handles, 2)
+	assert.NoError(t, restored.Validate())
+	assert.True(t, handles[1].Cancel())
+
+	clock.Advance(time.Millisecond * 500)
+	assert.Equal(t, 1, restored.Tick())
+	clock.Advance(time.Millisecond * 400)
+	assert.Equal(t, 1, restored.Tick())
+	assert.Equal(t, []interface{}{"a", "c"}, fired)
+}
+
+func TestListExpiringWithin(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	nop := func(interface{}) {}
+	a := tq.AddWithID("a", nop)
+	clock.Advance(time.Millisecond * 300)
+	tq.AddWithID("b", nop)
+	clock.Advance(time.Millisecond * 200)
+
+	assert.Empty(t, tq.ListExpiringWithin(time.Millisecond*100))
+	expiring := tq.ListExpiringWithin(time.Millisecond * 500)
+	if assert.Len(t, expiring, 1) {
+		assert.Equal(t, "a", expiring[0].ID)
+		assert.Equal(t, time.Millisecond*500, expiring[0].Remaining)
+		assert.True(t, expiring[0].Token.Cancel())
+		assert.False(t, a.Cancel())
+	}
+	assert.Len(t, tq.ListExpiringWithin(time.Second), 1)
+}
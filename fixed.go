@@ -0,0 +1,113 @@
+package timeoutqueue
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrFull is returned when something that can't follow the FullPolicy is added
+// to a queue from NewFixed that has no room for it.
+var ErrFull = errors.New("timeoutqueue: fixed queue is full")
+
+// FullPolicy decides what a queue from NewFixed does when something is added
+// while it is full.
+type FullPolicy uint8
+
+const (
+	// RejectWhenFull does not add the TimeoutAction, it is never called and the
+	// returned Token fails to Cancel or Reset. Each rejection is sent as
+	// EventRejected and counted in Stats.Rejected.
+	RejectWhenFull FullPolicy = iota
+	// EvictWhenFull makes room by firing the TimeoutAction closest to it's
+	// deadline early, with ReasonEvicted.
+	EvictWhenFull
+)
+
+// NewFixed returns a TimeoutQueue that allocates all capacity nodes up front and
+// never grows, for deployments that must not allocate after startup; only
+// SetCapacity grows it. When it is full, adding follows the FullPolicy;
+// AddBatch always rejects what does not fit and ReadFrom returns ErrFull.
+// Adding never allocates, except that without an Executor each action that
+// fires is called in it's own Go routine and a runner Go routine is started
+// whenever the queue stops being empty, so Inline or a worker pool and
+// SetLinger are needed for a guarantee.
+func NewFixed(timeout time.Duration, capacity int, full FullPolicy) *TimeoutQueue {
+	tq := New(timeout, capacity)
+	tq.fixed = true
+	tq.full = full
+	tq.linkFree(cap(tq.nodes))
+	return tq
+}
+
+// linkFree requires a mux lock. It extends the nodes to length ln, putting the
+// new nodes on the free list in order.
+func (tq *TimeoutQueue) linkFree(ln int) {
+	old := len(tq.nodes)
+	tq.nodes = tq.nodes[:ln]
+	for i := ln - 1; i >= old; i-- {
+		tq.nodes[i].next = tq.free
+		tq.free = uint32(i)
+	}
+	tq.debugValidate()
+}
+
+// addFull requires a mux lock and will unlock it when done. It is called to add
+// to a fixed queue that has no free nodes.
+func (tq *TimeoutQueue) addFull(action, id interface{}, class uint8, pinned bool) Handle {
+	e, ok := tq.room(id)
+	if !ok {
+		tq.mux.Unlock()
+		return Handle{}
+	}
+	t := tq.insertClass(action, id, class, tq.deadlineAfter(tq.classTimeout(class)))
+	if pinned || class != 0 {
+		tq.pin(t.nodeIdx)
+	}
+	tq.rearm()
+	tq.mux.Unlock()
+	e.dispatch(tq)
+	return t
+}
+
+// evicted is the action EvictWhenFull took out of a full queue, which is
+// dispatched once the queue is unlocked.
+type evicted struct {
+	idx uint32
+	n   node
+	d   dispatcher
+	now time.Time
+}
+
+// room requires a mux lock. It makes sure there is a free node to insert into,
+// following the FullPolicy if the queue is from NewFixed and full. The returned
+// bool is false if the FullPolicy rejects the action, in which case
+// EventRejected was sent for id. Whatever was evicted to make room has to be
+// dispatched once the queue is unlocked.
+func (tq *TimeoutQueue) room(id interface{}) (evicted, bool) {
+	if !tq.fixed || tq.free != empty {
+		return evicted{}, true
+	}
+	if tq.full != EvictWhenFull || tq.head == empty {
+		tq.emitID(EventRejected, id)
+		return evicted{}, false
+	}
+	idx := tq.head
+	e := evicted{
+		idx: idx,
+		n:   tq.nodeAt(idx),
+		d:   tq.dispatcher,
+		now: tq.clockNow(),
+	}
+	tq.emit(EventFired, idx)
+	tq.freeNode(idx)
+	tq.nodes[idx].inflight++
+	return e, true
+}
+
+// dispatch calls the evicted action with ReasonEvicted. It does nothing if room
+// did not evict anything.
+func (e evicted) dispatch(tq *TimeoutQueue) {
+	if e.n.action != nil {
+		e.d.dispatchFired(tq, e.idx, e.n, e.now, ReasonEvicted)
+	}
+}
@@ -0,0 +1,113 @@
+package timeoutqueue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixed(t *testing.T) {
+	tq := timeoutqueue.NewFixed(time.Hour, 2, timeoutqueue.RejectWhenFull)
+	assert.NoError(t, tq.Validate())
+	action := func() {}
+	a := tq.AddHandle(action)
+	tq.Add(action)
+	assert.False(t, tq.Add(action).Cancel())
+	assert.Equal(t, uint64(1), tq.Stats().Rejected)
+	assert.Equal(t, 2, tq.Len())
+	assert.Equal(t, 2, tq.Cap())
+
+	// pinning a runner keeps the measurement to the queue itself
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		a.Cancel()
+		a = tq.AddHandle(action)
+	}))
+	assert.Equal(t, uint64(0), tq.Stats().Grew)
+
+	assert.NoError(t, tq.SetCapacity(3))
+	assert.True(t, tq.Add(action).Cancel())
+	assert.NoError(t, tq.Validate())
+	tq.Close()
+}
+
+func TestFixedEvict(t *testing.T) {
+	tq := timeoutqueue.NewFixed(time.Hour, 2, timeoutqueue.EvictWhenFull)
+	tq.SetExecutor(timeoutqueue.Inline)
+	var reasons []interface{}
+	record := func(id interface{}) func(timeoutqueue.Reason) {
+		return func(r timeoutqueue.Reason) {
+			reasons = append(reasons, id, r)
+		}
+	}
+	tq.AddWithReason(record("a"))
+	tq.AddWithReason(record("b"))
+	tq.AddWithReason(record("c"))
+	assert.Equal(t, []interface{}{"a", timeoutqueue.ReasonEvicted}, reasons)
+	assert.Equal(t, 2, tq.Len())
+
+	handles := tq.AddBatch(nil, func() {})
+	assert.False(t, handles[0].Cancel())
+	assert.Equal(t, uint64(1), tq.Stats().Rejected)
+	assert.NoError(t, tq.Validate())
+	tq.Close()
+}
+
+func TestFixedNeverGrows(t *testing.T) {
+	tq := timeoutqueue.NewFixed(time.Hour, 2, timeoutqueue.RejectWhenFull)
+	nop := func(interface{}) {}
+	bind := func(interface{}) timeoutqueue.CorrelatedAction { return nop }
+	handles := tq.Import(nil, []timeoutqueue.Entry{
+		{ID: "a", Remaining: time.Minute},
+		{ID: "b", Remaining: time.Minute},
+		{ID: "c", Remaining: time.Minute},
+	}, bind)
+	assert.Len(t, handles, 3)
+	assert.False(t, handles[2].Cancel())
+	assert.Equal(t, 2, tq.Len())
+
+	other := timeoutqueue.NewManual(time.Hour, 0, nil)
+	other.AddWithID("d", nop)
+	assert.Equal(t, 0, tq.Merge(other))
+	assert.Equal(t, 1, other.Len())
+
+	// a rejected context still times out, without the queue
+	ctx, cancel := tq.WithTimeoutCtx(context.Background())
+	_, ok := ctx.Deadline()
+	assert.True(t, ok)
+	cancel()
+	assert.Equal(t, context.Canceled, ctx.Err())
+
+	assert.Equal(t, uint64(3), tq.Stats().Rejected)
+	assert.Equal(t, 2, tq.Len())
+	assert.Equal(t, 2, tq.Cap())
+	assert.NoError(t, tq.Validate())
+	tq.Close()
+}
+
+func TestFixedEvictImportMerge(t *testing.T) {
+	tq := timeoutqueue.NewFixed(time.Hour, 2, timeoutqueue.EvictWhenFull)
+	tq.SetExecutor(timeoutqueue.Inline)
+	var reasons []interface{}
+	record := func(id interface{}) timeoutqueue.CorrelatedAction {
+		return func(interface{}) { reasons = append(reasons, id) }
+	}
+	tq.AddWithID("a", record("a"))
+	tq.Import(nil, []timeoutqueue.Entry{
+		{ID: "b", Remaining: time.Hour},
+		{ID: "c", Remaining: time.Hour},
+	}, record)
+	assert.Equal(t, []interface{}{"a"}, reasons)
+
+	other := timeoutqueue.NewManual(time.Hour, 0, nil)
+	other.AddWithID("d", record("d"))
+	assert.Equal(t, 1, tq.Merge(other))
+	assert.Equal(t, []interface{}{"a", "b"}, reasons)
+	assert.Equal(t, 0, other.Len())
+	assert.Equal(t, 2, tq.Len())
+	assert.Equal(t, 2, tq.Cap())
+	assert.NoError(t, tq.Validate())
+	tq.Close()
+}
@@ -0,0 +1,59 @@
+package timeoutqueue
+
+import (
+	"errors"
+)
+
+// ErrCanceled is the error a Future resolves with if it is canceled.
+var ErrCanceled = errors.New("timeoutqueue: canceled")
+
+// Future is the result of an action added with AddFuture. It resolves when the
+// action is called or when it is canceled.
+type Future struct {
+	handle Handle
+	done   chan struct{}
+	val    interface{}
+	err    error
+}
+
+// AddFuture adds action to the queue and returns a Future that resolves with
+// the values the action returns once it is called.
+func (tq *TimeoutQueue) AddFuture(action func() (interface{}, error)) *Future {
+	f := &Future{
+		done: make(chan struct{}),
+	}
+	f.handle = tq.AddHandle(func() {
+		f.val, f.err = action()
+		close(f.done)
+	})
+	return f
+}
+
+// Done returns a channel that is closed when the Future resolves.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Get blocks until the Future resolves then returns the result of the action.
+// If the Future was canceled, the error is ErrCanceled.
+func (f *Future) Get() (interface{}, error) {
+	<-f.done
+	return f.val, f.err
+}
+
+// Cancel removes the action from the queue and resolves the Future with
+// ErrCanceled. The returned bool indicates if the Cancel happened, as with
+// Token.
+func (f *Future) Cancel() bool {
+	if !f.handle.Cancel() {
+		return false
+	}
+	f.err = ErrCanceled
+	close(f.done)
+	return true
+}
+
+// Reset the action's timeout, as with Token.
+func (f *Future) Reset() bool {
+	return f.handle.Reset()
+}
@@ -0,0 +1,43 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeout"
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuture(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*2, 10)
+
+	f := tq.AddFuture(func() (interface{}, error) {
+		return 42, nil
+	})
+	assert.NoError(t, timeout.After(20, f.Done()))
+	v, err := f.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, 42, v)
+	assert.False(t, f.Cancel())
+	assert.False(t, f.Reset())
+
+	errFailed := errors.New("failed")
+	f = tq.AddFuture(func() (interface{}, error) {
+		return 0, errFailed
+	})
+	_, err = f.Get()
+	assert.Equal(t, errFailed, err)
+
+	f = tq.AddFuture(func() (interface{}, error) {
+		t.Error("should be canceled")
+		return 0, nil
+	})
+	assert.True(t, f.Reset())
+	assert.True(t, f.Cancel())
+	_, err = f.Get()
+	assert.Equal(t, timeoutqueue.ErrCanceled, err)
+}
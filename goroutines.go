@@ -0,0 +1,110 @@
+package timeoutqueue
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Goroutines returns the number of Go routines started by the queue that have
+// not yet returned. That includes the runner, even one that was stopped by
+// Close and has not yet woken, and every action the queue dispatched in it's own
+// Go routine because no Executor was set. Actions run by an Executor are not
+// counted as the queue did not start them; Stats.Executing counts those. Once
+// Close returns this is zero until something else is added or the queue is
+// reopened.
+func (tq *TimeoutQueue) Goroutines() int {
+	return int(atomic.LoadInt64(&tq.goroutines))
+}
+
+// goRun requires a mux lock. It starts a runner with the given id.
+func (tq *TimeoutQueue) goRun(id uint16) {
+	atomic.AddInt64(&tq.goroutines, 1)
+	go tq.run(id)
+}
+
+// runnerExited requires a mux lock. It is called by a runner as it returns.
+func (tq *TimeoutQueue) runnerExited() {
+	if atomic.AddInt64(&tq.goroutines, -1) == 0 {
+		tq.lastExited()
+	}
+}
+
+// lastExited requires a mux lock. It is called when the last Go routine started
+// by the queue returns. Nothing can be asleep on the wake channel, so it is
+// dropped; a channel made inside a synctest bubble must not be used after the
+// bubble ends.
+func (tq *TimeoutQueue) lastExited() {
+	tq.wake = nil
+	if tq.exited != nil {
+		tq.exited.Broadcast()
+	}
+}
+
+// goAction calls action in a new Go routine that is counted by Goroutines. With
+// the timeoutqueuesingle tag it calls action directly.
+func (tq *TimeoutQueue) goAction(action func()) {
+	if single {
+		action()
+		return
+	}
+	atomic.AddInt64(&tq.goroutines, 1)
+	go func() {
+		action()
+		if atomic.AddInt64(&tq.goroutines, -1) == 0 {
+			tq.mux.Lock()
+			if atomic.LoadInt64(&tq.goroutines) == 0 {
+				tq.lastExited()
+			}
+			tq.mux.Unlock()
+		}
+	}()
+}
+
+// sleep requires a mux lock and will unlock it. It sleeps for d unless the
+// sleep is interrupted by Close.
+func (tq *TimeoutQueue) sleep(d time.Duration) {
+	if tq.wake == nil {
+		tq.wake = make(chan struct{})
+	}
+	wake := tq.wake
+	tq.mux.Unlock()
+	t := time.NewTimer(d)
+	select {
+	case <-t.C:
+	case <-wake:
+		t.Stop()
+	}
+}
+
+// wakeRunner requires a mux lock. If the runner is asleep it is woken so it can
+// work out how long to sleep again. A queue from NewAfterFunc re-arms it's timer
+// instead, unless it is manual.
+func (tq *TimeoutQueue) wakeRunner() {
+	if tq.afterFunc {
+		if !tq.manual {
+			tq.arm()
+		}
+		return
+	}
+	if tq.running != 0 && !tq.sleepUntil.IsZero() && tq.wake != nil {
+		close(tq.wake)
+		tq.wake = nil
+	}
+}
+
+// waitGoroutines requires a mux lock. It wakes every sleeping runner and waits
+// until every Go routine started by the queue has returned, unlocking while it
+// waits.
+func (tq *TimeoutQueue) waitGoroutines() {
+	if tq.exited == nil {
+		tq.exited = sync.NewCond(&tq.mux)
+	}
+	for atomic.LoadInt64(&tq.goroutines) != 0 {
+		if tq.wake != nil {
+			close(tq.wake)
+			tq.wake = nil
+		}
+		tq.exited.Wait()
+	}
+}
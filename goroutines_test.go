@@ -0,0 +1,64 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoroutines(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond, 10)
+	release := make(chan struct{})
+	tq.Add(func() { <-release })
+	time.Sleep(time.Millisecond * 10)
+	assert.Equal(t, 1, tq.Goroutines())
+
+	// an earlier deadline wakes the runner rather than starting another
+	tq.SetTimeout(time.Hour)
+	tq.Add(func() {})
+	time.Sleep(time.Millisecond * 5)
+	tq.SetTimeoutForNew(time.Millisecond * 10)
+	tq.Add(func() {})
+	assert.Equal(t, 2, tq.Goroutines())
+
+	closed := make(chan struct{})
+	go func() {
+		tq.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while an action was running")
+	case <-time.After(time.Millisecond * 10):
+	}
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return")
+	}
+	assert.Equal(t, 0, tq.Goroutines())
+}
+
+func TestFlushKeepsRunner(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*50, 10)
+	tq.SetExecutor(timeoutqueue.Inline)
+	defer tq.Close()
+	tq.Add(func() {})
+	tq.Flush()
+	ch := make(chan bool, 1)
+	tq.Add(func() { ch <- true })
+	// the runner from before the Flush serves the next Add
+	assert.True(t, tq.Goroutines() <= 1)
+	assert.Equal(t, uint16(1), tq.RunnerState().Generation)
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("action did not fire")
+	}
+	assert.True(t, tq.Goroutines() <= 1)
+}
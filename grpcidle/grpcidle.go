@@ -0,0 +1,144 @@
+// Package grpcidle aborts gRPC server streams that go idle. Rather than a timer
+// per stream, every stream is an entry in a KeyedQueue that is reset whenever a
+// message is sent or received on it.
+package grpcidle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrIdle is the cause of the stream context being canceled when a stream goes
+// idle. It is also returned by SendMsg after the stream has been aborted.
+var ErrIdle = errors.New("grpcidle: stream idle")
+
+// Enforcer tracks the activity of streams.
+type Enforcer struct {
+	// next is first to guarentee 64 bit alignment for atomic operations
+	next uint64
+	kq   *timeoutqueue.KeyedQueue
+}
+
+// New returns an Enforcer that aborts streams idle for longer than tq's timeout.
+func New(tq *timeoutqueue.TimeoutQueue) *Enforcer {
+	return &Enforcer{
+		kq: tq.NewKeyedQueue(),
+	}
+}
+
+// Active returns the number of streams being tracked.
+func (e *Enforcer) Active() int {
+	return e.kq.Len()
+}
+
+// StreamServerInterceptor returns an interceptor that enforces the idle timeout.
+// The handler runs in it's own Go routine so that a stream can be aborted while
+// the handler is blocked in RecvMsg; when it goes idle the interceptor returns
+// codes.Aborted straight away and cancels the handler's context with ErrIdle.
+// Any SendMsg in progress is allowed to finish first and any after fail with
+// ErrIdle. Because of the Go routine, panics in the handler are not seen by
+// interceptors that run before this one.
+func (e *Enforcer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return e.intercept
+}
+
+func (e *Enforcer) intercept(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, cancel := context.WithCancelCause(ss.Context())
+	s := &stream{
+		ServerStream: ss,
+		ctx:          ctx,
+		kq:           e.kq,
+		id:           atomic.AddUint64(&e.next, 1),
+	}
+	idle := make(chan struct{})
+	e.kq.Set(s.id, func() {
+		close(idle)
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- handler(srv, s)
+	}()
+
+	select {
+	case err := <-done:
+		e.kq.Cancel(s.id)
+		cancel(nil)
+		return err
+	case <-idle:
+		cancel(ErrIdle)
+		s.mux.Lock()
+		s.aborted = true
+		s.mux.Unlock()
+		return status.Error(codes.Aborted, ErrIdle.Error())
+	}
+}
+
+// stream guards the calls that write to the underlying stream so none happen
+// after the interceptor has returned.
+type stream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	kq      *timeoutqueue.KeyedQueue
+	id      uint64
+	mux     sync.Mutex
+	aborted bool
+}
+
+func (s *stream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *stream) SendMsg(m interface{}) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.aborted {
+		return ErrIdle
+	}
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.kq.Reset(s.id)
+	}
+	return err
+}
+
+func (s *stream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.kq.Reset(s.id)
+	}
+	return err
+}
+
+func (s *stream) SetHeader(md metadata.MD) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.aborted {
+		return ErrIdle
+	}
+	return s.ServerStream.SetHeader(md)
+}
+
+func (s *stream) SendHeader(md metadata.MD) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.aborted {
+		return ErrIdle
+	}
+	return s.ServerStream.SendHeader(md)
+}
+
+func (s *stream) SetTrailer(md metadata.MD) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if !s.aborted {
+		s.ServerStream.SetTrailer(md)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build !timeoutqueuesingle
+
+package grpcidle_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/grpcidle"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIdle(t *testing.T) {
+	e := grpcidle.New(timeoutqueue.New(time.Millisecond*20, 10))
+	intercept := e.StreamServerInterceptor()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := &fakeStream{
+		ctx:  ctx,
+		recv: make(chan bool),
+	}
+
+	handlerCtx := make(chan context.Context, 1)
+	var stream grpc.ServerStream
+	err := intercept(nil, f, nil, func(srv interface{}, ss grpc.ServerStream) error {
+		stream = ss
+		handlerCtx <- ss.Context()
+		for i := 0; i < 5; i++ {
+			time.Sleep(time.Millisecond * 10)
+			assert.NoError(t, ss.SendMsg(i))
+		}
+		// blocks until after the stream goes idle
+		return ss.RecvMsg(nil)
+	})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.Equal(t, 5, f.sent)
+	assert.Equal(t, grpcidle.ErrIdle, context.Cause(<-handlerCtx))
+	assert.Equal(t, grpcidle.ErrIdle, stream.SendMsg(nil))
+	assert.Equal(t, 0, e.Active())
+	close(f.recv)
+}
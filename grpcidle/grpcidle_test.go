@@ -0,0 +1,49 @@
+package grpcidle_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/grpcidle"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeStream receives a message every time one is put on recv and blocks
+// otherwise.
+type fakeStream struct {
+	ctx  context.Context
+	recv chan bool
+	sent int
+}
+
+func (f *fakeStream) SetHeader(metadata.MD) error  { return nil }
+func (f *fakeStream) SendHeader(metadata.MD) error { return nil }
+func (f *fakeStream) SetTrailer(metadata.MD)       {}
+func (f *fakeStream) Context() context.Context     { return f.ctx }
+func (f *fakeStream) SendMsg(m interface{}) error {
+	f.sent++
+	return nil
+}
+func (f *fakeStream) RecvMsg(m interface{}) error {
+	<-f.recv
+	return nil
+}
+
+func TestDone(t *testing.T) {
+	e := grpcidle.New(timeoutqueue.New(time.Second, 10))
+	f := &fakeStream{
+		ctx: context.Background(),
+	}
+	handlerErr := errors.New("done")
+	err := e.StreamServerInterceptor()(nil, f, nil, func(srv interface{}, ss grpc.ServerStream) error {
+		assert.Equal(t, 1, e.Active())
+		return handlerErr
+	})
+	assert.Equal(t, handlerErr, err)
+	assert.Equal(t, 0, e.Active())
+}
@@ -0,0 +1,61 @@
+package timeoutqueue
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrHandleText is returned when unmarshaling text that was not produced by
+// Handle.MarshalText.
+var ErrHandleText = errors.New("timeoutqueue: invalid Handle text")
+
+// MarshalText fulfills encoding.TextMarshaler so a Handle can be logged or kept
+// in an external store. The text identifies the action within it's queue but
+// not the queue itself, after unmarshaling Bind attaches it to the queue again.
+// As WriteTo and ReadFrom keep the generation counters, the text still refers
+// to the same action after a queue is restored from a snapshot.
+func (t Handle) MarshalText() ([]byte, error) {
+	b := strconv.AppendUint(nil, uint64(t.nodeIdx), 10)
+	b = append(b, ':')
+	return strconv.AppendUint(b, uint64(t.actionID), 10), nil
+}
+
+// UnmarshalText fulfills encoding.TextUnmarshaler. The Handle is not attached to
+// a queue, so until it is passed to Bind, Cancel and Reset on it return false.
+func (t *Handle) UnmarshalText(text []byte) error {
+	idx, id, ok := strings.Cut(string(text), ":")
+	if !ok {
+		return ErrHandleText
+	}
+	nodeIdx, err := strconv.ParseUint(idx, 10, 32)
+	if err != nil {
+		return ErrHandleText
+	}
+	actionID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return ErrHandleText
+	}
+	*t = Handle{
+		nodeIdx:  uint32(nodeIdx),
+		actionID: uint32(actionID),
+	}
+	return nil
+}
+
+// Bind attaches an unmarshaled Handle to the queue. The returned bool is false,
+// and the Handle is the zero value, if it does not refer to an action pending
+// in the queue.
+func (tq *TimeoutQueue) Bind(t Handle) (Handle, bool) {
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	if int(t.nodeIdx) >= len(tq.nodes) {
+		return Handle{}, false
+	}
+	n := tq.nodes[t.nodeIdx]
+	if n.action == nil || n.actionID != t.actionID {
+		return Handle{}, false
+	}
+	t.tq = tq
+	return t, true
+}
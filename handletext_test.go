@@ -0,0 +1,37 @@
+package timeoutqueue_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleText(t *testing.T) {
+	tq := timeoutqueue.NewManual(time.Second, 10, nil)
+	tq.AddHandle(func() {}).Cancel()
+	h := tq.AddHandle(func() {})
+
+	b, err := json.Marshal(map[string]timeoutqueue.Handle{"h": h})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"h":"0:1"}`, string(b))
+
+	var out map[string]timeoutqueue.Handle
+	assert.NoError(t, json.Unmarshal(b, &out))
+	unbound := out["h"]
+	assert.False(t, unbound.Cancel())
+	bound, ok := tq.Bind(unbound)
+	assert.True(t, ok)
+	assert.Equal(t, h, bound)
+	assert.True(t, bound.Cancel())
+	_, ok = tq.Bind(unbound)
+	assert.False(t, ok)
+
+	assert.Equal(t, timeoutqueue.ErrHandleText, unbound.UnmarshalText([]byte("1")))
+	assert.Equal(t, timeoutqueue.ErrHandleText, unbound.UnmarshalText([]byte("a:1")))
+	assert.NoError(t, unbound.UnmarshalText([]byte("100:0")))
+	_, ok = tq.Bind(unbound)
+	assert.False(t, ok)
+}
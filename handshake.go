@@ -0,0 +1,88 @@
+package timeoutqueue
+
+import (
+	"sync"
+)
+
+// HandshakeTracker times handshakes keyed by a peer or connection ID. Start arms
+// a timeout for the ID, Complete cancels it and if it expires the failure
+// callback is called with the ID.
+type HandshakeTracker struct {
+	mux     sync.Mutex
+	tq      *TimeoutQueue
+	onFail  func(id interface{})
+	handles map[interface{}]*handshake
+}
+
+// handshake is the timeout for one Start. It is set to the Handle once it has
+// been added to the queue.
+type handshake struct {
+	handle Handle
+	adding bool
+}
+
+// NewHandshakeTracker returns a HandshakeTracker that allows the queue's timeout
+// for each handshake and calls onFail with the ID of any that do not complete.
+func (tq *TimeoutQueue) NewHandshakeTracker(onFail func(id interface{})) *HandshakeTracker {
+	return &HandshakeTracker{
+		tq:      tq,
+		onFail:  onFail,
+		handles: make(map[interface{}]*handshake),
+	}
+}
+
+func (ht *HandshakeTracker) expired(id interface{}, hs *handshake) {
+	ht.mux.Lock()
+	cur, ok := ht.handles[id]
+	if ok && cur != hs {
+		// the handshake was started again between the queue firing and now
+		ht.mux.Unlock()
+		return
+	}
+	delete(ht.handles, id)
+	ht.mux.Unlock()
+	if ht.onFail != nil {
+		ht.onFail(id)
+	}
+}
+
+// Start the timeout for a handshake with id. If a handshake with the id is
+// already in progress, it's timeout is restarted and false is returned.
+func (ht *HandshakeTracker) Start(id interface{}) bool {
+	ht.mux.Lock()
+	if hs, ok := ht.handles[id]; ok && (hs.adding || hs.handle.Reset()) {
+		ht.mux.Unlock()
+		return false
+	}
+	hs := &handshake{adding: true}
+	ht.handles[id] = hs
+	ht.mux.Unlock()
+	// the handshake may fail from within Add, so it is added without the lock
+	h := ht.tq.AddHandle(func() { ht.expired(id, hs) })
+	ht.mux.Lock()
+	hs.handle = h
+	hs.adding = false
+	ht.mux.Unlock()
+	return true
+}
+
+// Complete the handshake with id, canceling it's timeout. The returned bool is
+// false if there was no handshake in progress with the id.
+func (ht *HandshakeTracker) Complete(id interface{}) bool {
+	ht.mux.Lock()
+	defer ht.mux.Unlock()
+	hs, ok := ht.handles[id]
+	if !ok {
+		return false
+	}
+	delete(ht.handles, id)
+	return hs.handle.Cancel()
+}
+
+// Pending returns the number of handshakes in progress.
+func (ht *HandshakeTracker) Pending() int {
+	ht.mux.Lock()
+	n := len(ht.handles)
+	ht.mux.Unlock()
+	return n
+}
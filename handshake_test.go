@@ -0,0 +1,74 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandshakeTracker(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	var failed []interface{}
+	ht := q.NewHandshakeTracker(func(id interface{}) {
+		failed = append(failed, id)
+	})
+
+	assert.True(t, ht.Start("alice"))
+	assert.True(t, ht.Start("bob"))
+	q.Advance(time.Millisecond * 500)
+	assert.False(t, ht.Start("bob"))
+	assert.Equal(t, 2, ht.Pending())
+
+	assert.True(t, ht.Complete("alice"))
+	assert.False(t, ht.Complete("alice"))
+	q.Advance(time.Millisecond * 600)
+	assert.Len(t, failed, 0)
+	q.Advance(time.Millisecond * 500)
+	assert.Equal(t, []interface{}{"bob"}, failed)
+	assert.Equal(t, 0, ht.Pending())
+	assert.False(t, ht.Complete("bob"))
+
+	assert.True(t, ht.Start("bob"))
+}
+
+func TestHandshakeRestartWhileFiring(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	var held []func()
+	tq.SetExecutor(timeoutqueue.ExecutorFunc(func(action func()) {
+		held = append(held, action)
+	}))
+	var failed []interface{}
+	ht := tq.NewHandshakeTracker(func(id interface{}) {
+		failed = append(failed, id)
+	})
+	assert.True(t, ht.Start("a"))
+	clock.Advance(time.Second)
+	assert.Equal(t, 1, tq.Tick())
+	// the timeout fired but has not run, so this is a new handshake
+	assert.True(t, ht.Start("a"))
+	for _, action := range held {
+		action()
+	}
+	assert.Len(t, failed, 0)
+	assert.Equal(t, 1, ht.Pending())
+}
+
+func TestHandshakeImmediate(t *testing.T) {
+	// a closed queue fails the handshake from within Add
+	tq := timeoutqueue.New(time.Hour, 1)
+	tq.SetExecutor(timeoutqueue.Inline)
+	tq.Close()
+	var pending []int
+	var ht *timeoutqueue.HandshakeTracker
+	ht = tq.NewHandshakeTracker(func(interface{}) {
+		pending = append(pending, ht.Pending())
+	})
+	assert.True(t, ht.Start("a"))
+	assert.Equal(t, []int{0}, pending)
+	assert.Equal(t, 0, ht.Pending())
+	assert.False(t, ht.Complete("a"))
+}
@@ -0,0 +1,88 @@
+// Package httpsession provides net/http middleware that expires idle sessions.
+// Each request touches a timer keyed by it's session ID and when a session has
+// had no requests for the queue's timeout, an expiry handler is called with
+// it's ID so the session can be evicted.
+package httpsession
+
+import (
+	"net/http"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+)
+
+// KeyFunc returns the session ID for a request. The bool is false if the
+// request does not belong to a session.
+type KeyFunc func(r *http.Request) (string, bool)
+
+// Cookie returns a KeyFunc that takes the session ID from the value of the
+// named cookie.
+func Cookie(name string) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		c, err := r.Cookie(name)
+		if err != nil || c.Value == "" {
+			return "", false
+		}
+		return c.Value, true
+	}
+}
+
+// Manager tracks the idle timeout of every session.
+type Manager struct {
+	kq       *timeoutqueue.KeyedQueue
+	key      KeyFunc
+	onExpire func(id string)
+}
+
+// New returns a Manager that uses tq's timeout as the idle timeout, key to find
+// the session of each request and calls onExpire with the ID of each session
+// that goes idle.
+func New(tq *timeoutqueue.TimeoutQueue, key KeyFunc, onExpire func(id string)) *Manager {
+	kq := tq.NewKeyedQueue()
+	kq.SetDedupe(timeoutqueue.DedupeReset)
+	return &Manager{
+		kq:       kq,
+		key:      key,
+		onExpire: onExpire,
+	}
+}
+
+// Handler returns middleware that touches the session of every request before
+// passing it on to next.
+func (m *Manager) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := m.key(r); ok {
+			m.Touch(id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Touch restarts the idle timeout of the session with id, starting one if it is
+// not already tracked.
+func (m *Manager) Touch(id string) {
+	if m.kq.Reset(id) {
+		return
+	}
+	m.kq.Set(id, func() {
+		if m.onExpire != nil {
+			m.onExpire(id)
+		}
+	})
+}
+
+// End stops tracking the session with id without calling the expiry handler,
+// for instance when the user logs out. It returns false if the session was not
+// tracked.
+func (m *Manager) End(id string) bool {
+	return m.kq.Cancel(id)
+}
+
+// Active returns true if the session with id is tracked and has not expired.
+func (m *Manager) Active(id string) bool {
+	return m.kq.Has(id)
+}
+
+// Len returns the number of sessions tracked.
+func (m *Manager) Len() int {
+	return m.kq.Len()
+}
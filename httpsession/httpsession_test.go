@@ -0,0 +1,49 @@
+package httpsession_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/httpsession"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager(t *testing.T) {
+	q := timeoutqueuetest.New(time.Minute, 10)
+	var expired []string
+	m := httpsession.New(q.TimeoutQueue, httpsession.Cookie("sid"), func(id string) {
+		expired = append(expired, id)
+	})
+	var served int
+	h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+	}))
+	request := func(sid string) {
+		r := httptest.NewRequest("GET", "/", nil)
+		if sid != "" {
+			r.AddCookie(&http.Cookie{Name: "sid", Value: sid})
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	request("a")
+	request("b")
+	request("")
+	assert.Equal(t, 3, served)
+	assert.Equal(t, 2, m.Len())
+
+	q.Advance(time.Second * 50)
+	request("a")
+	q.Advance(time.Second * 10)
+	assert.Equal(t, []string{"b"}, expired)
+	assert.True(t, m.Active("a"))
+	assert.False(t, m.Active("b"))
+
+	assert.True(t, m.End("a"))
+	q.Advance(time.Minute)
+	assert.Equal(t, []string{"b"}, expired)
+	assert.Equal(t, 0, m.Len())
+}
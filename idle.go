@@ -0,0 +1,66 @@
+package timeoutqueue
+
+import (
+	"io"
+)
+
+// IdleReader resets a Token every time a Read returns data, so the Token's
+// TimeoutAction only fires once the stream has gone idle for the queue's
+// timeout. The action is usually one that aborts the stream.
+type IdleReader struct {
+	r io.Reader
+	t Token
+}
+
+// NewIdleReader wraps r, resetting t on every Read that returns data.
+func NewIdleReader(r io.Reader, t Token) *IdleReader {
+	return &IdleReader{
+		r: r,
+		t: t,
+	}
+}
+
+// Read fulfills io.Reader.
+func (ir *IdleReader) Read(p []byte) (int, error) {
+	n, err := ir.r.Read(p)
+	if n > 0 {
+		ir.t.Reset()
+	}
+	return n, err
+}
+
+// Token returns the Token the IdleReader resets, which can be canceled once the
+// stream is done with.
+func (ir *IdleReader) Token() Token {
+	return ir.t
+}
+
+// IdleWriter resets a Token every time a Write succeeds in writing data, so the
+// Token's TimeoutAction only fires once the stream has gone idle for the
+// queue's timeout.
+type IdleWriter struct {
+	w io.Writer
+	t Token
+}
+
+// NewIdleWriter wraps w, resetting t on every Write that writes data.
+func NewIdleWriter(w io.Writer, t Token) *IdleWriter {
+	return &IdleWriter{
+		w: w,
+		t: t,
+	}
+}
+
+// Write fulfills io.Writer.
+func (iw *IdleWriter) Write(p []byte) (int, error) {
+	n, err := iw.w.Write(p)
+	if n > 0 {
+		iw.t.Reset()
+	}
+	return n, err
+}
+
+// Token returns the Token the IdleWriter resets.
+func (iw *IdleWriter) Token() Token {
+	return iw.t
+}
@@ -0,0 +1,55 @@
+package timeoutqueue_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdleReader(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	var idle bool
+	r := timeoutqueue.NewIdleReader(strings.NewReader("ab"), q.Add(func() {
+		idle = true
+	}))
+
+	buf := make([]byte, 1)
+	for i := 0; i < 2; i++ {
+		q.Advance(time.Millisecond * 900)
+		n, err := r.Read(buf)
+		assert.Equal(t, 1, n)
+		assert.NoError(t, err)
+	}
+	// EOF returns no data, so it does not reset the timeout
+	q.Advance(time.Millisecond * 900)
+	_, err := r.Read(buf)
+	assert.Equal(t, io.EOF, err)
+	assert.False(t, idle)
+	q.Advance(time.Millisecond * 100)
+	assert.True(t, idle)
+	assert.False(t, r.Token().Cancel())
+}
+
+func TestIdleWriter(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	var idle bool
+	buf := &bytes.Buffer{}
+	w := timeoutqueue.NewIdleWriter(buf, q.Add(func() {
+		idle = true
+	}))
+
+	q.Advance(time.Millisecond * 900)
+	_, err := w.Write([]byte("a"))
+	assert.NoError(t, err)
+	q.Advance(time.Millisecond * 900)
+	assert.False(t, idle)
+	q.Advance(time.Millisecond * 100)
+	assert.True(t, idle)
+	assert.Equal(t, "a", buf.String())
+}
@@ -0,0 +1,71 @@
+package timeoutqueue
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// IdleConn wraps a net.Conn, closing it if no Read or Write transfers any data
+// within the queue's timeout. It is safe to Read, Write and Close concurrently,
+// as with any net.Conn.
+type IdleConn struct {
+	net.Conn
+	t    Token
+	idle uint32
+	once sync.Once
+	err  error
+}
+
+// NewIdleConn returns an IdleConn wrapping conn that is closed when it has been
+// idle for the queue's timeout.
+func (tq *TimeoutQueue) NewIdleConn(conn net.Conn) *IdleConn {
+	ic := &IdleConn{
+		Conn: conn,
+	}
+	ic.t = tq.Add(ic.expire)
+	return ic
+}
+
+func (ic *IdleConn) expire() {
+	atomic.StoreUint32(&ic.idle, 1)
+	ic.close()
+}
+
+func (ic *IdleConn) close() error {
+	ic.once.Do(func() {
+		ic.err = ic.Conn.Close()
+	})
+	return ic.err
+}
+
+// Read fulfills net.Conn, resetting the idle timeout if any data is read.
+func (ic *IdleConn) Read(b []byte) (int, error) {
+	n, err := ic.Conn.Read(b)
+	if n > 0 {
+		ic.t.Reset()
+	}
+	return n, err
+}
+
+// Write fulfills net.Conn, resetting the idle timeout if any data is written.
+func (ic *IdleConn) Write(b []byte) (int, error) {
+	n, err := ic.Conn.Write(b)
+	if n > 0 {
+		ic.t.Reset()
+	}
+	return n, err
+}
+
+// Close fulfills net.Conn, canceling the idle timeout. The connection is only
+// closed once, whether by Close or by the timeout, and every call to Close
+// returns the error from closing it.
+func (ic *IdleConn) Close() error {
+	ic.t.Cancel()
+	return ic.close()
+}
+
+// Idle returns true if the connection was closed because it went idle.
+func (ic *IdleConn) Idle() bool {
+	return atomic.LoadUint32(&ic.idle) == 1
+}
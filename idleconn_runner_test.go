@@ -0,0 +1,31 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdleConnCloseRace(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond, 100)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		a, b := net.Pipe()
+		ic := tq.NewIdleConn(a)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			time.Sleep(time.Millisecond)
+			assert.NoError(t, ic.Close())
+			assert.NoError(t, ic.Close())
+			b.Close()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,38 @@
+package timeoutqueue_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdleConn(t *testing.T) {
+	q := timeoutqueuetest.New(time.Millisecond*50, 10)
+	a, b := net.Pipe()
+	defer b.Close()
+	ic := q.NewIdleConn(a)
+
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := b.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		q.Advance(time.Millisecond * 40)
+		_, err := ic.Write([]byte{1})
+		assert.NoError(t, err)
+	}
+	assert.False(t, ic.Idle())
+
+	assert.Equal(t, 1, q.Advance(time.Millisecond*50))
+	assert.True(t, ic.Idle())
+	_, err := ic.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.NoError(t, ic.Close())
+}
@@ -0,0 +1,271 @@
+package timeoutqueue
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrKeyExists is returned by KeyedQueue.Set when the key is already set and
+// the DedupeMode is DedupeError.
+var ErrKeyExists = errors.New("timeoutqueue: key already set")
+
+// DedupeMode controls what KeyedQueue.Set does when the key is already set.
+type DedupeMode uint8
+
+const (
+	// DedupeReplace resets the entry's timeout and replaces it's action. It is
+	// the default.
+	DedupeReplace DedupeMode = iota
+	// DedupeReset resets the entry's timeout but keeps it's original action.
+	DedupeReset
+	// DedupeIgnore leaves the existing entry unchanged.
+	DedupeIgnore
+	// DedupeError leaves the existing entry unchanged and returns ErrKeyExists.
+	DedupeError
+)
+
+// ExpiryMode controls whether accessing a KeyedQueue entry with Touch extends
+// it's life.
+type ExpiryMode uint8
+
+const (
+	// ExpireAbsolute entries expire the queue's timeout after they are Set. It
+	// is the default.
+	ExpireAbsolute ExpiryMode = iota
+	// ExpireSliding entries expire the queue's timeout after they are last
+	// Set or Touched.
+	ExpireSliding
+)
+
+// KeyedQueue identifies entries by caller chosen keys instead of Handles, so
+// callers don't need to keep their own map from keys to Handles. Keys must be
+// comparable.
+type KeyedQueue struct {
+	mux     sync.Mutex
+	tq      *TimeoutQueue
+	entries map[interface{}]*keyedEntry
+	expire  CorrelatedAction
+	dedupe  DedupeMode
+	expiry  ExpiryMode
+	capQ    *TimeoutQueue
+	capped  CorrelatedAction
+}
+
+type keyedEntry struct {
+	handle Handle
+	cap    Handle
+	action TimeoutAction
+	expiry ExpiryMode
+	// adding is set while set adds the entry to the queues and fired if it
+	// expired in the meantime
+	adding, fired bool
+}
+
+// NewKeyedQueue returns a KeyedQueue using the queue for it's timeouts.
+func (tq *TimeoutQueue) NewKeyedQueue() *KeyedQueue {
+	kq := &KeyedQueue{
+		tq:      tq,
+		entries: make(map[interface{}]*keyedEntry),
+	}
+	kq.expire = kq.expired
+	kq.capped = kq.capExpired
+	return kq
+}
+
+func (kq *KeyedQueue) expired(key interface{}) {
+	kq.mux.Lock()
+	e, ok := kq.entries[key]
+	// the key may have been set again between the queue firing and now
+	if !ok || e.handle.live() {
+		kq.mux.Unlock()
+		return
+	}
+	if e.adding {
+		e.fired = true
+		kq.mux.Unlock()
+		return
+	}
+	e.cap.Cancel()
+	delete(kq.entries, key)
+	kq.mux.Unlock()
+	e.action()
+}
+
+func (kq *KeyedQueue) capExpired(key interface{}) {
+	kq.mux.Lock()
+	e, ok := kq.entries[key]
+	if !ok || e.cap.live() {
+		kq.mux.Unlock()
+		return
+	}
+	if e.adding {
+		e.fired = true
+		kq.mux.Unlock()
+		return
+	}
+	e.handle.Cancel()
+	delete(kq.entries, key)
+	kq.mux.Unlock()
+	e.action()
+}
+
+// SetLifetimeCap limits how long an entry can live however often it is
+// Touched; entries Set after the call expire no later than the timeout of
+// capQueue after they were created. Passing nil removes the cap for new
+// entries.
+func (kq *KeyedQueue) SetLifetimeCap(capQueue *TimeoutQueue) {
+	kq.mux.Lock()
+	kq.capQ = capQueue
+	kq.mux.Unlock()
+}
+
+// SetDedupe sets how Set handles a key that is already set.
+func (kq *KeyedQueue) SetDedupe(mode DedupeMode) {
+	kq.mux.Lock()
+	kq.dedupe = mode
+	kq.mux.Unlock()
+}
+
+// SetExpiry sets the ExpiryMode used by Set for new entries.
+func (kq *KeyedQueue) SetExpiry(mode ExpiryMode) {
+	kq.mux.Lock()
+	kq.expiry = mode
+	kq.mux.Unlock()
+}
+
+// Set schedules action to run after the queue's timeout under key. If the key
+// is already set, the DedupeMode decides what happens to the existing entry;
+// an error is only returned by DedupeError.
+func (kq *KeyedQueue) Set(key interface{}, action TimeoutAction) error {
+	kq.mux.Lock()
+	return kq.set(key, action, kq.expiry)
+}
+
+// SetExpiring is the same as Set but uses mode for the entry instead of the
+// KeyedQueue's ExpiryMode. If an existing entry is kept by the DedupeMode, it
+// keeps it's original mode.
+func (kq *KeyedQueue) SetExpiring(key interface{}, action TimeoutAction, mode ExpiryMode) error {
+	kq.mux.Lock()
+	return kq.set(key, action, mode)
+}
+
+// set requires the KeyedQueue's mux lock and will unlock it.
+func (kq *KeyedQueue) set(key interface{}, action TimeoutAction, mode ExpiryMode) error {
+	if e, ok := kq.entries[key]; ok {
+		switch kq.dedupe {
+		case DedupeIgnore:
+			kq.mux.Unlock()
+			return nil
+		case DedupeError:
+			kq.mux.Unlock()
+			return ErrKeyExists
+		}
+		if e.handle.Reset() {
+			if kq.dedupe == DedupeReplace {
+				e.action = action
+				e.expiry = mode
+			}
+			kq.mux.Unlock()
+			return nil
+		}
+		// the entry fired but it's action has not run yet, so it's replaced
+	}
+	e := &keyedEntry{
+		action: action,
+		expiry: mode,
+		adding: true,
+	}
+	kq.entries[key] = e
+	capQ := kq.capQ
+	kq.mux.Unlock()
+
+	// the entry may expire from within addAction, so it is added without the
+	// lock
+	kq.tq.mux.Lock()
+	h := kq.tq.addAction(kq.expire, key)
+	var c Handle
+	if capQ != nil {
+		capQ.mux.Lock()
+		c = capQ.addAction(kq.capped, key)
+	}
+	kq.mux.Lock()
+	if kq.entries[key] != e {
+		// canceled or replaced before it's handles were stored
+		h.Cancel()
+		c.Cancel()
+		kq.mux.Unlock()
+		return nil
+	}
+	e.handle, e.cap = h, c
+	e.adding = false
+	// fired may have been set by an earlier entry for the key, only trust it if
+	// one of this entry's handles is spent
+	if e.fired && (!h.live() || (capQ != nil && !c.live())) {
+		h.Cancel()
+		c.Cancel()
+		delete(kq.entries, key)
+		kq.mux.Unlock()
+		e.action()
+		return nil
+	}
+	kq.mux.Unlock()
+	return nil
+}
+
+// Cancel the entry with key. The returned bool is false if the key was not set.
+func (kq *KeyedQueue) Cancel(key interface{}) bool {
+	kq.mux.Lock()
+	defer kq.mux.Unlock()
+	e, ok := kq.entries[key]
+	if !ok {
+		return false
+	}
+	delete(kq.entries, key)
+	e.cap.Cancel()
+	// an entry that is still being added will be canceled by set
+	return e.handle.Cancel() || e.adding
+}
+
+// Reset the timeout of the entry with key. The returned bool is false if the
+// key was not set.
+func (kq *KeyedQueue) Reset(key interface{}) bool {
+	kq.mux.Lock()
+	defer kq.mux.Unlock()
+	e, ok := kq.entries[key]
+	return ok && (e.adding || e.handle.Reset())
+}
+
+// Touch records an access to the entry with key, which resets it's timeout if
+// it is an ExpireSliding entry. The returned bool is false if the key was not
+// set.
+func (kq *KeyedQueue) Touch(key interface{}) bool {
+	kq.mux.Lock()
+	defer kq.mux.Unlock()
+	e, ok := kq.entries[key]
+	if !ok {
+		return false
+	}
+	if e.adding {
+		return true
+	}
+	if e.expiry == ExpireSliding {
+		return e.handle.Reset()
+	}
+	return e.handle.live()
+}
+
+// Has returns true if the key is set.
+func (kq *KeyedQueue) Has(key interface{}) bool {
+	kq.mux.Lock()
+	_, ok := kq.entries[key]
+	kq.mux.Unlock()
+	return ok
+}
+
+// Len returns the number of keys set.
+func (kq *KeyedQueue) Len() int {
+	kq.mux.Lock()
+	n := len(kq.entries)
+	kq.mux.Unlock()
+	return n
+}
@@ -0,0 +1,148 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedQueue(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	kq := q.NewKeyedQueue()
+
+	var fired []string
+	set := func(key, val string) {
+		assert.NoError(t, kq.Set(key, func() { fired = append(fired, val) }))
+	}
+	set("a", "a1")
+	set("b", "b1")
+	set("c", "c1")
+	assert.Equal(t, 3, kq.Len())
+
+	q.Advance(time.Millisecond * 500)
+	set("a", "a2")
+	assert.True(t, kq.Reset("b"))
+	assert.True(t, kq.Cancel("c"))
+	assert.False(t, kq.Cancel("c"))
+	assert.False(t, kq.Reset("c"))
+	assert.False(t, kq.Has("c"))
+
+	q.Advance(time.Millisecond * 500)
+	assert.Nil(t, fired)
+	q.Advance(time.Millisecond * 500)
+	assert.Equal(t, []string{"a2", "b1"}, fired)
+	assert.Equal(t, 0, kq.Len())
+	assert.False(t, kq.Has("a"))
+}
+
+func TestKeyedQueueDedupe(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	kq := q.NewKeyedQueue()
+
+	var fired []string
+	action := func(val string) timeoutqueue.TimeoutAction {
+		return func() { fired = append(fired, val) }
+	}
+	modes := []timeoutqueue.DedupeMode{
+		timeoutqueue.DedupeReplace,
+		timeoutqueue.DedupeReset,
+		timeoutqueue.DedupeIgnore,
+		timeoutqueue.DedupeError,
+	}
+	for i := range modes {
+		assert.NoError(t, kq.Set(i, action("first")))
+	}
+	q.Advance(time.Millisecond * 500)
+	for i, mode := range modes {
+		kq.SetDedupe(mode)
+		err := kq.Set(i, action("second"))
+		if mode == timeoutqueue.DedupeError {
+			assert.Equal(t, timeoutqueue.ErrKeyExists, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+
+	// ignored and errored keys keep their original deadline
+	q.Advance(time.Millisecond * 500)
+	assert.Equal(t, []string{"first", "first"}, fired)
+	q.Advance(time.Millisecond * 500)
+	assert.Equal(t, []string{"first", "first", "second", "first"}, fired)
+}
+
+func TestKeyedQueueExpiry(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	kq := q.NewKeyedQueue()
+
+	var fired []string
+	action := func(key string) timeoutqueue.TimeoutAction {
+		return func() { fired = append(fired, key) }
+	}
+	assert.NoError(t, kq.Set("absolute", action("absolute")))
+	assert.NoError(t, kq.SetExpiring("sliding", action("sliding"), timeoutqueue.ExpireSliding))
+	kq.SetExpiry(timeoutqueue.ExpireSliding)
+	assert.NoError(t, kq.Set("store", action("store")))
+	assert.NoError(t, kq.SetExpiring("entry", action("entry"), timeoutqueue.ExpireAbsolute))
+
+	q.Advance(time.Millisecond * 600)
+	for _, key := range []string{"absolute", "sliding", "store", "entry"} {
+		assert.True(t, kq.Touch(key))
+	}
+	assert.False(t, kq.Touch("missing"))
+
+	q.Advance(time.Millisecond * 600)
+	assert.Equal(t, []string{"absolute", "entry"}, fired)
+	q.Advance(time.Millisecond * 600)
+	assert.Equal(t, []string{"absolute", "entry", "sliding", "store"}, fired)
+}
+
+func TestKeyedQueueLifetimeCap(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	capQ := timeoutqueue.NewManual(time.Second*3, 10, clock)
+	tq.SetExecutor(timeoutqueue.Inline)
+	capQ.SetExecutor(timeoutqueue.Inline)
+	advance := func(d time.Duration) {
+		clock.Advance(d)
+		tq.Tick()
+		capQ.Tick()
+	}
+
+	kq := tq.NewKeyedQueue()
+	kq.SetExpiry(timeoutqueue.ExpireSliding)
+	kq.SetLifetimeCap(capQ)
+	var fired []string
+	action := func(key string) timeoutqueue.TimeoutAction {
+		return func() { fired = append(fired, key) }
+	}
+	assert.NoError(t, kq.Set("capped", action("capped")))
+	assert.NoError(t, kq.Set("canceled", action("canceled")))
+	assert.True(t, kq.Cancel("canceled"))
+
+	for i := 0; i < 5; i++ {
+		advance(time.Millisecond * 700)
+		kq.Touch("capped")
+	}
+	assert.Equal(t, []string{"capped"}, fired)
+	assert.False(t, kq.Has("capped"))
+	assert.Equal(t, 0, tq.Len())
+	assert.Equal(t, 0, capQ.Len())
+}
+
+func TestKeyedQueueZeroTimeout(t *testing.T) {
+	tq := timeoutqueue.New(0, 10)
+	tq.SetExecutor(timeoutqueue.Inline)
+	kq := tq.NewKeyedQueue()
+	var fired []string
+	// with no timeout the entry expires from within Set
+	assert.NoError(t, kq.Set("a", func() {
+		fired = append(fired, "a")
+		assert.NoError(t, kq.Set("b", func() { fired = append(fired, "b") }))
+	}))
+	assert.Equal(t, []string{"a", "b"}, fired)
+	assert.Equal(t, 0, kq.Len())
+	assert.Equal(t, 0, tq.Len())
+}
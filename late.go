@@ -0,0 +1,41 @@
+package timeoutqueue
+
+import (
+	"time"
+)
+
+// LateAction is called in place of a TimeoutAction that was dispatched too far
+// past it's deadline. It receives the correlation ID of the TimeoutAction, see
+// AddWithID, and how late it was.
+type LateAction func(id interface{}, late time.Duration)
+
+// SetLateThreshold sets how far past it's deadline a TimeoutAction may be and
+// still be called. This protects against a stalled process (from a long GC
+// pause or a frozen VM) firing actions that are no longer meaningful. Actions
+// beyond the threshold are skipped and onLate, if not nil, is called through
+// the Executor instead. A threshold of zero or less removes the limit. Flush
+// is not affected by the threshold.
+func (tq *TimeoutQueue) SetLateThreshold(threshold time.Duration, onLate LateAction) {
+	tq.mux.Lock()
+	tq.lateThreshold = threshold
+	tq.dispatcher.onLate = onLate
+	tq.mux.Unlock()
+}
+
+func (d dispatcher) dispatchLate(id interface{}, late time.Duration) {
+	if d.onLate == nil {
+		return
+	}
+	d.goFunc(func() {
+		d.onLate(id, late)
+	})
+}
+
+// goFunc calls fn through the Executor.
+func (d dispatcher) goFunc(fn func()) {
+	if d.exec == nil {
+		go fn()
+		return
+	}
+	d.exec.Go(fn)
+}
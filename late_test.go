@@ -0,0 +1,50 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLateThreshold(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	tq.SetExecutor(timeoutqueue.ExecutorFunc(func(action func()) {
+		action()
+	}))
+	var called []string
+	var lateness []time.Duration
+	tq.SetLateThreshold(time.Millisecond*100, func(id interface{}, late time.Duration) {
+		called = append(called, id.(string))
+		lateness = append(lateness, late)
+	})
+	action := func(id interface{}) {
+		called = append(called, "fired "+id.(string))
+	}
+
+	tq.AddWithID("a", action)
+	clock.Advance(time.Millisecond * 500)
+	tq.AddWithID("b", action)
+	clock.Advance(time.Millisecond * 550)
+
+	// a is 50ms late, b is not due yet
+	assert.Equal(t, 1, tq.Tick())
+	clock.Advance(time.Second)
+	// b is 550ms late
+	assert.Equal(t, 0, tq.Tick())
+
+	assert.Equal(t, []string{"fired a", "b"}, called)
+	assert.Equal(t, []time.Duration{time.Millisecond * 550}, lateness)
+	s := tq.Stats()
+	assert.Equal(t, uint64(1), s.Late)
+	assert.Equal(t, uint64(0), s.Pending)
+
+	tq.SetLateThreshold(0, nil)
+	tq.AddWithID("c", action)
+	clock.Advance(time.Hour)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, "fired c", called[2])
+}
@@ -0,0 +1,107 @@
+package timeoutqueue
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrLeaseHeld is returned by Acquire if the id already has a live Lease.
+var ErrLeaseHeld = errors.New("timeoutqueue: lease held")
+
+// LeaseManager hands out Leases by id. A Lease must be renewed within the
+// queue's timeout or it expires.
+type LeaseManager struct {
+	mux    sync.Mutex
+	tq     *TimeoutQueue
+	leases map[interface{}]*Lease
+}
+
+// NewLeaseManager returns a LeaseManager whose Leases last for the queue's
+// timeout.
+func (tq *TimeoutQueue) NewLeaseManager() *LeaseManager {
+	return &LeaseManager{
+		tq:     tq,
+		leases: make(map[interface{}]*Lease),
+	}
+}
+
+// Lease is held until it is released or fails to be renewed in time.
+type Lease struct {
+	lm       *LeaseManager
+	id       interface{}
+	onExpire func(id interface{})
+	handle   Handle
+	// adding is set while Acquire adds the Lease to the queue
+	adding bool
+}
+
+// Acquire a Lease on id. If the Lease expires, it is released and onExpire is
+// called with the id.
+func (lm *LeaseManager) Acquire(id interface{}, onExpire func(id interface{})) (*Lease, error) {
+	lm.mux.Lock()
+	if l, ok := lm.leases[id]; ok && (l.adding || l.handle.live()) {
+		lm.mux.Unlock()
+		return nil, ErrLeaseHeld
+	}
+	l := &Lease{
+		lm:       lm,
+		id:       id,
+		onExpire: onExpire,
+		adding:   true,
+	}
+	lm.leases[id] = l
+	lm.mux.Unlock()
+	// the Lease may expire from within Add, so it is added without the lock
+	h := lm.tq.AddHandle(l.expire)
+	lm.mux.Lock()
+	l.handle = h
+	l.adding = false
+	lm.mux.Unlock()
+	return l, nil
+}
+
+func (l *Lease) expire() {
+	l.lm.mux.Lock()
+	// the id may have been acquired again between the queue firing and now
+	if l.lm.leases[l.id] == l {
+		delete(l.lm.leases, l.id)
+	}
+	l.lm.mux.Unlock()
+	if l.onExpire != nil {
+		l.onExpire(l.id)
+	}
+}
+
+// Renew the Lease for another period of the queue's timeout. Renewal and expiry
+// are resolved by the queue; if the Lease has already expired or been released
+// Renew returns false and the Lease is not held.
+func (l *Lease) Renew() bool {
+	return l.handle.Reset()
+}
+
+// Release the Lease without calling onExpire. The returned bool is false if the
+// Lease had already expired or been released.
+func (l *Lease) Release() bool {
+	l.lm.mux.Lock()
+	defer l.lm.mux.Unlock()
+	if !l.handle.Cancel() {
+		return false
+	}
+	if l.lm.leases[l.id] == l {
+		delete(l.lm.leases, l.id)
+	}
+	return true
+}
+
+// ID returns the id the Lease was acquired for.
+func (l *Lease) ID() interface{} {
+	return l.id
+}
+
+// Held returns the number of Leases that are held.
+func (lm *LeaseManager) Held() int {
+	lm.mux.Lock()
+	n := len(lm.leases)
+	lm.mux.Unlock()
+	return n
+}
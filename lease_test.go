@@ -0,0 +1,60 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLease(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	lm := q.NewLeaseManager()
+	var expired []interface{}
+	onExpire := func(id interface{}) {
+		expired = append(expired, id)
+	}
+
+	a, err := lm.Acquire("a", onExpire)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", a.ID())
+	_, err = lm.Acquire("a", onExpire)
+	assert.Equal(t, timeoutqueue.ErrLeaseHeld, err)
+
+	b, err := lm.Acquire("b", onExpire)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, lm.Held())
+
+	q.Advance(time.Millisecond * 900)
+	assert.True(t, a.Renew())
+	q.Advance(time.Millisecond * 200)
+	assert.Equal(t, []interface{}{"b"}, expired)
+	assert.False(t, b.Renew())
+	assert.False(t, b.Release())
+	assert.Equal(t, 1, lm.Held())
+
+	assert.True(t, a.Release())
+	assert.False(t, a.Renew())
+	assert.Equal(t, 0, lm.Held())
+	q.Advance(time.Second)
+	assert.Equal(t, []interface{}{"b"}, expired)
+
+	_, err = lm.Acquire("a", onExpire)
+	assert.NoError(t, err)
+}
+
+func TestLeaseImmediate(t *testing.T) {
+	// with no timeout the Lease expires from within Add
+	tq := timeoutqueue.New(0, 1)
+	tq.SetExecutor(timeoutqueue.Inline)
+	lm := tq.NewLeaseManager()
+	var held []int
+	l, err := lm.Acquire("a", func(interface{}) { held = append(held, lm.Held()) })
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, held)
+	assert.Equal(t, 0, lm.Held())
+	assert.False(t, l.Renew())
+	assert.False(t, l.Release())
+}
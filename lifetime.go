@@ -0,0 +1,24 @@
+//go:build go1.24
+
+package timeoutqueue
+
+import (
+	"runtime"
+)
+
+// CancelWhenCollected cancels t if owner is garbage collected first, so a
+// Token belonging to an object that is dropped without being cleaned up, for
+// instance on an error path, does not fire later or hold a place in the queue.
+// As with any cleanup it runs some time after owner becomes unreachable, not
+// straight away, and it is never run if the program exits first. The
+// TimeoutAction must not reference owner, or owner will never be collected.
+// Calling Stop on the returned Cleanup detaches t from owner. The cleanup runs
+// on a Go routine of the runtime's, so CancelWhenCollected is not supported
+// with the timeoutqueuesingle tag. It needs Go 1.24 for runtime.AddCleanup.
+func CancelWhenCollected[T any](owner *T, t Token) runtime.Cleanup {
+	// the type parameter is only there because runtime.AddCleanup takes owner
+	// as a *T, the rest of the package does not use generics
+	return runtime.AddCleanup(owner, func(t Token) {
+		t.Cancel()
+	}, t)
+}
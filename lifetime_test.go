@@ -0,0 +1,38 @@
+//go:build go1.24 && !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+type owner struct {
+	buf []byte
+}
+
+func TestCancelWhenCollected(t *testing.T) {
+	tq := timeoutqueue.NewManual(time.Second, 10, nil)
+	func() {
+		o := &owner{buf: make([]byte, 64)}
+		timeoutqueue.CancelWhenCollected(o, tq.Add(func() {}))
+	}()
+	kept := &owner{buf: make([]byte, 64)}
+	tkn := tq.Add(func() {})
+	c := timeoutqueue.CancelWhenCollected(kept, tkn)
+	assert.Equal(t, 2, tq.Len())
+
+	for i := 0; i < 100 && tq.Len() > 1; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 1, tq.Len())
+	runtime.KeepAlive(kept)
+
+	c.Stop()
+	assert.True(t, tkn.Cancel())
+}
@@ -64,5 +64,5 @@ func TestLinkedLists(t *testing.T) {
 	assert.EqualValues(t, 1, tq.free)
 
 	// Just to get to 100% test coverage
-	Token(token{}).private()
+	Token(Handle{}).private()
 }
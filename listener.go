@@ -0,0 +1,51 @@
+package timeoutqueue
+
+import (
+	"time"
+)
+
+// FireListener is notified each time a TimeoutAction is called, with it's
+// correlation ID, see AddWithID, it's deadline, when it fired and why.
+type FireListener func(id interface{}, deadline, fired time.Time, reason Reason)
+
+type fireListener struct {
+	fn FireListener
+	id uint64
+}
+
+// AddFireListener registers a FireListener, so several independent parts of a
+// program, like metrics and replication, can each observe actions firing
+// without wrapping the actions. Listeners are called in the order they were
+// added from the same Go routine as the action once it returns. Listeners are not
+// called for actions skipped by the late threshold. The returned func removes
+// the listener.
+func (tq *TimeoutQueue) AddFireListener(l FireListener) (remove func()) {
+	tq.mux.Lock()
+	tq.listenerID++
+	id := tq.listenerID
+	// the slice is copied so dispatchers already holding it are unaffected
+	listeners := make([]fireListener, len(tq.dispatcher.listeners), len(tq.dispatcher.listeners)+1)
+	copy(listeners, tq.dispatcher.listeners)
+	tq.dispatcher.listeners = append(listeners, fireListener{fn: l, id: id})
+	tq.mux.Unlock()
+	return func() {
+		tq.mux.Lock()
+		old := tq.dispatcher.listeners
+		for i, fl := range old {
+			if fl.id == id {
+				listeners := make([]fireListener, 0, len(old)-1)
+				listeners = append(listeners, old[:i]...)
+				tq.dispatcher.listeners = append(listeners, old[i+1:]...)
+				break
+			}
+		}
+		tq.mux.Unlock()
+	}
+}
+
+// notify calls the listeners for an action that was called.
+func (d dispatcher) notify(n node, fired time.Time, reason Reason) {
+	for _, fl := range d.listeners {
+		fl.fn(n.id, n.timeout, fired, reason)
+	}
+}
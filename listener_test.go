@@ -0,0 +1,37 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFireListener(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	var got []string
+	removeA := q.AddFireListener(func(id interface{}, deadline, fired time.Time, reason timeoutqueue.Reason) {
+		got = append(got, "a "+id.(string)+" "+reason.String())
+	})
+	q.AddFireListener(func(id interface{}, deadline, fired time.Time, reason timeoutqueue.Reason) {
+		if reason == timeoutqueue.ReasonTimeout {
+			assert.False(t, fired.Before(deadline))
+		}
+		got = append(got, "b "+id.(string))
+	})
+
+	nop := func(interface{}) {}
+	q.AddWithID("x", func(interface{}) { got = append(got, "action") })
+	q.AddWithID("y", nop).Cancel()
+	q.Advance(time.Second)
+	assert.Equal(t, []string{"action", "a x Timeout", "b x"}, got)
+
+	got = nil
+	removeA()
+	removeA()
+	q.AddWithID("z", nop)
+	q.Flush()
+	assert.Equal(t, []string{"b z"}, got)
+}
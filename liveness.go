@@ -0,0 +1,155 @@
+package timeoutqueue
+
+import (
+	"sync"
+)
+
+// PeerState is the liveness of a peer tracked by a LivenessTracker.
+type PeerState uint8
+
+// The states a peer moves through. A peer that is not tracked is Unknown.
+const (
+	Unknown PeerState = iota
+	Alive
+	Suspect
+	Dead
+)
+
+var peerStateNames = [...]string{
+	Unknown: "Unknown",
+	Alive:   "Alive",
+	Suspect: "Suspect",
+	Dead:    "Dead",
+}
+
+func (ps PeerState) String() string {
+	if int(ps) < len(peerStateNames) {
+		return peerStateNames[ps]
+	}
+	return "Invalid"
+}
+
+// LivenessTracker marks peers Alive whenever a message is received from them.
+// A peer that is silent for the suspect queue's timeout becomes Suspect and one
+// that is silent for the dead queue's timeout becomes Dead and is no longer
+// tracked. The dead queue's timeout should be the longer of the two.
+type LivenessTracker struct {
+	mux       sync.Mutex
+	suspectQ  *TimeoutQueue
+	deadQ     *TimeoutQueue
+	onChange  func(id interface{}, from, to PeerState)
+	peers     map[interface{}]*peer
+	toSuspect CorrelatedAction
+	toDead    CorrelatedAction
+}
+
+type peer struct {
+	state   PeerState
+	suspect Handle
+	dead    Handle
+}
+
+// NewLivenessTracker returns a LivenessTracker using the two queues for it's
+// timeouts. If onChange is not nil, it is called for every state transition
+// with the tracker locked, so it may not call methods on the tracker.
+func NewLivenessTracker(suspect, dead *TimeoutQueue, onChange func(id interface{}, from, to PeerState)) *LivenessTracker {
+	lt := &LivenessTracker{
+		suspectQ: suspect,
+		deadQ:    dead,
+		onChange: onChange,
+		peers:    make(map[interface{}]*peer),
+	}
+	lt.toSuspect = lt.suspected
+	lt.toDead = lt.died
+	return lt
+}
+
+// transition requires the tracker's mux lock.
+func (lt *LivenessTracker) transition(id interface{}, p *peer, to PeerState) {
+	from := p.state
+	p.state = to
+	if from != to && lt.onChange != nil {
+		lt.onChange(id, from, to)
+	}
+}
+
+// Seen marks the peer Alive and restarts it's timeouts.
+func (lt *LivenessTracker) Seen(id interface{}) {
+	lt.mux.Lock()
+	p, ok := lt.peers[id]
+	if !ok {
+		p = &peer{}
+		lt.peers[id] = p
+	}
+	addSuspect := !p.suspect.Reset()
+	addDead := !p.dead.Reset()
+	lt.transition(id, p, Alive)
+	lt.mux.Unlock()
+
+	// the timeouts may fire from within Add, so they are added without the lock
+	var suspect, dead Handle
+	if addSuspect {
+		lt.suspectQ.mux.Lock()
+		suspect = lt.suspectQ.addAction(lt.toSuspect, id)
+	}
+	if addDead {
+		lt.deadQ.mux.Lock()
+		dead = lt.deadQ.addAction(lt.toDead, id)
+	}
+	lt.mux.Lock()
+	if lt.peers[id] == p {
+		if addSuspect {
+			p.suspect = suspect
+		}
+		if addDead {
+			p.dead = dead
+		}
+	} else {
+		suspect.Cancel()
+		dead.Cancel()
+	}
+	lt.mux.Unlock()
+}
+
+func (lt *LivenessTracker) suspected(id interface{}) {
+	lt.mux.Lock()
+	// if the timeout is live, the peer was seen again after this fired
+	if p, ok := lt.peers[id]; ok && p.state == Alive && !p.suspect.live() {
+		lt.transition(id, p, Suspect)
+	}
+	lt.mux.Unlock()
+}
+
+func (lt *LivenessTracker) died(id interface{}) {
+	lt.mux.Lock()
+	if p, ok := lt.peers[id]; ok && !p.dead.live() {
+		p.suspect.Cancel()
+		delete(lt.peers, id)
+		lt.transition(id, p, Dead)
+	}
+	lt.mux.Unlock()
+}
+
+// State returns the current state of the peer.
+func (lt *LivenessTracker) State(id interface{}) PeerState {
+	lt.mux.Lock()
+	defer lt.mux.Unlock()
+	if p, ok := lt.peers[id]; ok {
+		return p.state
+	}
+	return Unknown
+}
+
+// Remove stops tracking the peer without a state transition. The returned bool
+// is false if the peer was not tracked.
+func (lt *LivenessTracker) Remove(id interface{}) bool {
+	lt.mux.Lock()
+	defer lt.mux.Unlock()
+	p, ok := lt.peers[id]
+	if ok {
+		p.suspect.Cancel()
+		p.dead.Cancel()
+		delete(lt.peers, id)
+	}
+	return ok
+}
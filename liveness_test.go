@@ -0,0 +1,74 @@
+package timeoutqueue_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLivenessTracker(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	suspect := timeoutqueue.NewManual(time.Second, 10, clock)
+	dead := timeoutqueue.NewManual(time.Second*3, 10, clock)
+	suspect.SetExecutor(timeoutqueue.Inline)
+	dead.SetExecutor(timeoutqueue.Inline)
+	advance := func(d time.Duration) {
+		clock.Advance(d)
+		suspect.Tick()
+		dead.Tick()
+	}
+
+	var changes []string
+	lt := timeoutqueue.NewLivenessTracker(suspect, dead, func(id interface{}, from, to timeoutqueue.PeerState) {
+		changes = append(changes, fmt.Sprintf("%v %s->%s", id, from, to))
+	})
+
+	lt.Seen("a")
+	lt.Seen("b")
+	assert.Equal(t, timeoutqueue.Alive, lt.State("a"))
+	advance(time.Millisecond * 500)
+	lt.Seen("a")
+	advance(time.Millisecond * 600)
+	assert.Equal(t, timeoutqueue.Alive, lt.State("a"))
+	assert.Equal(t, timeoutqueue.Suspect, lt.State("b"))
+
+	lt.Seen("b")
+	advance(time.Second * 2)
+	assert.Equal(t, timeoutqueue.Suspect, lt.State("a"))
+	advance(time.Millisecond * 500)
+	assert.Equal(t, "Dead", timeoutqueue.Dead.String())
+	assert.Equal(t, timeoutqueue.Unknown, lt.State("a"))
+
+	assert.True(t, lt.Remove("b"))
+	assert.False(t, lt.Remove("b"))
+	assert.Equal(t, []string{
+		"a Unknown->Alive",
+		"b Unknown->Alive",
+		"b Alive->Suspect",
+		"b Suspect->Alive",
+		"a Alive->Suspect",
+		"b Alive->Suspect",
+		"a Suspect->Dead",
+	}, changes)
+}
+
+func TestLivenessTrackerImmediate(t *testing.T) {
+	// with no suspect timeout the peer is suspected from within Add
+	suspect := timeoutqueue.New(0, 1)
+	suspect.SetExecutor(timeoutqueue.Inline)
+	dead := timeoutqueue.NewManual(time.Hour, 1, nil)
+	var changes []string
+	lt := timeoutqueue.NewLivenessTracker(suspect, dead, func(id interface{}, from, to timeoutqueue.PeerState) {
+		changes = append(changes, fmt.Sprintf("%v:%v->%v", id, from, to))
+	})
+	lt.Seen("a")
+	assert.Equal(t, []string{"a:Unknown->Alive", "a:Alive->Suspect"}, changes)
+	assert.Equal(t, timeoutqueue.Suspect, lt.State("a"))
+	assert.Equal(t, 1, dead.Len())
+	assert.True(t, lt.Remove("a"))
+	assert.Equal(t, 0, dead.Len())
+}
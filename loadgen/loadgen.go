@@ -0,0 +1,177 @@
+// Package loadgen drives a TimeoutQueue with a synthetic workload and reports
+// how it behaved. It is meant for checking that a queue configured the way an
+// application uses it keeps up with the expected load and for catching
+// performance regressions in that integration.
+package loadgen
+
+import (
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+)
+
+// Distribution of timeouts given to the actions added.
+type Distribution interface {
+	Duration(r *rand.Rand) time.Duration
+}
+
+// Fixed gives every action the same timeout.
+type Fixed time.Duration
+
+// Duration fulfills Distribution.
+func (f Fixed) Duration(r *rand.Rand) time.Duration {
+	return time.Duration(f)
+}
+
+// Uniform gives timeouts evenly spread between Min and Max.
+type Uniform struct {
+	Min, Max time.Duration
+}
+
+// Duration fulfills Distribution.
+func (u Uniform) Duration(r *rand.Rand) time.Duration {
+	if u.Max <= u.Min {
+		return u.Min
+	}
+	return u.Min + time.Duration(r.Int63n(int64(u.Max-u.Min)))
+}
+
+// Exponential gives timeouts with the mean Mean, most of them short with a long
+// tail.
+type Exponential struct {
+	Mean time.Duration
+}
+
+// Duration fulfills Distribution.
+func (e Exponential) Duration(r *rand.Rand) time.Duration {
+	return time.Duration(r.ExpFloat64() * float64(e.Mean))
+}
+
+// Config describes a workload. Rates are operations per second. Cancel and
+// Reset pick a random action that has not yet fired or been canceled, if there
+// is one.
+type Config struct {
+	AddRate, CancelRate, ResetRate float64
+	// Duration is how long operations are generated for.
+	Duration time.Duration
+	// Timeouts is the distribution of timeouts for added actions. If it is nil,
+	// the queue's timeout is used, otherwise the queue's timeout is set before
+	// each Add so the queue should not be shared while the workload runs.
+	Timeouts Distribution
+	// Tick is how often operations are generated, defaulting to a millisecond.
+	// Operations due between ticks are done together.
+	Tick time.Duration
+	// Seed for the random choices, so a workload can be repeated.
+	Seed int64
+}
+
+// Report of a workload. Drift and the counts come from the queue's Stats so
+// they include anything else the queue did while the workload ran.
+type Report struct {
+	Added, Canceled, Reset, Fired uint64
+	// Pending is the number of actions added by the workload that had neither
+	// fired nor been canceled when it finished. Actions that are mid way
+	// through firing count as fired.
+	Pending int
+	Elapsed time.Duration
+	Drift   timeoutqueue.Histogram
+	// Allocs and Bytes are the heap allocations made by the whole process while
+	// the workload ran.
+	Allocs, Bytes uint64
+}
+
+// AllocsPerOp returns the heap allocations per Add, Cancel and Reset.
+func (r Report) AllocsPerOp() float64 {
+	ops := r.Added + r.Canceled + r.Reset
+	if ops == 0 {
+		return 0
+	}
+	return float64(r.Allocs) / float64(ops)
+}
+
+// Run the workload against tq, returning once cfg.Duration has passed.
+// Actions still pending are left in the queue.
+func Run(tq *timeoutqueue.TimeoutQueue, cfg Config) Report {
+	tick := cfg.Tick
+	if tick <= 0 {
+		tick = time.Millisecond
+	}
+	r := rand.New(rand.NewSource(cfg.Seed))
+	var fired uint64
+	action := func() {
+		atomic.AddUint64(&fired, 1)
+	}
+	var handles []timeoutqueue.Handle
+	var pending int
+	pick := func() int {
+		if len(handles) == 0 {
+			return -1
+		}
+		return r.Intn(len(handles))
+	}
+
+	before := tq.Stats()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	allocs, bytes := mem.Mallocs, mem.TotalAlloc
+
+	var added, canceled, reset float64
+	start := time.Now()
+	for elapsed := time.Duration(0); elapsed < cfg.Duration; elapsed = time.Since(start) {
+		secs := elapsed.Seconds()
+		for ; added < cfg.AddRate*secs; added++ {
+			if cfg.Timeouts != nil {
+				tq.SetTimeoutForNew(cfg.Timeouts.Duration(r))
+			}
+			handles = append(handles, tq.AddHandle(action))
+			pending++
+		}
+		for ; canceled < cfg.CancelRate*secs; canceled++ {
+			// a handle that has already fired is dropped without counting
+			// toward the rate, as is one that fails to Reset
+			if i := pick(); i >= 0 {
+				if handles[i].Cancel() {
+					pending--
+				} else {
+					canceled--
+				}
+				remove(&handles, i)
+			}
+		}
+		for ; reset < cfg.ResetRate*secs; reset++ {
+			if i := pick(); i >= 0 && !handles[i].Reset() {
+				reset--
+				remove(&handles, i)
+			}
+		}
+		time.Sleep(tick)
+	}
+
+	rep := Report{
+		Elapsed: time.Since(start),
+		Fired:   atomic.LoadUint64(&fired),
+	}
+	runtime.ReadMemStats(&mem)
+	rep.Allocs = mem.Mallocs - allocs
+	rep.Bytes = mem.TotalAlloc - bytes
+	after := tq.Stats()
+	rep.Added = after.Added - before.Added
+	rep.Canceled = after.Canceled - before.Canceled
+	rep.Reset = after.Reset - before.Reset
+	rep.Drift.Sum = after.Drift.Sum - before.Drift.Sum
+	for i := range rep.Drift.Counts {
+		rep.Drift.Counts[i] = after.Drift.Counts[i] - before.Drift.Counts[i]
+	}
+	rep.Pending = pending - int(rep.Fired)
+	return rep
+}
+
+func remove(handles *[]timeoutqueue.Handle, i int) {
+	hs := *handles
+	last := len(hs) - 1
+	hs[i] = hs[last]
+	*handles = hs[:last]
+}
@@ -0,0 +1,29 @@
+//go:build !timeoutqueuesingle
+
+package loadgen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/loadgen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 100)
+	rep := loadgen.Run(tq, loadgen.Config{
+		AddRate:    10000,
+		CancelRate: 2000,
+		ResetRate:  2000,
+		Duration:   time.Millisecond * 50,
+		Timeouts:   loadgen.Uniform{Min: time.Millisecond, Max: time.Millisecond * 10},
+	})
+	assert.True(t, rep.Added > 100)
+	assert.True(t, rep.Canceled > 0)
+	assert.True(t, rep.Fired > 0)
+	assert.True(t, rep.Fired <= rep.Drift.Count())
+	assert.Equal(t, int(rep.Added-rep.Canceled-rep.Fired), rep.Pending)
+	assert.True(t, rep.AllocsPerOp() > 0)
+}
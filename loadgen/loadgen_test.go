@@ -0,0 +1,21 @@
+package loadgen_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/loadgen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistributions(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	assert.Equal(t, time.Second, loadgen.Fixed(time.Second).Duration(r))
+	u := loadgen.Uniform{Min: time.Millisecond, Max: time.Millisecond * 2}
+	for i := 0; i < 100; i++ {
+		d := u.Duration(r)
+		assert.True(t, d >= u.Min && d < u.Max)
+		assert.True(t, loadgen.Exponential{Mean: time.Millisecond}.Duration(r) >= 0)
+	}
+}
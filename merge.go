@@ -0,0 +1,112 @@
+package timeoutqueue
+
+import (
+	"sync"
+	"time"
+)
+
+// mergeMux is held while locking two queues so that two merges in opposite
+// directions cannot deadlock.
+var mergeMux sync.Mutex
+
+// Merge moves everything pending in other into tq, keeping their deadlines, and
+// returns the number moved. Paused entries are moved after the rest and stay
+// paused with the duration they had remaining. Moved entries count as canceled
+// in other's Stats and Events and as added in tq's. Tokens from other do not
+// follow the entries. There is no need for the queues to have the same timeout,
+// though entries will fire in deadline order. Entries added with AddPinned stay
+// pinned and entries keep their class if tq has it; in a class tq does not have
+// they join class 0 but stay pinned, so SetTimeout on tq does not move them.
+// Tags and tenants are not moved. If tq is from NewFixed and fills up, it
+// follows it's FullPolicy and Merge stops at the first entry it rejects,
+// leaving the rest in other.
+func (tq *TimeoutQueue) Merge(other *TimeoutQueue) int {
+	if other == tq {
+		return 0
+	}
+	mergeMux.Lock()
+	tq.mux.Lock()
+	other.mux.Lock()
+	mergeMux.Unlock()
+
+	var moved int
+	var out []evicted
+	// move takes a node out of other and inserts it in tq with the deadline,
+	// the returned bool is false if tq rejected it
+	move := func(idx uint32, deadline time.Time) (Handle, bool) {
+		n := other.nodes[idx]
+		ev, ok := tq.room(n.id)
+		if !ok {
+			return Handle{}, false
+		}
+		if ev.n.action != nil {
+			out = append(out, ev)
+		}
+		other.emit(EventCanceled, idx)
+		other.freeNode(idx)
+		class := n.class
+		if int(class) > len(tq.classes) {
+			class = 0
+		}
+		h := tq.insertClass(n.action, n.id, class, deadline)
+		if n.pinned {
+			tq.pin(h.nodeIdx)
+		}
+		moved++
+		return h, true
+	}
+	ok := true
+	for ok && other.head != empty {
+		_, ok = move(other.head, other.deadline(other.head))
+	}
+	now := tq.clockNow()
+	for _, idx := range other.pausedNodes() {
+		if !ok {
+			break
+		}
+		var h Handle
+		remaining := other.paused[idx]
+		if h, ok = move(idx, now.Add(remaining)); ok {
+			tq.pause(h.nodeIdx, remaining)
+		}
+	}
+	other.mux.Unlock()
+
+	tq.rearm()
+	tq.debugValidate()
+	tq.mux.Unlock()
+	for _, ev := range out {
+		ev.dispatch(tq)
+	}
+	return moved
+}
+
+// MoveTo atomically removes the action from it's queue and adds it to other with
+// other's timeout, keeping it's correlation ID. As a Handle is a value, the
+// moved action has a new Handle which is returned. If the action already fired
+// or was canceled the returned bool is false and nothing is added, so an action
+// can never both fire from it's old queue and be moved. Moving to the same
+// queue is the same as Reset.
+func (t Handle) MoveTo(other *TimeoutQueue) (Handle, bool) {
+	if t.tq == nil {
+		return Handle{}, false
+	}
+	if other == t.tq {
+		return t, t.Reset()
+	}
+	mergeMux.Lock()
+	t.tq.mux.Lock()
+	other.mux.Lock()
+	mergeMux.Unlock()
+
+	n := t.tq.nodes[t.nodeIdx]
+	if n.action == nil || n.actionID != t.actionID {
+		other.mux.Unlock()
+		t.tq.mux.Unlock()
+		return Handle{}, false
+	}
+	t.tq.emit(EventCanceled, t.nodeIdx)
+	t.tq.freeNode(t.nodeIdx)
+	t.tq.mux.Unlock()
+	return other.addAction(n.action, n.id), true
+}
@@ -0,0 +1,27 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeRunner(t *testing.T) {
+	a := timeoutqueue.New(time.Millisecond*50, 10)
+	b := timeoutqueue.New(time.Millisecond*5, 10)
+	ch := make(chan int, 2)
+	a.Add(func() { ch <- 1 })
+	b.Add(func() { ch <- 2 })
+	a.Merge(b)
+
+	select {
+	case i := <-ch:
+		assert.Equal(t, 2, i)
+	case <-time.After(time.Millisecond * 40):
+		t.Error("merged entry did not fire before the original")
+	}
+}
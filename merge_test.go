@@ -0,0 +1,73 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	a := timeoutqueue.NewManual(time.Second, 10, clock)
+	b := timeoutqueue.NewManual(time.Second, 10, clock)
+	a.SetExecutor(timeoutqueue.Inline)
+
+	var fired []int
+	add := func(tq *timeoutqueue.TimeoutQueue, i int) timeoutqueue.Token {
+		return tq.Add(func() { fired = append(fired, i) })
+	}
+	add(b, 0)
+	clock.Advance(time.Millisecond * 100)
+	add(a, 1)
+	clock.Advance(time.Millisecond * 100)
+	bt := add(b, 2)
+	add(b, 3)
+	clock.Advance(time.Millisecond * 100)
+	add(a, 4)
+	clock.Advance(time.Millisecond * 100)
+	add(b, 5)
+
+	assert.Equal(t, 0, a.Merge(a))
+	assert.Equal(t, 4, a.Merge(b))
+	assert.NoError(t, a.Validate())
+	assert.Equal(t, 0, b.Len())
+	assert.Equal(t, 6, a.Len())
+	assert.False(t, bt.Cancel())
+	assert.Equal(t, uint64(4), b.Stats().Canceled)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, 6, a.Tick())
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5}, fired)
+}
+
+func TestMoveTo(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	fast := timeoutqueue.NewManual(time.Second, 10, clock)
+	slow := timeoutqueue.NewManual(time.Second*5, 10, clock)
+	slow.SetExecutor(timeoutqueue.Inline)
+
+	var fired []interface{}
+	tkn := fast.AddWithID("a", func(id interface{}) { fired = append(fired, id) })
+	moved, ok := tkn.MoveTo(slow)
+	assert.True(t, ok)
+	assert.False(t, tkn.Cancel())
+	_, ok = tkn.MoveTo(slow)
+	assert.False(t, ok)
+	_, ok = timeoutqueue.Handle{}.MoveTo(slow)
+	assert.False(t, ok)
+	assert.Equal(t, 0, fast.Len())
+
+	same, ok := moved.MoveTo(slow)
+	assert.True(t, ok)
+	assert.Equal(t, moved, same)
+
+	clock.Advance(time.Second * 2)
+	assert.Equal(t, 0, fast.Tick())
+	assert.Equal(t, 0, slow.Tick())
+	clock.Advance(time.Second * 3)
+	assert.Equal(t, 1, slow.Tick())
+	assert.Equal(t, []interface{}{"a"}, fired)
+}
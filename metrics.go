@@ -0,0 +1,72 @@
+package timeoutqueue
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// WriteMetrics writes the queue's Stats to w in the OpenMetrics text format,
+// which Prometheus also accepts. This allows the queue to be scraped without
+// importing a metrics client library. The metric names all begin with
+// timeoutqueue_.
+func (tq *TimeoutQueue) WriteMetrics(w io.Writer) error {
+	s := tq.Stats()
+	bw := bufio.NewWriter(w)
+
+	writeMetric(bw, "pending", "gauge", "TimeoutActions in the queue.", "", s.Pending)
+	counters := []struct {
+		name, help string
+		value      uint64
+	}{
+		{"added", "TimeoutActions added to the queue.", s.Added},
+		{"fired", "TimeoutActions called.", s.Fired},
+		{"canceled", "TimeoutActions canceled.", s.Canceled},
+		{"reset", "TimeoutActions reset.", s.Reset},
+		{"grew", "Times the internal slice grew.", s.Grew},
+		{"late", "TimeoutActions skipped for being too late.", s.Late},
+	}
+	for _, c := range counters {
+		writeMetric(bw, c.name, "counter", c.help, "_total", c.value)
+	}
+
+	bw.WriteString("# TYPE timeoutqueue_drift_seconds histogram\n")
+	bw.WriteString("# HELP timeoutqueue_drift_seconds How late TimeoutActions were dispatched.\n")
+	var cumulative uint64
+	for i, c := range s.Drift.Counts {
+		cumulative += c
+		le := "+Inf"
+		if i < len(DriftBuckets) {
+			le = strconv.FormatFloat(DriftBuckets[i].Seconds(), 'g', -1, 64)
+		}
+		bw.WriteString(`timeoutqueue_drift_seconds_bucket{le="`)
+		bw.WriteString(le)
+		bw.WriteString(`"} `)
+		bw.WriteString(strconv.FormatUint(cumulative, 10))
+		bw.WriteByte('\n')
+	}
+	bw.WriteString("timeoutqueue_drift_seconds_sum ")
+	bw.WriteString(strconv.FormatFloat(s.Drift.Sum.Seconds(), 'g', -1, 64))
+	bw.WriteString("\ntimeoutqueue_drift_seconds_count ")
+	bw.WriteString(strconv.FormatUint(cumulative, 10))
+	bw.WriteString("\n# EOF\n")
+
+	return bw.Flush()
+}
+
+func writeMetric(bw *bufio.Writer, name, kind, help, suffix string, value uint64) {
+	bw.WriteString("# TYPE timeoutqueue_")
+	bw.WriteString(name)
+	bw.WriteByte(' ')
+	bw.WriteString(kind)
+	bw.WriteString("\n# HELP timeoutqueue_")
+	bw.WriteString(name)
+	bw.WriteByte(' ')
+	bw.WriteString(help)
+	bw.WriteString("\ntimeoutqueue_")
+	bw.WriteString(name)
+	bw.WriteString(suffix)
+	bw.WriteByte(' ')
+	bw.WriteString(strconv.FormatUint(value, 10))
+	bw.WriteByte('\n')
+}
@@ -0,0 +1,44 @@
+package timeoutqueue_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	tq.SetExecutor(timeoutqueue.ExecutorFunc(func(action func()) {
+		action()
+	}))
+	tq.Add(func() {})
+	tq.Add(func() {})
+	clock.Advance(time.Second + time.Millisecond*2)
+	tq.Tick()
+	tq.Add(func() {})
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, tq.WriteMetrics(buf))
+	out := buf.String()
+	for _, line := range []string{
+		"# TYPE timeoutqueue_pending gauge",
+		"timeoutqueue_pending 1",
+		"# TYPE timeoutqueue_added counter",
+		"timeoutqueue_added_total 3",
+		"timeoutqueue_fired_total 2",
+		`timeoutqueue_drift_seconds_bucket{le="0.001"} 0`,
+		`timeoutqueue_drift_seconds_bucket{le="0.0025"} 2`,
+		`timeoutqueue_drift_seconds_bucket{le="+Inf"} 2`,
+		"timeoutqueue_drift_seconds_sum 0.004",
+		"timeoutqueue_drift_seconds_count 2",
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+	assert.True(t, strings.HasSuffix(out, "# EOF\n"))
+}
@@ -0,0 +1,120 @@
+package timeoutqueue
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// NATKeepalive sends periodic keepalives for NAT bindings so the mappings do
+// not expire. Each binding is refreshed margin before it's expiry, less a random
+// jitter picked once per binding so that bindings created together do not send
+// in lock step. Bindings with similar refresh intervals share a queue.
+type NATKeepalive struct {
+	margin time.Duration
+	jitter time.Duration
+	send   func(id interface{})
+	queues delayQueues
+}
+
+// NewNATKeepalive returns a NATKeepalive that calls send with the id of each
+// binding that needs a keepalive.
+func NewNATKeepalive(margin, jitter time.Duration, send func(id interface{})) *NATKeepalive {
+	return &NATKeepalive{
+		margin: margin,
+		jitter: jitter,
+		send:   send,
+		queues: newDelayQueues(),
+	}
+}
+
+// SetResolution sets the granularity refresh intervals are rounded up to. The
+// default is one millisecond.
+func (nk *NATKeepalive) SetResolution(resolution time.Duration) {
+	nk.queues.setResolution(resolution)
+}
+
+// Binding is a NAT mapping being kept alive by a NATKeepalive.
+type Binding struct {
+	mux    sync.Mutex
+	nk     *NATKeepalive
+	id     interface{}
+	d      time.Duration
+	handle Handle
+	closed bool
+}
+
+// Bind starts sending keepalives for a binding that expires after expiry
+// without traffic. The interval is never less than one nanosecond, so a margin
+// and jitter larger than expiry send keepalives as fast as the queue allows.
+func (nk *NATKeepalive) Bind(id interface{}, expiry time.Duration) *Binding {
+	d := expiry - nk.margin
+	if nk.jitter > 0 {
+		d -= time.Duration(rand.Int63n(int64(nk.jitter)))
+	}
+	if d <= 0 {
+		d = 1
+	}
+	b := &Binding{
+		nk: nk,
+		id: id,
+		d:  d,
+	}
+	b.arm()
+	return b
+}
+
+// arm adds the binding's next keepalive. It must be called without the
+// binding's mux lock.
+func (b *Binding) arm() {
+	h := b.nk.queues.add(b.d, b.refresh)
+	b.mux.Lock()
+	if b.closed {
+		h.Cancel()
+	} else {
+		b.handle = h
+	}
+	b.mux.Unlock()
+}
+
+func (b *Binding) refresh() {
+	b.mux.Lock()
+	closed := b.closed
+	b.mux.Unlock()
+	if closed {
+		return
+	}
+	b.arm()
+	b.nk.send(b.id)
+}
+
+// Seen resets the binding's timer when other traffic has refreshed the
+// mapping, so no keepalive is needed yet. It returns false if the binding
+// is closed or a keepalive is already being sent.
+func (b *Binding) Seen() bool {
+	b.mux.Lock()
+	ok := !b.closed && b.handle.Cancel()
+	b.mux.Unlock()
+	if ok {
+		b.arm()
+	}
+	return ok
+}
+
+// Close stops the keepalives when the binding is torn down. The returned bool
+// is false if it was already closed.
+func (b *Binding) Close() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.closed {
+		return false
+	}
+	b.closed = true
+	b.handle.Cancel()
+	return true
+}
+
+// ID returns the id the binding was created with.
+func (b *Binding) ID() interface{} {
+	return b.id
+}
@@ -0,0 +1,62 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNATKeepalive(t *testing.T) {
+	var mux sync.Mutex
+	sent := make(map[interface{}]int)
+	nk := timeoutqueue.NewNATKeepalive(time.Millisecond*10, time.Millisecond*5, func(id interface{}) {
+		mux.Lock()
+		sent[id]++
+		mux.Unlock()
+	})
+
+	a := nk.Bind("a", time.Millisecond*40)
+	b := nk.Bind("b", time.Millisecond*40)
+	assert.Equal(t, "a", a.ID())
+	assert.True(t, b.Close())
+	assert.False(t, b.Close())
+	assert.False(t, b.Seen())
+
+	time.Sleep(time.Millisecond * 100)
+	assert.True(t, a.Close())
+	mux.Lock()
+	assert.True(t, sent["a"] >= 2)
+	assert.Equal(t, 0, sent["b"])
+	n := sent["a"]
+	mux.Unlock()
+
+	time.Sleep(time.Millisecond * 50)
+	mux.Lock()
+	assert.Equal(t, n, sent["a"])
+	mux.Unlock()
+}
+
+func TestNATKeepaliveCloseFromSend(t *testing.T) {
+	// an interval of a nanosecond sends as fast as the queue allows
+	bound := make(chan *timeoutqueue.Binding, 1)
+	sent := make(chan bool, 1)
+	nk := timeoutqueue.NewNATKeepalive(time.Second, 0, func(interface{}) {
+		b := <-bound
+		assert.True(t, b.Close())
+		sent <- true
+	})
+	nk.SetResolution(0)
+	bound <- nk.Bind("a", time.Millisecond)
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("no keepalive")
+	}
+	time.Sleep(time.Millisecond * 10)
+	assert.Len(t, sent, 0)
+}
@@ -0,0 +1,29 @@
+package timeoutqueue
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// NextDeadline returns the earliest deadline in the queue without taking the
+// lock, so it can be polled often without contending with Add and Cancel. The
+// returned bool is false if the queue is empty. As it is read without the lock
+// the deadline may already be out of date, and it is truncated to the wall
+// clock so it has no monotonic reading. Paused actions are not included.
+func (tq *TimeoutQueue) NextDeadline() (time.Time, bool) {
+	next := atomic.LoadInt64(&tq.next)
+	if next == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, next), true
+}
+
+// publishNext requires a mux lock. It is called whenever the head of the list
+// or it's deadline changes.
+func (tq *TimeoutQueue) publishNext() {
+	var next int64
+	if tq.head != empty {
+		next = tq.deadline(tq.head).UnixNano()
+	}
+	atomic.StoreInt64(&tq.next, next)
+}
@@ -0,0 +1,37 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDeadline(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	_, ok := tq.NextDeadline()
+	assert.False(t, ok)
+
+	start := clock.Now()
+	a := tq.Add(func() {})
+	clock.Advance(time.Millisecond * 100)
+	tq.Add(func() {})
+	next, ok := tq.NextDeadline()
+	assert.True(t, ok)
+	assert.True(t, next.Equal(start.Add(time.Second)))
+
+	a.Cancel()
+	next, _ = tq.NextDeadline()
+	assert.True(t, next.Equal(start.Add(time.Millisecond*1100)))
+
+	tq.SetTimeout(time.Millisecond * 500)
+	next, _ = tq.NextDeadline()
+	assert.True(t, next.Equal(start.Add(time.Millisecond*600)))
+
+	tq.Flush()
+	_, ok = tq.NextDeadline()
+	assert.False(t, ok)
+}
@@ -0,0 +1,99 @@
+package timeoutqueue
+
+import (
+	"sort"
+	"time"
+)
+
+// Pause freezes the remaining duration of a single action, taking it out of the
+// queue until Resume is called. A paused action never fires, but it still
+// counts towards Len and can be canceled or reset; resetting a paused action
+// sets it's remaining duration to the full timeout and leaves it paused. Flush
+// and Close call paused actions, but Drain leaves them. The returned bool is
+// false if the action already fired, was canceled or is already paused.
+func (t Handle) Pause() bool {
+	if t.tq == nil {
+		return false
+	}
+	tq := t.tq
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	n := tq.nodes[t.nodeIdx]
+	if n.action == nil || n.actionID != t.actionID || tq.isPaused(t.nodeIdx) {
+		return false
+	}
+	remaining := tq.deadline(t.nodeIdx).Sub(tq.now())
+	if remaining < 0 {
+		remaining = 0
+	}
+	tq.pause(t.nodeIdx, remaining)
+	tq.debugValidate()
+	return true
+}
+
+// pause requires a mux lock. It takes a node out of the list and holds it
+// paused with the given remaining duration.
+func (tq *TimeoutQueue) pause(nodeIdx uint32, remaining time.Duration) {
+	tq.remove(nodeIdx)
+	if tq.paused == nil {
+		tq.paused = make(map[uint32]time.Duration)
+	}
+	tq.paused[nodeIdx] = remaining
+}
+
+// Resume puts a paused action back in the queue with the duration it had
+// remaining when it was paused. The returned bool is false if the action is not
+// paused.
+func (t Handle) Resume() bool {
+	if t.tq == nil {
+		return false
+	}
+	tq := t.tq
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	n := tq.nodes[t.nodeIdx]
+	remaining, ok := tq.paused[t.nodeIdx]
+	if n.action == nil || n.actionID != t.actionID || !ok {
+		return false
+	}
+	delete(tq.paused, t.nodeIdx)
+	tq.setDeadline(t.nodeIdx, tq.deadlineAfter(remaining))
+	tq.linkClass(t.nodeIdx)
+	tq.rearm()
+	tq.debugValidate()
+	return true
+}
+
+// Paused returns true if the action is paused.
+func (t Handle) Paused() bool {
+	if t.tq == nil {
+		return false
+	}
+	t.tq.mux.Lock()
+	defer t.tq.mux.Unlock()
+	n := t.tq.nodes[t.nodeIdx]
+	return n.action != nil && n.actionID == t.actionID && t.tq.isPaused(t.nodeIdx)
+}
+
+// pausedNodes requires a mux lock. It returns the paused nodes in index order,
+// so anything walking them does so the same way every time.
+func (tq *TimeoutQueue) pausedNodes() []uint32 {
+	if len(tq.paused) == 0 {
+		return nil
+	}
+	paused := make([]uint32, 0, len(tq.paused))
+	for idx := range tq.paused {
+		paused = append(paused, idx)
+	}
+	sort.Slice(paused, func(i, j int) bool { return paused[i] < paused[j] })
+	return paused
+}
+
+// isPaused requires a mux lock.
+func (tq *TimeoutQueue) isPaused(nodeIdx uint32) bool {
+	if len(tq.paused) == 0 {
+		return false
+	}
+	_, ok := tq.paused[nodeIdx]
+	return ok
+}
@@ -0,0 +1,116 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPause(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	var fired []int
+	a := q.Add(func() { fired = append(fired, 1) })
+	q.Add(func() { fired = append(fired, 2) })
+	q.Advance(time.Millisecond * 600)
+
+	assert.True(t, a.Pause())
+	assert.False(t, a.Pause())
+	assert.Equal(t, 2, q.Len())
+	assert.NoError(t, q.Validate())
+	assert.Equal(t, 1, q.Advance(time.Hour))
+	assert.Equal(t, []int{2}, fired)
+
+	assert.True(t, a.Resume())
+	assert.False(t, a.Resume())
+	assert.Equal(t, 0, q.Advance(time.Millisecond*300))
+	assert.Equal(t, 1, q.Advance(time.Millisecond*100))
+	assert.Equal(t, []int{2, 1}, fired)
+	assert.False(t, a.Pause())
+
+	// reset while paused gives the full timeout once resumed
+	b := q.Add(func() { fired = append(fired, 3) })
+	assert.True(t, b.Pause())
+	assert.True(t, b.Reset())
+	assert.Equal(t, 0, q.Advance(time.Hour))
+	assert.True(t, b.Resume())
+	assert.Equal(t, 0, q.Advance(time.Millisecond*900))
+	assert.Equal(t, 1, q.Advance(time.Millisecond*100))
+
+	// cancel and flush see paused actions
+	c := q.Add(func() { fired = append(fired, 4) })
+	c.Pause()
+	assert.True(t, c.Cancel())
+	assert.Equal(t, 0, q.Len())
+	q.Add(func() { fired = append(fired, 5) }).Pause()
+	q.Flush()
+	assert.Equal(t, []int{2, 1, 3, 5}, fired)
+	assert.NoError(t, q.Validate())
+}
+
+func TestFlushWherePaused(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	var fired []int
+	q.AddWithID(1, func(interface{}) { fired = append(fired, 1) }).Pause()
+	q.AddWithID(2, func(interface{}) { fired = append(fired, 2) })
+	kept := q.AddWithID(3, func(interface{}) { fired = append(fired, 3) })
+	kept.Pause()
+	q.FlushWhere(func(t timeoutqueue.Token) bool { return t != kept })
+	assert.Equal(t, []int{2, 1}, fired)
+	assert.Equal(t, 1, q.Len())
+	assert.NoError(t, q.Validate())
+}
+
+func TestCancelIfPaused(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	a := q.AddWithID(1, func(interface{}) {})
+	a.Pause()
+	q.AddWithID(2, func(interface{}) {})
+	assert.Equal(t, 2, q.CancelIf(func(timeoutqueue.Token) bool { return true }))
+	assert.Equal(t, 0, q.Len())
+	assert.False(t, a.Resume())
+	assert.NoError(t, q.Validate())
+}
+
+func TestCancelIDPaused(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	q.AddWithID("a", func(interface{}) {}).Pause()
+	q.AddWithID("a", func(interface{}) {})
+	q.AddWithID("b", func(interface{}) {}).Pause()
+	assert.Equal(t, 2, q.CancelID("a"))
+	assert.Equal(t, 1, q.Len())
+	assert.NoError(t, q.Validate())
+}
+
+func TestCountWherePaused(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	q.Add(func() {}).Pause()
+	q.Add(func() {})
+	all := func(timeoutqueue.Token) bool { return true }
+	assert.Equal(t, q.Len(), q.CountWhere(all))
+	assert.Equal(t, 2, q.CountWhere(all))
+}
+
+func TestExportPaused(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	nop := func(interface{}) {}
+	q.AddWithID("a", nop)
+	q.Advance(time.Millisecond * 400)
+	q.AddWithID("b", nop).Pause()
+	entries := q.Export()
+	assert.Equal(t, []timeoutqueue.Entry{
+		{ID: "a", Remaining: time.Millisecond * 600},
+		{ID: "b", Remaining: time.Second, Paused: true},
+	}, entries)
+
+	restored := timeoutqueuetest.New(time.Second, 10)
+	handles := restored.Import(nil, entries, func(interface{}) timeoutqueue.CorrelatedAction {
+		return nop
+	})
+	assert.False(t, handles[0].Paused())
+	assert.True(t, handles[1].Paused())
+	assert.Equal(t, entries, restored.Export())
+	assert.NoError(t, restored.Validate())
+}
@@ -0,0 +1,63 @@
+package timeoutqueue
+
+import (
+	"time"
+)
+
+// AddPinned adds a TimeoutAction whose deadline is pinned, so SetTimeout never
+// moves it. This lets a few timers with a mandated duration share a queue whose
+// timeout is tuned dynamically. Resetting a pinned action uses the queue's
+// timeout at the time of the Reset and it remains pinned.
+func (tq *TimeoutQueue) AddPinned(action TimeoutAction) Token {
+	tq.mux.Lock()
+	return tq.addNode(action, tq.hookID(), 0, true)
+}
+
+// pin requires a mux lock.
+func (tq *TimeoutQueue) pin(nodeIdx uint32) {
+	if !tq.nodes[nodeIdx].pinned {
+		tq.nodes[nodeIdx].pinned = true
+		tq.pinned++
+	}
+}
+
+// relinkPinned requires a mux lock. After SetTimeout has moved every node by d,
+// the pinned nodes are moved back and may then be out of order. They are still
+// in order among themselves, so they are taken out and merged back in a single
+// pass over the list.
+func (tq *TimeoutQueue) relinkPinned(d time.Duration) {
+	// the pinned nodes are chained through next while they are out of the list
+	first, last := empty, empty
+	for cur := tq.head; cur != empty; {
+		next := tq.nodes[cur].next
+		if tq.nodes[cur].pinned {
+			tq.remove(cur)
+			tq.nodes[cur].timeout = tq.nodes[cur].timeout.Add(-d)
+			tq.nodes[cur].next = empty
+			if first == empty {
+				first = cur
+			} else {
+				tq.nodes[last].next = cur
+			}
+			last = cur
+		}
+		cur = next
+	}
+	at := tq.head
+	for p := first; p != empty; {
+		next := tq.nodes[p].next
+		timeout := tq.nodes[p].timeout
+		for at != empty && !tq.nodes[at].timeout.After(timeout) {
+			at = tq.nodes[at].next
+		}
+		if at == empty {
+			tq.nodes[p].next = empty
+			tq.nodes[p].prev = tq.tail
+			tq.add(p)
+		} else {
+			tq.insertBefore(p, at)
+		}
+		p = next
+	}
+	tq.debugValidate()
+}
@@ -0,0 +1,79 @@
+package timeoutqueue_test
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddPinned(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	tq.SetExecutor(timeoutqueue.Inline)
+
+	var fired []int
+	tq.Add(func() { fired = append(fired, 1) })
+	tq.AddPinned(func() { fired = append(fired, 2) })
+	canceled := tq.AddPinned(func() { fired = append(fired, 3) })
+	tq.Add(func() { fired = append(fired, 4) })
+	assert.True(t, canceled.Cancel())
+
+	tq.SetTimeout(time.Millisecond * 100)
+	assert.NoError(t, tq.Validate())
+	clock.Advance(time.Millisecond * 100)
+	assert.Equal(t, 2, tq.Tick())
+	assert.Equal(t, []int{1, 4}, fired)
+
+	tq.SetTimeout(time.Second * 5)
+	clock.Advance(time.Millisecond * 900)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, []int{1, 4, 2}, fired)
+
+	// a node that was pinned is not pinned when it is reused
+	tq.Add(func() { fired = append(fired, 5) })
+	tq.SetTimeout(time.Second)
+	clock.Advance(time.Second)
+	assert.Equal(t, 1, tq.Tick())
+}
+
+func TestSetTimeoutInterleavedPinned(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 0, clock)
+	tq.SetExecutor(timeoutqueue.Inline)
+	var fired []int
+	// entries 10ms apart, every third one pinned
+	for i := 0; i < 30; i++ {
+		i := i
+		action := func() { fired = append(fired, i) }
+		if i%3 == 0 {
+			tq.AddPinned(action)
+		} else {
+			tq.Add(action)
+		}
+		clock.Advance(time.Millisecond * 10)
+	}
+	// moving the rest 305ms earlier puts each pinned entry behind those added
+	// in the 300ms after it
+	tq.SetTimeout(time.Millisecond * 695)
+	assert.NoError(t, tq.Validate())
+	clock.Advance(time.Hour)
+	assert.Equal(t, 30, tq.Tick())
+	// entry i is due at i*10ms plus 695ms, or 1s if it is pinned
+	expected := make([]int, 30)
+	for i := range expected {
+		expected[i] = i
+	}
+	due := func(i int) time.Duration {
+		d := time.Duration(i) * time.Millisecond * 10
+		if i%3 == 0 {
+			return d + time.Second
+		}
+		return d + time.Millisecond*695
+	}
+	sort.SliceStable(expected, func(a, b int) bool { return due(expected[a]) < due(expected[b]) })
+	assert.Equal(t, expected, fired)
+}
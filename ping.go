@@ -0,0 +1,123 @@
+package timeoutqueue
+
+import (
+	"sync"
+)
+
+// Pinger enforces a deadline on the pong for each ping sent on a connection,
+// such as a WebSocket. It does not depend on any WebSocket package; instead
+// it is connected with small funcs. With gorilla/websocket:
+//
+//	p := tq.NewPinger(func() error {
+//		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second))
+//	}, func() { conn.Close() })
+//	conn.SetPongHandler(func(string) error { p.Pong(); return nil })
+//
+// and with nhooyr.io/websocket, whose Ping waits for the pong:
+//
+//	p := tq.NewPinger(func() error {
+//		go func() {
+//			if conn.Ping(ctx) == nil {
+//				p.Pong()
+//			}
+//		}()
+//		return nil
+//	}, func() { conn.Close(websocket.StatusPolicyViolation, "pong timeout") })
+type Pinger struct {
+	mux     sync.Mutex
+	tq      *TimeoutQueue
+	ping    func() error
+	onClose func()
+	handle  Handle
+	stopped bool
+	// adding is set while Ping adds the deadline to the queue and ponged if
+	// the pong arrived in the meantime
+	adding, ponged bool
+}
+
+// NewPinger returns a Pinger that uses ping to send each ping and calls onClose
+// if a pong does not arrive within the queue's timeout.
+func (tq *TimeoutQueue) NewPinger(ping func() error, onClose func()) *Pinger {
+	return &Pinger{
+		tq:      tq,
+		ping:    ping,
+		onClose: onClose,
+	}
+}
+
+func (p *Pinger) expired() {
+	p.mux.Lock()
+	if p.stopped {
+		p.mux.Unlock()
+		return
+	}
+	p.stopped = true
+	p.mux.Unlock()
+	if p.onClose != nil {
+		p.onClose()
+	}
+}
+
+// Ping sends a ping and schedules the deadline for it's pong. If an earlier ping
+// is still waiting for a pong, it's deadline is kept. An error from sending the
+// ping is returned and no deadline is scheduled for it. After Stop or the close
+// callback, Ping does nothing.
+func (p *Pinger) Ping() error {
+	p.mux.Lock()
+	if p.stopped {
+		p.mux.Unlock()
+		return nil
+	}
+	if err := p.ping(); err != nil {
+		p.mux.Unlock()
+		return err
+	}
+	if p.adding || p.handle.live() {
+		p.mux.Unlock()
+		return nil
+	}
+	p.adding, p.ponged = true, false
+	p.mux.Unlock()
+
+	// the deadline may expire from within AddHandle, so it is added without the
+	// lock
+	h := p.tq.AddHandle(p.expired)
+	p.mux.Lock()
+	p.adding = false
+	if p.stopped || p.ponged {
+		h.Cancel()
+	} else {
+		p.handle = h
+	}
+	p.mux.Unlock()
+	return nil
+}
+
+// Pong records that a pong was received, canceling the deadline. It returns
+// false if no pong was expected.
+func (p *Pinger) Pong() bool {
+	p.mux.Lock()
+	ok := p.handle.Cancel()
+	if p.adding && !p.ponged {
+		p.ponged, ok = true, true
+	}
+	p.mux.Unlock()
+	return ok
+}
+
+// Waiting returns true if a ping is waiting for it's pong.
+func (p *Pinger) Waiting() bool {
+	p.mux.Lock()
+	waiting := p.handle.live() || (p.adding && !p.ponged && !p.stopped)
+	p.mux.Unlock()
+	return waiting
+}
+
+// Stop the Pinger. The close callback will not be called after Stop returns,
+// unless it was already running.
+func (p *Pinger) Stop() {
+	p.mux.Lock()
+	p.stopped = true
+	p.handle.Cancel()
+	p.mux.Unlock()
+}
@@ -0,0 +1,77 @@
+package timeoutqueue_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPinger(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	var pings, closes int
+	var pingErr error
+	p := q.NewPinger(func() error {
+		pings++
+		return pingErr
+	}, func() {
+		closes++
+	})
+
+	assert.NoError(t, p.Ping())
+	assert.True(t, p.Waiting())
+	q.Advance(time.Millisecond * 500)
+	assert.True(t, p.Pong())
+	assert.False(t, p.Pong())
+	q.Advance(time.Second)
+	assert.Equal(t, 0, closes)
+
+	pingErr = errors.New("write failed")
+	assert.Equal(t, pingErr, p.Ping())
+	assert.False(t, p.Waiting())
+	pingErr = nil
+
+	// a second ping keeps the first ping's deadline
+	assert.NoError(t, p.Ping())
+	q.Advance(time.Millisecond * 500)
+	assert.NoError(t, p.Ping())
+	q.Advance(time.Millisecond * 500)
+	assert.Equal(t, 1, closes)
+	assert.Equal(t, 4, pings)
+
+	assert.NoError(t, p.Ping())
+	assert.Equal(t, 4, pings)
+}
+
+func TestPingerStop(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	var closes int
+	p := q.NewPinger(func() error { return nil }, func() {
+		closes++
+	})
+	assert.NoError(t, p.Ping())
+	p.Stop()
+	q.Advance(time.Second)
+	assert.Equal(t, 0, closes)
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestPingerZeroTimeout(t *testing.T) {
+	tq := timeoutqueue.New(0, 10)
+	tq.SetExecutor(timeoutqueue.Inline)
+	var p *timeoutqueue.Pinger
+	closes := 0
+	p = tq.NewPinger(func() error { return nil }, func() {
+		// with no timeout the deadline expires from within Ping
+		closes++
+		assert.False(t, p.Waiting())
+		assert.NoError(t, p.Ping())
+	})
+	assert.NoError(t, p.Ping())
+	assert.Equal(t, 1, closes)
+	assert.False(t, p.Waiting())
+	assert.Equal(t, 0, tq.Len())
+}
@@ -0,0 +1,63 @@
+package timeoutqueue
+
+// SetThreshold sets a limit on the number of pending TimeoutActions. When the
+// number pending reaches threshold, fn is called with true and when it falls
+// back below threshold, fn is called with false. This lets producers shed load
+// before the queue grows without bound. The fn is called with the queue locked,
+// so it may not call methods on the queue or its Tokens. A threshold of zero or
+// less removes the limit.
+func (tq *TimeoutQueue) SetThreshold(threshold int, fn func(over bool)) {
+	tq.mux.Lock()
+	tq.setPressure(threshold, threshold-1, fn)
+	tq.mux.Unlock()
+}
+
+// SetWatermarks is like SetThreshold but with hysteresis. When the number
+// pending reaches high, fn is called with true, but it is not called with false
+// until the number pending has fallen to low. This avoids toggling rapidly
+// when the queue hovers around a single threshold. If low is not less than
+// high it is treated as high-1, which is the same as SetThreshold(high, fn).
+func (tq *TimeoutQueue) SetWatermarks(high, low int, fn func(over bool)) {
+	if low >= high {
+		low = high - 1
+	}
+	tq.mux.Lock()
+	tq.setPressure(high, low, fn)
+	tq.mux.Unlock()
+}
+
+// setPressure requires a mux lock.
+func (tq *TimeoutQueue) setPressure(high, low int, fn func(over bool)) {
+	if high <= 0 || fn == nil {
+		tq.pressure = pressure{}
+		return
+	}
+	tq.pressure = pressure{
+		high: high,
+		low:  low,
+		fn:   fn,
+	}
+	tq.checkPressure()
+}
+
+type pressure struct {
+	high, low int
+	over      bool
+	fn        func(over bool)
+}
+
+// checkPressure requires a mux lock and must be called whenever pending
+// changes.
+func (tq *TimeoutQueue) checkPressure() {
+	p := &tq.pressure
+	if p.fn == nil {
+		return
+	}
+	if !p.over && tq.pending >= p.high {
+		p.over = true
+		p.fn(true)
+	} else if p.over && tq.pending <= p.low {
+		p.over = false
+		p.fn(false)
+	}
+}
@@ -0,0 +1,60 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreshold(t *testing.T) {
+	tq := timeoutqueue.New(time.Second, 10)
+	var calls []bool
+	tq.SetThreshold(2, func(over bool) {
+		calls = append(calls, over)
+	})
+
+	a := tq.Add(func() {})
+	assert.Len(t, calls, 0)
+	b := tq.Add(func() {})
+	assert.Equal(t, []bool{true}, calls)
+	tq.Add(func() {})
+	assert.Equal(t, []bool{true}, calls)
+
+	a.Cancel()
+	assert.Equal(t, []bool{true}, calls)
+	b.Cancel()
+	assert.Equal(t, []bool{true, false}, calls)
+
+	tq.SetThreshold(0, nil)
+	tq.Add(func() {})
+	tq.Add(func() {})
+	assert.Equal(t, []bool{true, false}, calls)
+	tq.Flush()
+}
+
+func TestWatermarks(t *testing.T) {
+	tq := timeoutqueue.New(time.Second, 10)
+	var calls []bool
+	tq.SetWatermarks(3, 1, func(over bool) {
+		calls = append(calls, over)
+	})
+
+	tkns := []timeoutqueue.Token{
+		tq.Add(func() {}),
+		tq.Add(func() {}),
+		tq.Add(func() {}),
+	}
+	assert.Equal(t, []bool{true}, calls)
+
+	tkns[0].Cancel()
+	assert.Equal(t, []bool{true}, calls)
+	tq.Add(func() {})
+	assert.Equal(t, []bool{true}, calls)
+	tkns[1].Cancel()
+	tkns[2].Cancel()
+	assert.Equal(t, []bool{true, false}, calls)
+	tq.Flush()
+	assert.Equal(t, []bool{true, false}, calls)
+}
@@ -0,0 +1,52 @@
+package timeoutqueue
+
+// Producer stages TimeoutActions from a single Go routine and adds them to the
+// queue in batches, so a producer only takes the queue's lock once per batch
+// instead of once per action. The timeout of a staged action starts when the
+// batch is added to the queue, not when it is staged. A Producer is not
+// threadsafe; each producing Go routine should have it's own.
+type Producer struct {
+	tq      *TimeoutQueue
+	actions []TimeoutAction
+	handles []Handle
+}
+
+// NewProducer returns a Producer that adds to the queue whenever size actions
+// have been staged.
+func (tq *TimeoutQueue) NewProducer(size int) *Producer {
+	if size < 1 {
+		size = 1
+	}
+	return &Producer{
+		tq:      tq,
+		actions: make([]TimeoutAction, 0, size),
+		handles: make([]Handle, 0, size),
+	}
+}
+
+// Add stages the action. If this fills the Producer the batch is added to the
+// queue and the Handles are returned, otherwise the returned slice is nil.
+func (p *Producer) Add(action TimeoutAction) []Handle {
+	p.actions = append(p.actions, action)
+	if len(p.actions) < cap(p.actions) {
+		return nil
+	}
+	return p.Flush()
+}
+
+// Flush adds everything staged to the queue and returns the Handles in the
+// order the actions were staged. The returned slice is reused by the Producer
+// and is only valid until the next call to Add or Flush.
+func (p *Producer) Flush() []Handle {
+	p.handles = p.tq.AddBatch(p.handles[:0], p.actions...)
+	for i := range p.actions {
+		p.actions[i] = nil
+	}
+	p.actions = p.actions[:0]
+	return p.handles
+}
+
+// Staged returns the number of actions waiting to be added to the queue.
+func (p *Producer) Staged() int {
+	return len(p.actions)
+}
@@ -0,0 +1,34 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeout"
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProducer(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
+	ch := make(chan int, 3)
+	p := tq.NewProducer(2)
+
+	assert.Nil(t, p.Add(getAction(ch, 1)))
+	assert.Equal(t, 1, p.Staged())
+	assert.Equal(t, 0, tq.CountWhere(func(timeoutqueue.Token) bool { return true }))
+
+	handles := p.Add(getAction(ch, 2))
+	assert.Len(t, handles, 2)
+	assert.Equal(t, 0, p.Staged())
+	assert.True(t, handles[1].Cancel())
+
+	p.Add(getAction(ch, 3))
+	assert.Len(t, p.Flush(), 1)
+
+	assert.NoError(t, timeout.After(20, func() {
+		assert.Equal(t, 4, <-ch+<-ch)
+	}))
+}
@@ -0,0 +1,166 @@
+package timeoutqueue
+
+import (
+	"sync"
+	"time"
+)
+
+// PTOManager arms a probe timeout for each network path, in the style of QUIC.
+// Every consecutive expiration without an ACK doubles the path's PTO, up to the
+// maximum, and an ACK resets it. Paths with similar PTOs share a queue.
+type PTOManager struct {
+	max     time.Duration
+	onProbe func(id interface{}, probes int)
+	queues  delayQueues
+}
+
+// NewPTOManager returns a PTOManager that calls onProbe with the path id and the
+// number of consecutive expirations each time a path's PTO expires. A max of
+// zero or less means the PTO can grow without limit.
+func NewPTOManager(max time.Duration, onProbe func(id interface{}, probes int)) *PTOManager {
+	return &PTOManager{
+		max:     max,
+		onProbe: onProbe,
+		queues:  newDelayQueues(),
+	}
+}
+
+// SetResolution sets the granularity PTOs are rounded up to. The default is one
+// millisecond.
+func (pm *PTOManager) SetResolution(resolution time.Duration) {
+	pm.queues.setResolution(resolution)
+}
+
+// Path is the probe timeout state of a single network path.
+type Path struct {
+	mux    sync.Mutex
+	pm     *PTOManager
+	id     interface{}
+	base   time.Duration
+	probes int
+	handle Handle
+	gen    uint64
+	closed bool
+}
+
+// Arm a path with a base PTO. For QUIC the base is the smoothed RTT plus four
+// times the RTT variation plus the peer's max ACK delay; it can be updated as
+// the RTT is measured with SetBase.
+func (pm *PTOManager) Arm(id interface{}, base time.Duration) *Path {
+	p := &Path{
+		pm:   pm,
+		id:   id,
+		base: base,
+	}
+	p.arm(0, p.pto())
+	return p
+}
+
+// pto requires the Path's mux lock.
+func (p *Path) pto() time.Duration {
+	d := p.base
+	for i := 0; i < p.probes; i++ {
+		if p.pm.max > 0 && d >= p.pm.max/2 {
+			return p.pm.max
+		}
+		d *= 2
+	}
+	if p.pm.max > 0 && d > p.pm.max {
+		return p.pm.max
+	}
+	if d <= 0 {
+		d = 1
+	}
+	return d
+}
+
+// arm adds the PTO for generation gen. It must be called without the Path's
+// mux lock; if the path was closed or acked in the meantime the PTO is
+// canceled.
+func (p *Path) arm(gen uint64, d time.Duration) {
+	h := p.pm.queues.add(d, func() { p.expired(gen) })
+	p.mux.Lock()
+	if p.closed || p.gen != gen {
+		h.Cancel()
+	} else {
+		p.handle = h
+	}
+	p.mux.Unlock()
+}
+
+// expired is called when the PTO armed for generation gen fires. If the path
+// was acked after it fired the Ack has already armed the next PTO.
+func (p *Path) expired(gen uint64) {
+	p.mux.Lock()
+	if p.closed || p.gen != gen {
+		p.mux.Unlock()
+		return
+	}
+	p.probes++
+	probes := p.probes
+	p.gen++
+	gen, d := p.gen, p.pto()
+	p.mux.Unlock()
+	p.arm(gen, d)
+	if p.pm.onProbe != nil {
+		p.pm.onProbe(p.id, probes)
+	}
+}
+
+// Ack resets the backoff and restarts the PTO from the base. It returns false
+// if the path is closed.
+func (p *Path) Ack() bool {
+	p.mux.Lock()
+	if p.closed {
+		p.mux.Unlock()
+		return false
+	}
+	p.handle.Cancel()
+	p.probes = 0
+	p.gen++
+	gen, d := p.gen, p.pto()
+	p.mux.Unlock()
+	p.arm(gen, d)
+	return true
+}
+
+// SetBase changes the base PTO. It takes effect the next time the PTO is armed.
+func (p *Path) SetBase(base time.Duration) {
+	p.mux.Lock()
+	p.base = base
+	p.mux.Unlock()
+}
+
+// PTO returns the path's current probe timeout, including any backoff.
+func (p *Path) PTO() time.Duration {
+	p.mux.Lock()
+	d := p.pto()
+	p.mux.Unlock()
+	return d
+}
+
+// Probes returns the number of consecutive expirations since the last Ack.
+func (p *Path) Probes() int {
+	p.mux.Lock()
+	probes := p.probes
+	p.mux.Unlock()
+	return probes
+}
+
+// Close stops the path's PTO. The returned bool is false if it was already
+// closed.
+func (p *Path) Close() bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.closed {
+		return false
+	}
+	p.closed = true
+	p.handle.Cancel()
+	return true
+}
+
+// ID returns the id the path was armed with.
+func (p *Path) ID() interface{} {
+	return p.id
+}
@@ -0,0 +1,52 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPTOManager(t *testing.T) {
+	ch := make(chan int, 10)
+	pm := timeoutqueue.NewPTOManager(time.Millisecond*20, func(id interface{}, probes int) {
+		assert.Equal(t, "path", id)
+		ch <- probes
+	})
+	p := pm.Arm("path", time.Millisecond*5)
+	assert.Equal(t, time.Millisecond*5, p.PTO())
+
+	start := time.Now()
+	wait := func(expected int) {
+		select {
+		case probes := <-ch:
+			assert.Equal(t, expected, probes)
+		case <-time.After(time.Second):
+			t.Error("timed out waiting for probe", expected)
+		}
+	}
+	// 5ms, 10ms then 20ms, capped at the max
+	wait(1)
+	wait(2)
+	wait(3)
+	assert.True(t, time.Since(start) >= time.Millisecond*35)
+	assert.Equal(t, time.Millisecond*20, p.PTO())
+
+	assert.True(t, p.Ack())
+	assert.Equal(t, 0, p.Probes())
+	assert.Equal(t, time.Millisecond*5, p.PTO())
+	p.SetBase(time.Millisecond * 7)
+	assert.Equal(t, time.Millisecond*7, p.PTO())
+
+	assert.True(t, p.Close())
+	assert.False(t, p.Close())
+	assert.False(t, p.Ack())
+	select {
+	case <-ch:
+		t.Error("probe after close")
+	case <-time.After(time.Millisecond * 30):
+	}
+}
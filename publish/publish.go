@@ -0,0 +1,73 @@
+// Package publish sends an event to a message bus whenever an action fires,
+// so other services can react to expirations without being in the same
+// process.
+package publish
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+)
+
+// Publisher sends data to a subject on a message bus. It matches the Publish
+// method of a NATS connection; other buses need a small adapter.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// Message is the JSON published when an action fires.
+type Message struct {
+	Key       string    `json:"key"`
+	Scheduled time.Time `json:"scheduled"`
+	Fired     time.Time `json:"fired"`
+	Payload   []byte    `json:"payload,omitempty"`
+}
+
+// Hook publishes a Message for every action added through it.
+type Hook struct {
+	pub     Publisher
+	subject string
+	onError func(error)
+}
+
+// New returns a Hook publishing to subject. If onError is not nil, it is called
+// with any error from encoding or publishing.
+func New(pub Publisher, subject string, onError func(error)) *Hook {
+	return &Hook{
+		pub:     pub,
+		subject: subject,
+		onError: onError,
+	}
+}
+
+// Wrap returns a TimedAction that publishes a Message and then calls action,
+// if it is not nil.
+func (h *Hook) Wrap(key string, payload []byte, action timeoutqueue.TimeoutAction) timeoutqueue.TimedAction {
+	return func(scheduled, fired time.Time) {
+		h.publish(Message{
+			Key:       key,
+			Scheduled: scheduled,
+			Fired:     fired,
+			Payload:   payload,
+		})
+		if action != nil {
+			action()
+		}
+	}
+}
+
+// Add is shorthand for adding the action from Wrap to tq.
+func (h *Hook) Add(tq *timeoutqueue.TimeoutQueue, key string, payload []byte, action timeoutqueue.TimeoutAction) timeoutqueue.Token {
+	return tq.AddTimed(h.Wrap(key, payload, action))
+}
+
+func (h *Hook) publish(m Message) {
+	data, err := json.Marshal(m)
+	if err == nil {
+		err = h.pub.Publish(h.subject, data)
+	}
+	if err != nil && h.onError != nil {
+		h.onError(err)
+	}
+}
@@ -0,0 +1,50 @@
+package publish_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/publish"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	subjects []string
+	data     [][]byte
+	err      error
+}
+
+func (r *recorder) Publish(subject string, data []byte) error {
+	r.subjects = append(r.subjects, subject)
+	r.data = append(r.data, data)
+	return r.err
+}
+
+func TestHook(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	r := &recorder{}
+	var errs []error
+	h := publish.New(r, "timers.fired", func(err error) { errs = append(errs, err) })
+
+	start := q.Clock.Now()
+	called := false
+	h.Add(q.TimeoutQueue, "a", []byte("hello"), func() { called = true })
+	q.Advance(time.Second * 2)
+	assert.True(t, called)
+	assert.Equal(t, []string{"timers.fired"}, r.subjects)
+
+	var m publish.Message
+	assert.NoError(t, json.Unmarshal(r.data[0], &m))
+	assert.Equal(t, "a", m.Key)
+	assert.Equal(t, []byte("hello"), m.Payload)
+	assert.True(t, m.Scheduled.Equal(start.Add(time.Second)))
+	assert.True(t, m.Fired.Equal(start.Add(time.Second*2)))
+
+	r.err = errors.New("bus down")
+	h.Add(q.TimeoutQueue, "b", nil, nil)
+	q.Advance(time.Second)
+	assert.Equal(t, []error{r.err}, errs)
+}
@@ -0,0 +1,59 @@
+package timeoutqueue
+
+import (
+	"sync/atomic"
+)
+
+// RateLimiter allows at most burst events in any period of the queue's
+// timeout. Each event that is allowed takes a token from the bucket and the
+// queue returns it once the timeout has passed. Because the refills are
+// driven by the queue, any number of RateLimiters can share one queue and one
+// runner. The refills are trivial, so a queue dedicated to RateLimiters should
+// use the Inline Executor.
+type RateLimiter struct {
+	available int64
+	burst     int64
+	tq        *TimeoutQueue
+	refill    TimeoutAction
+}
+
+// NewRateLimiter returns a RateLimiter on the queue with a full bucket.
+func (tq *TimeoutQueue) NewRateLimiter(burst int) *RateLimiter {
+	r := &RateLimiter{
+		available: int64(burst),
+		burst:     int64(burst),
+		tq:        tq,
+	}
+	// stored once so Allow does not allocate a method value
+	r.refill = r.release
+	return r
+}
+
+func (r *RateLimiter) release() {
+	atomic.AddInt64(&r.available, 1)
+}
+
+// Allow takes a token from the bucket if one is available. The returned bool
+// indicates if the event is allowed.
+func (r *RateLimiter) Allow() bool {
+	for {
+		a := atomic.LoadInt64(&r.available)
+		if a <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&r.available, a, a-1) {
+			r.tq.AddHandle(r.refill)
+			return true
+		}
+	}
+}
+
+// Available returns the number of tokens in the bucket.
+func (r *RateLimiter) Available() int {
+	return int(atomic.LoadInt64(&r.available))
+}
+
+// Burst returns the size of the bucket.
+func (r *RateLimiter) Burst() int {
+	return int(r.burst)
+}
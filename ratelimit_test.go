@@ -0,0 +1,32 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	a := q.NewRateLimiter(2)
+	b := q.NewRateLimiter(1)
+	assert.Equal(t, 2, a.Burst())
+
+	assert.True(t, a.Allow())
+	q.Advance(time.Millisecond * 500)
+	assert.True(t, a.Allow())
+	assert.False(t, a.Allow())
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+	assert.Equal(t, 0, a.Available())
+
+	q.Advance(time.Millisecond * 500)
+	assert.Equal(t, 1, a.Available())
+	assert.Equal(t, 0, b.Available())
+	q.Advance(time.Millisecond * 500)
+	assert.Equal(t, 2, a.Available())
+	assert.Equal(t, 1, b.Available())
+	assert.True(t, b.Allow())
+}
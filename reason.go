@@ -0,0 +1,137 @@
+package timeoutqueue
+
+// Reason is why an action was called.
+type Reason uint8
+
+// The reasons an action can be called.
+const (
+	// ReasonTimeout is a genuine timeout, including actions added to a queue
+	// with a timeout of zero or less.
+	ReasonTimeout Reason = iota
+	// ReasonFlush is from Flush or FlushWhere.
+	ReasonFlush
+	// ReasonDrain is from Drain.
+	ReasonDrain
+	// ReasonClose is from Close or adding to a queue that is closed.
+	ReasonClose
+	// ReasonEvicted is from adding to a full queue from NewFixed, see
+	// EvictWhenFull.
+	ReasonEvicted
+)
+
+var reasonNames = [...]string{
+	ReasonTimeout: "Timeout",
+	ReasonFlush:   "Flush",
+	ReasonDrain:   "Drain",
+	ReasonClose:   "Close",
+	ReasonEvicted: "Evicted",
+}
+
+func (r Reason) String() string {
+	if int(r) < len(reasonNames) {
+		return reasonNames[r]
+	}
+	return "Invalid"
+}
+
+// ReasonAction is called like a TimeoutAction but receives the Reason it was
+// called, so it can tell a real timeout from the queue being flushed, drained or
+// closed.
+type ReasonAction func(reason Reason)
+
+// AddWithReason adds a ReasonAction to the queue.
+func (tq *TimeoutQueue) AddWithReason(action ReasonAction) Token {
+	tq.mux.Lock()
+	return tq.addAction(action, tq.hookID())
+}
+
+// immediateReason requires a mux lock. It is the Reason for an action that is
+// dispatched as soon as it is added.
+func (tq *TimeoutQueue) immediateReason() Reason {
+	if tq.closed {
+		return ReasonClose
+	}
+	return ReasonTimeout
+}
+
+// Drain dispatches everything currently in the queue through the Executor
+// without waiting for it to timeout and returns the number dispatched. Unlike
+// Flush, Drain does not wait for the actions to complete and they may call
+// methods on the queue. Anything added while Drain is running is left in the
+// queue. To know that the actions and the runner have returned, follow Drain
+// with Close.
+func (tq *TimeoutQueue) Drain() int {
+	tq.mux.Lock()
+	count := tq.pending
+	now := tq.clockNow()
+	tq.mux.Unlock()
+	for i := 0; i < count; i++ {
+		tq.mux.Lock()
+		if tq.head == empty {
+			tq.mux.Unlock()
+			return i
+		}
+		idx := tq.head
+		n, d := tq.nodeAt(idx), tq.dispatcher
+		tq.emit(EventFired, idx)
+		tq.freeNode(idx)
+		tq.nodes[idx].inflight++
+		tq.mux.Unlock()
+		d.dispatchFired(tq, idx, n, now, ReasonDrain)
+	}
+	return count
+}
+
+// Close calls everything in the queue the same as Flush, but with ReasonClose.
+// Once closed, any action added to the queue is dispatched immediately with
+// ReasonClose. Close then wakes any runner that is still asleep and waits for
+// every Go routine the queue started to return, including actions that already
+// fired, so when it returns Goroutines is zero and nothing is left behind.
+// Because of that, Close must not be called from a TimeoutAction. Calling Close
+// on a closed queue only does the wait.
+func (tq *TimeoutQueue) Close() {
+	tq.mux.Lock()
+	if !tq.closed {
+		tq.closed = true
+		tq.flush(ReasonClose)
+		// retire the runner so it returns once woken
+		tq.running = 0
+		if tq.afterFunc {
+			tq.arm()
+		}
+	}
+	tq.waitGoroutines()
+	tq.mux.Unlock()
+}
+
+// Reopen a closed queue so actions added to it are scheduled again. The queue
+// keeps the nodes it allocated before it was closed, so a component that cycles
+// through connecting and disconnecting does not pay to grow a new queue each
+// time. Tokens from before the queue was closed remain invalid. The returned
+// bool is false if the queue was not closed.
+func (tq *TimeoutQueue) Reopen() bool {
+	tq.mux.Lock()
+	reopened := tq.closed
+	tq.closed = false
+	tq.mux.Unlock()
+	return reopened
+}
+
+// Closed returns true if Close has been called and the queue has not been
+// reopened.
+func (tq *TimeoutQueue) Closed() bool {
+	tq.mux.Lock()
+	closed := tq.closed
+	tq.mux.Unlock()
+	return closed
+}
+
+// schedules returns true if an action added now with the queue's timeout would
+// wait in the queue rather than being dispatched from within Add. Helpers that
+// add again from an action check it so they do not recurse forever.
+func (tq *TimeoutQueue) schedules() bool {
+	tq.mux.Lock()
+	ok := tq.timeout > 0 && !tq.closed
+	tq.mux.Unlock()
+	return ok
+}
@@ -0,0 +1,58 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReason(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
+	tq.SetExecutor(timeoutqueue.Inline)
+
+	var mux sync.Mutex
+	var reasons []timeoutqueue.Reason
+	record := func(r timeoutqueue.Reason) {
+		mux.Lock()
+		reasons = append(reasons, r)
+		mux.Unlock()
+	}
+	get := func() []timeoutqueue.Reason {
+		mux.Lock()
+		defer mux.Unlock()
+		return append([]timeoutqueue.Reason(nil), reasons...)
+	}
+
+	tq.AddWithReason(record)
+	time.Sleep(time.Millisecond * 20)
+	assert.Equal(t, []timeoutqueue.Reason{timeoutqueue.ReasonTimeout}, get())
+
+	tq.AddWithReason(record)
+	tq.Flush()
+	tq.AddWithReason(record)
+	tq.AddWithReason(record)
+	assert.Equal(t, 2, tq.Drain())
+	tq.AddWithReason(record)
+	assert.False(t, tq.Closed())
+	tq.Close()
+	assert.True(t, tq.Closed())
+	tq.Close()
+	tq.AddWithReason(record)
+
+	assert.Equal(t, []timeoutqueue.Reason{
+		timeoutqueue.ReasonTimeout,
+		timeoutqueue.ReasonFlush,
+		timeoutqueue.ReasonDrain,
+		timeoutqueue.ReasonDrain,
+		timeoutqueue.ReasonClose,
+		timeoutqueue.ReasonClose,
+	}, get())
+	assert.Equal(t, 0, tq.Len())
+	assert.Equal(t, "Drain", timeoutqueue.ReasonDrain.String())
+	assert.Equal(t, "Invalid", timeoutqueue.Reason(100).String())
+}
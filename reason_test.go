@@ -0,0 +1,39 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReopen(t *testing.T) {
+	tq := timeoutqueue.NewManual(time.Second, 0, nil)
+	tq.SetExecutor(timeoutqueue.Inline)
+	assert.False(t, tq.Reopen())
+
+	var reasons []timeoutqueue.Reason
+	record := func(r timeoutqueue.Reason) {
+		reasons = append(reasons, r)
+	}
+	old := tq.AddWithReason(record)
+	tq.AddWithReason(record)
+	tq.Close()
+	assert.True(t, tq.Reopen())
+	assert.False(t, tq.Closed())
+
+	// the nodes from before Close are reused
+	grew := tq.Stats().Grew
+	tkn := tq.AddWithReason(record)
+	assert.False(t, old.Cancel())
+	assert.Equal(t, 1, tq.Len())
+	assert.Equal(t, grew, tq.Stats().Grew)
+	assert.True(t, tkn.Reset())
+	tq.Flush()
+	assert.Equal(t, []timeoutqueue.Reason{
+		timeoutqueue.ReasonClose,
+		timeoutqueue.ReasonClose,
+		timeoutqueue.ReasonFlush,
+	}, reasons)
+}
@@ -0,0 +1,36 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReentrantRunner(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond, 10)
+	beats := make(chan int, 10)
+	n := 0
+	var beat timeoutqueue.TimeoutAction
+	beat = func() {
+		n++
+		beats <- n
+		if n < 5 {
+			tq.Add(beat)
+		}
+	}
+	tq.Add(beat)
+	for i := 1; i <= 5; i++ {
+		select {
+		case got := <-beats:
+			assert.Equal(t, i, got)
+		case <-time.After(time.Second):
+			t.Fatal("heartbeat stopped")
+		}
+	}
+	tq.Close()
+	assert.NoError(t, tq.Validate())
+}
@@ -0,0 +1,87 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReentrantTick(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 2)
+	beats := 0
+	var beat timeoutqueue.TimeoutAction
+	var self timeoutqueue.Token
+	beat = func() {
+		beats++
+		// the fired token is spent, resetting it is a safe no-op
+		assert.False(t, self.Reset())
+		self = q.Add(beat)
+	}
+	self = q.Add(beat)
+	var other timeoutqueue.Token
+	q.Add(func() { assert.True(t, other.Cancel()) })
+	other = q.Add(func() { t.Error("canceled action fired") })
+
+	for i := 1; i <= 5; i++ {
+		q.Advance(time.Second)
+		assert.Equal(t, i, beats)
+		assert.NoError(t, q.Validate())
+	}
+	assert.Equal(t, 1, q.Len())
+}
+
+func TestReentrantFlush(t *testing.T) {
+	tq := timeoutqueue.New(time.Hour, 10)
+	var fired []int
+	stop := false
+	var again timeoutqueue.TimeoutAction
+	again = func() {
+		fired = append(fired, 1)
+		if !stop {
+			tq.Add(again)
+		}
+	}
+	tq.Add(again)
+	b := tq.Add(func() { fired = append(fired, 2) })
+	tq.Add(func() {
+		fired = append(fired, 3)
+		b.Cancel()
+	})
+	tq.Flush()
+	assert.Equal(t, []int{1, 2, 3}, fired)
+	// the action added by the flush is left scheduled
+	assert.Equal(t, 1, tq.Len())
+	assert.NoError(t, tq.Validate())
+
+	fired = nil
+	c := tq.Add(func() { fired = append(fired, 4) })
+	tq.Add(func() { fired = append(fired, 5) })
+	tq.FlushWhere(func(timeoutqueue.Token) bool { return true })
+	assert.Equal(t, []int{1, 4, 5}, fired)
+	assert.False(t, c.Cancel())
+
+	// an action canceled by an earlier one is skipped
+	fired = nil
+	var d timeoutqueue.Token
+	tq.Add(func() { d.Cancel() })
+	d = tq.Add(func() { fired = append(fired, 6) })
+	tq.FlushWhere(func(timeoutqueue.Token) bool { return true })
+	assert.Equal(t, []int{1}, fired)
+	assert.NoError(t, tq.Validate())
+
+	// once closed an action added by an action is dispatched with ReasonClose
+	stop = true
+	var reasons []timeoutqueue.Reason
+	tq.AddWithReason(func(r timeoutqueue.Reason) {
+		reasons = append(reasons, r)
+		tq.SetExecutor(timeoutqueue.Inline)
+		tq.AddWithReason(func(r timeoutqueue.Reason) { reasons = append(reasons, r) })
+	})
+	tq.Close()
+	assert.Equal(t, []timeoutqueue.Reason{timeoutqueue.ReasonClose, timeoutqueue.ReasonClose}, reasons)
+	assert.Equal(t, 0, tq.Len())
+	assert.NoError(t, tq.Validate())
+}
@@ -0,0 +1,81 @@
+package timeoutqueue
+
+import (
+	"sync"
+)
+
+// BucketRefresher keeps one refresh timer per bucket of a DHT routing table
+// multiplexed over a single queue. Activity in a bucket resets it's timer and a
+// bucket that goes stale for the queue's timeout is passed to the refresh
+// callback, after which it's timer starts again.
+type BucketRefresher struct {
+	mux       sync.Mutex
+	tq        *TimeoutQueue
+	onRefresh func(bucket int)
+	handles   []Handle
+	stopped   bool
+	refresh   CorrelatedAction
+}
+
+// NewBucketRefresher starts timers for buckets number of buckets. The
+// onRefresh callback is called with the index of each bucket that goes stale.
+func (tq *TimeoutQueue) NewBucketRefresher(buckets int, onRefresh func(bucket int)) *BucketRefresher {
+	br := &BucketRefresher{
+		tq:        tq,
+		onRefresh: onRefresh,
+		handles:   make([]Handle, buckets),
+	}
+	br.refresh = br.stale
+	for i := range br.handles {
+		br.arm(i)
+	}
+	return br
+}
+
+// arm adds the timer for a bucket. It must be called without the
+// BucketRefresher's mux lock as the timer may fire from within Add.
+func (br *BucketRefresher) arm(bucket int) {
+	br.tq.mux.Lock()
+	h := br.tq.addAction(br.refresh, bucket)
+	br.mux.Lock()
+	if br.stopped {
+		h.Cancel()
+	} else {
+		br.handles[bucket] = h
+	}
+	br.mux.Unlock()
+}
+
+func (br *BucketRefresher) stale(id interface{}) {
+	bucket := id.(int)
+	br.mux.Lock()
+	stopped := br.stopped
+	br.mux.Unlock()
+	if stopped {
+		return
+	}
+	if br.tq.schedules() {
+		br.arm(bucket)
+	}
+	br.onRefresh(bucket)
+}
+
+// Touch records activity in the bucket, resetting it's timer. An index out of
+// range is ignored.
+func (br *BucketRefresher) Touch(bucket int) {
+	br.mux.Lock()
+	if !br.stopped && bucket >= 0 && bucket < len(br.handles) {
+		br.handles[bucket].Reset()
+	}
+	br.mux.Unlock()
+}
+
+// Stop all the timers.
+func (br *BucketRefresher) Stop() {
+	br.mux.Lock()
+	br.stopped = true
+	for _, h := range br.handles {
+		h.Cancel()
+	}
+	br.mux.Unlock()
+}
@@ -0,0 +1,53 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketRefresher(t *testing.T) {
+	q := timeoutqueuetest.New(time.Minute, 160)
+	var refreshed []int
+	br := q.NewBucketRefresher(160, func(bucket int) {
+		refreshed = append(refreshed, bucket)
+	})
+	q.AssertPending(t, 160)
+
+	q.Advance(time.Second * 30)
+	for i := 1; i < 160; i++ {
+		br.Touch(i)
+	}
+	br.Touch(-1)
+	br.Touch(160)
+	q.Advance(time.Second * 31)
+	assert.Equal(t, []int{0}, refreshed)
+	q.AssertPending(t, 160)
+
+	q.Advance(time.Second * 30)
+	assert.Len(t, refreshed, 160)
+	q.Advance(time.Second * 30)
+	assert.Len(t, refreshed, 161)
+
+	br.Stop()
+	q.AssertPending(t, 0)
+	q.Advance(time.Hour)
+	assert.Len(t, refreshed, 161)
+}
+
+func TestBucketRefresherClosed(t *testing.T) {
+	// a closed queue refreshes every bucket once from within Add
+	tq := timeoutqueue.New(time.Minute, 2)
+	tq.SetExecutor(timeoutqueue.Inline)
+	tq.Close()
+	var refreshed []int
+	br := tq.NewBucketRefresher(2, func(bucket int) {
+		refreshed = append(refreshed, bucket)
+	})
+	assert.Equal(t, []int{0, 1}, refreshed)
+	br.Touch(0)
+	br.Stop()
+}
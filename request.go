@@ -0,0 +1,150 @@
+package timeoutqueue
+
+import (
+	"sync"
+)
+
+// RequestTracker tracks outstanding requests keyed by request ID until they are
+// acknowledged. A request that is not acknowledged within the queue's timeout
+// has it's timeout callback called with the ID.
+type RequestTracker struct {
+	mux      sync.Mutex
+	tq       *TimeoutQueue
+	requests map[interface{}]*request
+	expire   CorrelatedAction
+	stats    RequestStats
+}
+
+type request struct {
+	handle    Handle
+	onTimeout func(id interface{})
+	// adding is set while Register adds the request to the queue and fired if
+	// it timed out in the meantime
+	adding, fired bool
+}
+
+// RequestStats counts the requests handled by a RequestTracker.
+type RequestStats struct {
+	Registered uint64
+	Acked      uint64
+	TimedOut   uint64
+}
+
+// TimeoutRate returns the fraction of completed requests that timed out rather
+// than being acknowledged. It is zero if no requests have completed.
+func (rs RequestStats) TimeoutRate() float64 {
+	done := rs.Acked + rs.TimedOut
+	if done == 0 {
+		return 0
+	}
+	return float64(rs.TimedOut) / float64(done)
+}
+
+// NewRequestTracker returns a RequestTracker that allows each request the
+// queue's timeout.
+func (tq *TimeoutQueue) NewRequestTracker() *RequestTracker {
+	rt := &RequestTracker{
+		tq:       tq,
+		requests: make(map[interface{}]*request),
+	}
+	rt.expire = rt.expired
+	return rt
+}
+
+func (rt *RequestTracker) expired(id interface{}) {
+	rt.mux.Lock()
+	r, ok := rt.requests[id]
+	// the request may have been acked and registered again between the queue
+	// firing and now
+	if !ok || r.handle.live() {
+		rt.mux.Unlock()
+		return
+	}
+	if r.adding {
+		r.fired = true
+		rt.mux.Unlock()
+		return
+	}
+	rt.timedOut(id, r)
+}
+
+// timedOut requires the lock and will unlock it.
+func (rt *RequestTracker) timedOut(id interface{}, r *request) {
+	delete(rt.requests, id)
+	rt.stats.TimedOut++
+	rt.mux.Unlock()
+	if r.onTimeout != nil {
+		r.onTimeout(id)
+	}
+}
+
+// Register an outstanding request. If it is not acknowledged in time, onTimeout
+// is called with the id. The returned bool is false and nothing is registered
+// if a request with the id is already outstanding.
+func (rt *RequestTracker) Register(id interface{}, onTimeout func(id interface{})) bool {
+	rt.mux.Lock()
+	if _, ok := rt.requests[id]; ok {
+		rt.mux.Unlock()
+		return false
+	}
+	r := &request{
+		onTimeout: onTimeout,
+		adding:    true,
+	}
+	rt.requests[id] = r
+	rt.stats.Registered++
+	rt.mux.Unlock()
+
+	// the request may time out from within addAction, so it is added without
+	// the lock
+	rt.tq.mux.Lock()
+	h := rt.tq.addAction(rt.expire, id)
+	rt.mux.Lock()
+	if rt.requests[id] != r {
+		// acked before it's handle was stored
+		h.Cancel()
+		rt.mux.Unlock()
+		return true
+	}
+	r.handle = h
+	r.adding = false
+	// fired may have been set by an earlier registration of the id, only trust
+	// it if this request's handle is spent
+	if r.fired && !h.live() {
+		rt.timedOut(id, r)
+		return true
+	}
+	rt.mux.Unlock()
+	return true
+}
+
+// Ack acknowledges the request with id, canceling it's timeout. The returned
+// bool is false if no request with the id was outstanding.
+func (rt *RequestTracker) Ack(id interface{}) bool {
+	rt.mux.Lock()
+	defer rt.mux.Unlock()
+	r, ok := rt.requests[id]
+	if !ok {
+		return false
+	}
+	delete(rt.requests, id)
+	r.handle.Cancel()
+	rt.stats.Acked++
+	return true
+}
+
+// Outstanding returns the number of requests waiting to be acknowledged.
+func (rt *RequestTracker) Outstanding() int {
+	rt.mux.Lock()
+	n := len(rt.requests)
+	rt.mux.Unlock()
+	return n
+}
+
+// Stats returns the counts of requests registered, acknowledged and timed out.
+func (rt *RequestTracker) Stats() RequestStats {
+	rt.mux.Lock()
+	s := rt.stats
+	rt.mux.Unlock()
+	return s
+}
@@ -0,0 +1,56 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestTracker(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	rt := q.NewRequestTracker()
+
+	var timedOut []interface{}
+	onTimeout := func(id interface{}) {
+		timedOut = append(timedOut, id)
+	}
+	assert.True(t, rt.Register(1, onTimeout))
+	assert.True(t, rt.Register(2, onTimeout))
+	assert.True(t, rt.Register(3, nil))
+	assert.False(t, rt.Register(1, onTimeout))
+	assert.Equal(t, 3, rt.Outstanding())
+
+	assert.True(t, rt.Ack(2))
+	assert.False(t, rt.Ack(2))
+	q.Advance(time.Second)
+	assert.Equal(t, []interface{}{1}, timedOut)
+	assert.Equal(t, 0, rt.Outstanding())
+	assert.False(t, rt.Ack(1))
+
+	s := rt.Stats()
+	assert.Equal(t, timeoutqueue.RequestStats{
+		Registered: 3,
+		Acked:      1,
+		TimedOut:   2,
+	}, s)
+	assert.InDelta(t, 2.0/3.0, s.TimeoutRate(), 1e-9)
+	assert.Equal(t, 0.0, timeoutqueue.RequestStats{}.TimeoutRate())
+}
+
+func TestRequestTrackerZeroTimeout(t *testing.T) {
+	tq := timeoutqueue.New(0, 10)
+	tq.SetExecutor(timeoutqueue.Inline)
+	rt := tq.NewRequestTracker()
+	var timedOut []interface{}
+	// with no timeout the request times out from within Register
+	assert.True(t, rt.Register(1, func(id interface{}) {
+		timedOut = append(timedOut, id)
+		assert.True(t, rt.Register(2, nil))
+	}))
+	assert.Equal(t, []interface{}{1}, timedOut)
+	assert.Equal(t, 0, rt.Outstanding())
+	assert.Equal(t, uint64(2), rt.Stats().TimedOut)
+}
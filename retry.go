@@ -0,0 +1,257 @@
+package timeoutqueue
+
+import (
+	"math/bits"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Backoff decides how long to wait before each attempt of a retried job.
+// Attempts are counted from zero, so Delay(0) is the wait before the first
+// attempt.
+type Backoff interface {
+	Delay(attempt int) time.Duration
+}
+
+// ConstantBackoff waits the same duration before every attempt.
+type ConstantBackoff time.Duration
+
+// Delay fulfills Backoff.
+func (c ConstantBackoff) Delay(int) time.Duration {
+	return time.Duration(c)
+}
+
+// ExponentialBackoff waits Base before the first attempt and multiplies the
+// wait by Factor for every attempt after, up to Max. A Factor less than or equal
+// to 1 is treated as 2 and a Max of zero means there is no maximum.
+type ExponentialBackoff struct {
+	Base   time.Duration
+	Max    time.Duration
+	Factor float64
+}
+
+// Delay fulfills Backoff.
+func (e ExponentialBackoff) Delay(attempt int) time.Duration {
+	f := e.Factor
+	if f <= 1 {
+		f = 2
+	}
+	d := float64(e.Base)
+	for i := 0; i < attempt; i++ {
+		d *= f
+		if e.Max > 0 && d >= float64(e.Max) {
+			return e.Max
+		}
+	}
+	return time.Duration(d)
+}
+
+// JitterBackoff randomizes the delay of another Backoff by up to Fraction of
+// it in either direction, so a Fraction of 0.1 gives delays between 90% and
+// 110% of the underlying delay.
+type JitterBackoff struct {
+	Backoff  Backoff
+	Fraction float64
+}
+
+// Delay fulfills Backoff.
+func (j JitterBackoff) Delay(attempt int) time.Duration {
+	d := float64(j.Backoff.Delay(attempt))
+	d += d * j.Fraction * (2*rand.Float64() - 1)
+	if d < 0 {
+		return 0
+	}
+	return time.Duration(d)
+}
+
+// Job is a unit of work run by a RetryScheduler. Returning nil reports success,
+// any error causes it to be retried.
+type Job func() error
+
+// GiveUpAction is called when a Job has failed on every attempt. It receives
+// the error from the last attempt.
+type GiveUpAction func(err error, attempts int)
+
+// RetryScheduler runs Jobs, retrying them according to a Backoff until they
+// succeed or reach the maximum attempts. As a TimeoutQueue has a fixed timeout,
+// delays that are within a factor of two of each other share a queue, so there
+// are never more than a few queues however varied the delays are.
+type RetryScheduler struct {
+	backoff     Backoff
+	maxAttempts int
+	onGiveUp    GiveUpAction
+	queues      delayQueues
+}
+
+// NewRetryScheduler returns a RetryScheduler. A maxAttempts of zero or less
+// retries forever. If onGiveUp is not nil it is called when a Job runs out of
+// attempts.
+func NewRetryScheduler(backoff Backoff, maxAttempts int, onGiveUp GiveUpAction) *RetryScheduler {
+	return &RetryScheduler{
+		backoff:     backoff,
+		maxAttempts: maxAttempts,
+		onGiveUp:    onGiveUp,
+		queues:      newDelayQueues(),
+	}
+}
+
+// SetResolution sets the granularity delays are rounded up to. The default is
+// one millisecond.
+func (rs *RetryScheduler) SetResolution(resolution time.Duration) {
+	rs.queues.setResolution(resolution)
+}
+
+// Retry tracks a Job scheduled on a RetryScheduler.
+type Retry struct {
+	mux      sync.Mutex
+	rs       *RetryScheduler
+	job      Job
+	attempt  int
+	handle   Handle
+	canceled bool
+	// done is set once the Job has succeeded or given up
+	done bool
+}
+
+// Schedule runs job after the Backoff's first delay, retrying it until it
+// succeeds.
+func (rs *RetryScheduler) Schedule(job Job) *Retry {
+	r := &Retry{
+		rs:  rs,
+		job: job,
+	}
+	r.schedule()
+	return r
+}
+
+// schedule adds the next attempt. It must be called without the Retry's mux
+// lock.
+func (r *Retry) schedule() {
+	r.mux.Lock()
+	d := r.rs.backoff.Delay(r.attempt)
+	r.mux.Unlock()
+	h := r.rs.queues.add(d, r.run)
+	r.mux.Lock()
+	if r.canceled {
+		h.Cancel()
+	} else {
+		r.handle = h
+	}
+	r.mux.Unlock()
+}
+
+// delayQueues schedules actions after varying delays, rounded up to the
+// resolution. Delays are grouped by their highest bit and each group shares a
+// queue that keeps it's actions in deadline order, so there are at most 65
+// queues and the runner of each exits while it is empty.
+type delayQueues struct {
+	mux        sync.Mutex
+	resolution time.Duration
+	queues     [65]*TimeoutQueue
+}
+
+func newDelayQueues() delayQueues {
+	return delayQueues{
+		resolution: time.Millisecond,
+	}
+}
+
+func (dq *delayQueues) setResolution(resolution time.Duration) {
+	dq.mux.Lock()
+	dq.resolution = resolution
+	dq.mux.Unlock()
+}
+
+// add schedules action to be called after d. The Handle can be canceled but not
+// reset, as the queue's timeout is not d.
+func (dq *delayQueues) add(d time.Duration, action TimeoutAction) Handle {
+	if d < 0 {
+		d = 0
+	}
+	dq.mux.Lock()
+	if dq.resolution > 0 {
+		if rem := d % dq.resolution; rem != 0 {
+			d += dq.resolution - rem
+		}
+	}
+	i := bits.Len64(uint64(d))
+	tq := dq.queues[i]
+	if tq == nil {
+		// the first queue has no timeout and dispatches immediately
+		var timeout time.Duration
+		if i > 0 {
+			timeout = 1 << uint(i-1)
+		}
+		tq = New(timeout, 0)
+		dq.queues[i] = tq
+	}
+	dq.mux.Unlock()
+
+	tq.mux.Lock()
+	if d == 0 {
+		return tq.addAction(action, nil)
+	}
+	h := tq.insert(action, nil, tq.deadlineAfter(d))
+	tq.rearm()
+	tq.mux.Unlock()
+	return h
+}
+
+func (r *Retry) run() {
+	r.mux.Lock()
+	if r.canceled {
+		r.mux.Unlock()
+		return
+	}
+	r.handle = Handle{}
+	r.mux.Unlock()
+
+	err := r.job()
+
+	r.mux.Lock()
+	if r.canceled {
+		r.mux.Unlock()
+		return
+	}
+	if err == nil {
+		r.done = true
+		r.mux.Unlock()
+		return
+	}
+	r.attempt++
+	attempt := r.attempt
+	if max := r.rs.maxAttempts; max > 0 && attempt >= max {
+		r.done = true
+		r.mux.Unlock()
+		if r.rs.onGiveUp != nil {
+			r.rs.onGiveUp(err, attempt)
+		}
+		return
+	}
+	r.mux.Unlock()
+	r.schedule()
+}
+
+// Cancel prevents any further attempts. The returned bool is false if the Job
+// had already succeeded, given up or been canceled. An attempt that is running
+// when Cancel is called will complete but will not be retried, and Cancel still
+// returns true.
+func (r *Retry) Cancel() bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.canceled || r.done {
+		return false
+	}
+	r.canceled = true
+	r.handle.Cancel()
+	return true
+}
+
+// Attempts returns the number of attempts that have failed.
+func (r *Retry) Attempts() int {
+	r.mux.Lock()
+	a := r.attempt
+	r.mux.Unlock()
+	return a
+}
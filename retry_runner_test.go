@@ -0,0 +1,90 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeout"
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryCallbacks(t *testing.T) {
+	errFailed := errors.New("failed")
+	gaveUp := make(chan int, 1)
+	var r *timeoutqueue.Retry
+	ready := make(chan bool)
+	rs := timeoutqueue.NewRetryScheduler(timeoutqueue.ConstantBackoff(0), 2, func(err error, attempts int) {
+		// the Retry is not locked while onGiveUp is called
+		<-ready
+		gaveUp <- r.Attempts()
+	})
+	r = rs.Schedule(func() error { return errFailed })
+	close(ready)
+	assert.NoError(t, timeout.After(50, func() {
+		assert.Equal(t, 2, <-gaveUp)
+	}))
+	assert.False(t, r.Cancel())
+
+	// canceling a running attempt stops it being retried
+	running := make(chan bool)
+	release := make(chan bool)
+	calls := 0
+	r = rs.Schedule(func() error {
+		calls++
+		running <- true
+		<-release
+		return errFailed
+	})
+	<-running
+	assert.True(t, r.Cancel())
+	close(release)
+	time.Sleep(time.Millisecond * 10)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 0, r.Attempts())
+	assert.False(t, r.Cancel())
+}
+
+func TestRetryScheduler(t *testing.T) {
+	errFailed := errors.New("failed")
+	gaveUp := make(chan int, 1)
+	rs := timeoutqueue.NewRetryScheduler(timeoutqueue.ExponentialBackoff{
+		Base: time.Millisecond,
+	}, 3, func(err error, attempts int) {
+		assert.Equal(t, errFailed, err)
+		gaveUp <- attempts
+	})
+
+	calls := make(chan int, 10)
+	var n int
+	r := rs.Schedule(func() error {
+		n++
+		calls <- n
+		if n < 2 {
+			return errFailed
+		}
+		return nil
+	})
+	assert.NoError(t, timeout.After(30, func() {
+		assert.Equal(t, 1, <-calls)
+		assert.Equal(t, 2, <-calls)
+	}))
+	assert.Equal(t, 1, r.Attempts())
+
+	rs.Schedule(func() error {
+		return errFailed
+	})
+	assert.NoError(t, timeout.After(50, func() {
+		assert.Equal(t, 3, <-gaveUp)
+	}))
+
+	r = rs.Schedule(func() error {
+		t.Error("should be canceled")
+		return nil
+	})
+	assert.True(t, r.Cancel())
+	assert.False(t, r.Cancel())
+}
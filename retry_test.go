@@ -0,0 +1,50 @@
+package timeoutqueue_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	c := timeoutqueue.ConstantBackoff(time.Second)
+	assert.Equal(t, time.Second, c.Delay(0))
+	assert.Equal(t, time.Second, c.Delay(5))
+
+	e := timeoutqueue.ExponentialBackoff{
+		Base: time.Millisecond,
+		Max:  time.Millisecond * 6,
+	}
+	assert.Equal(t, time.Millisecond, e.Delay(0))
+	assert.Equal(t, time.Millisecond*4, e.Delay(2))
+	assert.Equal(t, time.Millisecond*6, e.Delay(3))
+
+	j := timeoutqueue.JitterBackoff{
+		Backoff:  c,
+		Fraction: 0.1,
+	}
+	for i := 0; i < 10; i++ {
+		d := j.Delay(i)
+		assert.True(t, d >= time.Millisecond*900 && d <= time.Millisecond*1100)
+	}
+}
+
+func TestRetrySchedulerJitter(t *testing.T) {
+	// jittered delays share a few queues rather than one per delay
+	rs := timeoutqueue.NewRetryScheduler(timeoutqueue.JitterBackoff{
+		Backoff:  timeoutqueue.ConstantBackoff(time.Second),
+		Fraction: 0.2,
+	}, 0, nil)
+	before := runtime.NumGoroutine()
+	retries := make([]*timeoutqueue.Retry, 1000)
+	for i := range retries {
+		retries[i] = rs.Schedule(func() error { return nil })
+	}
+	assert.True(t, runtime.NumGoroutine()-before < 10)
+	for _, r := range retries {
+		assert.True(t, r.Cancel())
+	}
+}
@@ -0,0 +1,73 @@
+package timeoutqueue
+
+import (
+	"time"
+)
+
+// RunnerState describes the queue's runner, the Go routine that sleeps until
+// the next deadline and fires the TimeoutActions that are due.
+type RunnerState struct {
+	// Running is true if a runner is active.
+	Running bool
+	// SleepUntil is when the runner will next wake. It is zero if there is no
+	// runner or the runner is awake.
+	SleepUntil time.Time
+	// Generation is 1 while a runner is active. Something added with a
+	// deadline before SleepUntil wakes the runner rather than starting another,
+	// so it no longer changes. It is zero if there is no runner.
+	Generation uint16
+}
+
+// IsRunning returns true if the queue has an active runner.
+func (tq *TimeoutQueue) IsRunning() bool {
+	tq.mux.Lock()
+	running := tq.running != 0
+	tq.mux.Unlock()
+	return running
+}
+
+// RunnerState returns the state of the queue's runner.
+func (tq *TimeoutQueue) RunnerState() RunnerState {
+	tq.mux.Lock()
+	rs := RunnerState{
+		Running:    tq.running != 0,
+		SleepUntil: tq.sleepUntil,
+		Generation: tq.running,
+	}
+	tq.mux.Unlock()
+	return rs
+}
+
+// SetLinger keeps the runner alive for up to linger after the queue empties, so
+// a queue that is often briefly empty does not keep starting a new runner Go
+// routine. While lingering the runner wakes at least once every timeout, so
+// anything added with the queue's timeout is picked up without a new runner,
+// and once every SetCoarseClock precision to keep the cached time fresh. The
+// default of zero exits as soon as the queue is empty.
+func (tq *TimeoutQueue) SetLinger(linger time.Duration) {
+	tq.mux.Lock()
+	tq.linger = linger
+	tq.mux.Unlock()
+}
+
+// lingerFor requires a mux lock. It is called by the runner when there is
+// nothing to do and returns how long to sleep before checking again, or zero
+// if the runner should exit. lingerUntil is set when the runner starts to
+// linger.
+func (tq *TimeoutQueue) lingerFor(now time.Time, lingerUntil *time.Time) time.Duration {
+	if tq.suspended || tq.linger <= 0 {
+		return 0
+	}
+	if lingerUntil.IsZero() {
+		*lingerUntil = now.Add(tq.linger)
+	}
+	d := lingerUntil.Sub(now)
+	// anything added while sleeping has a deadline no earlier than the wake
+	// up, so there is no need to wake this runner
+	if tq.timeout > 0 && d > tq.timeout {
+		d = tq.timeout
+	}
+	// the coarse clock is only read from the cache while the runner is alive,
+	// so a lingering runner still has to refresh it
+	return tq.sleepFor(d)
+}
@@ -0,0 +1,133 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnerState(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*50, 10)
+	assert.False(t, tq.IsRunning())
+	assert.Equal(t, timeoutqueue.RunnerState{}, tq.RunnerState())
+
+	ch := make(chan bool, 2)
+	start := time.Now()
+	tq.Add(func() { ch <- true })
+	assert.True(t, tq.IsRunning())
+
+	// wait for the runner to go to sleep
+	var rs timeoutqueue.RunnerState
+	for i := 0; i < 100 && rs.SleepUntil.IsZero(); i++ {
+		time.Sleep(time.Millisecond)
+		rs = tq.RunnerState()
+	}
+	assert.True(t, rs.Running)
+	assert.Equal(t, uint16(1), rs.Generation)
+	assert.True(t, rs.SleepUntil.After(start))
+
+	// an earlier deadline wakes the sleeping runner
+	tq.SetTimeoutForNew(time.Millisecond * 10)
+	tq.Add(func() { ch <- true })
+	assert.Equal(t, uint16(1), tq.RunnerState().Generation)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Error("timed out")
+		}
+	}
+	for i := 0; i < 100 && tq.IsRunning(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.False(t, tq.IsRunning())
+}
+
+func TestLinger(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
+	tq.SetLinger(time.Millisecond * 50)
+	ch := make(chan bool, 1)
+	action := func() { ch <- true }
+
+	tq.Add(action)
+	<-ch
+	time.Sleep(time.Millisecond * 10)
+	assert.True(t, tq.IsRunning())
+
+	// the lingering runner serves the next Add
+	tq.Add(action)
+	assert.Equal(t, uint16(1), tq.RunnerState().Generation)
+	<-ch
+
+	for i := 0; i < 200 && tq.IsRunning(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.False(t, tq.IsRunning())
+}
+
+func TestLingerCoarseClock(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*200, 10)
+	tq.SetCoarseClock(time.Millisecond)
+	tq.SetLinger(time.Second * 10)
+	defer tq.Close()
+	ch := make(chan time.Time, 1)
+	action := func() { ch <- time.Now() }
+
+	tq.SetTimeout(time.Millisecond)
+	tq.Add(action)
+	<-ch
+	tq.SetTimeout(time.Millisecond * 200)
+	time.Sleep(time.Millisecond * 150)
+	assert.True(t, tq.IsRunning())
+
+	// the lingering runner kept the cached time fresh, so the deadline is a
+	// full timeout from now
+	start := time.Now()
+	tq.Add(action)
+	assert.True(t, (<-ch).Sub(start) >= time.Millisecond*190)
+}
+
+func TestSetTimeoutWakesRunner(t *testing.T) {
+	tq := timeoutqueue.New(time.Hour, 10)
+	ch := make(chan bool, 1)
+	tq.Add(func() { ch <- true })
+	for i := 0; i < 100 && tq.RunnerState().SleepUntil.IsZero(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	// shortening the timeout wakes the runner instead of replacing it
+	for i := 0; i < 100; i++ {
+		tq.SetTimeout(time.Hour - time.Duration(i)*time.Second)
+	}
+	assert.Equal(t, 1, tq.Goroutines())
+	tq.SetTimeout(time.Millisecond * 10)
+	assert.Equal(t, uint16(1), tq.RunnerState().Generation)
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	tq.Close()
+}
+
+func TestEarlierDeadlineWakesRunner(t *testing.T) {
+	tq := timeoutqueue.New(time.Hour, 10)
+	tq.Add(func() {})
+	for i := 0; i < 100 && tq.RunnerState().SleepUntil.IsZero(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	// each Add has an earlier deadline than the one the runner sleeps until
+	for i := 1; i <= 100; i++ {
+		tq.SetTimeoutForNew(time.Hour - time.Duration(i)*time.Second)
+		tq.Add(func() {})
+		assert.Equal(t, 1, tq.Goroutines())
+	}
+	assert.Equal(t, uint16(1), tq.RunnerState().Generation)
+	tq.Close()
+	assert.Equal(t, 0, tq.Goroutines())
+}
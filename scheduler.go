@@ -0,0 +1,100 @@
+package timeoutqueue
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TimeoutScheduler is the core behavior of a TimeoutQueue, so code that only
+// needs to schedule and cancel timeouts can accept any implementation. It is
+// fulfilled by TimeoutQueue, Sharded and the Queue from timeoutqueuetest, which
+// can stand in for either in tests.
+type TimeoutScheduler interface {
+	Add(action TimeoutAction) Token
+	AddWithID(id interface{}, action CorrelatedAction) Token
+	Timeout() time.Duration
+	SetTimeout(timeout time.Duration)
+	Flush()
+	Len() int
+}
+
+var (
+	_ TimeoutScheduler = (*TimeoutQueue)(nil)
+	_ TimeoutScheduler = (*Sharded)(nil)
+)
+
+// Sharded spreads TimeoutActions over several TimeoutQueues with the same
+// timeout, each with it's own lock and runner, to cut contention when a great
+// many Go routines add to the same queue. Adds are spread round robin and the
+// Tokens work as they would for a single queue.
+type Sharded struct {
+	// next is first to guarentee 64 bit alignment for atomic operations
+	next   uint64
+	shards []*TimeoutQueue
+}
+
+// NewSharded returns a Sharded with the given number of shards, each a new
+// TimeoutQueue with the timeout and capacity. At least one shard is always
+// created.
+func NewSharded(timeout time.Duration, capacity, shards int) *Sharded {
+	if shards < 1 {
+		shards = 1
+	}
+	s := &Sharded{
+		shards: make([]*TimeoutQueue, shards),
+	}
+	for i := range s.shards {
+		s.shards[i] = New(timeout, capacity)
+	}
+	return s
+}
+
+func (s *Sharded) shard() *TimeoutQueue {
+	return s.shards[(atomic.AddUint64(&s.next, 1)-1)%uint64(len(s.shards))]
+}
+
+// Add fulfills TimeoutScheduler.
+func (s *Sharded) Add(action TimeoutAction) Token {
+	return s.shard().Add(action)
+}
+
+// AddWithID fulfills TimeoutScheduler.
+func (s *Sharded) AddWithID(id interface{}, action CorrelatedAction) Token {
+	return s.shard().AddWithID(id, action)
+}
+
+// Timeout fulfills TimeoutScheduler.
+func (s *Sharded) Timeout() time.Duration {
+	return s.shards[0].Timeout()
+}
+
+// SetTimeout fulfills TimeoutScheduler. Each shard is changed in turn, so for a
+// moment the shards may have different timeouts.
+func (s *Sharded) SetTimeout(timeout time.Duration) {
+	for _, tq := range s.shards {
+		tq.SetTimeout(timeout)
+	}
+}
+
+// Flush fulfills TimeoutScheduler, flushing each shard in turn.
+func (s *Sharded) Flush() {
+	for _, tq := range s.shards {
+		tq.Flush()
+	}
+}
+
+// Len fulfills TimeoutScheduler. It is the sum of the shards' lengths, each
+// read separately.
+func (s *Sharded) Len() int {
+	var n int
+	for _, tq := range s.shards {
+		n += tq.Len()
+	}
+	return n
+}
+
+// Shards returns the queues of the Sharded, for configuring them or reading
+// their Stats.
+func (s *Sharded) Shards() []*TimeoutQueue {
+	return s.shards
+}
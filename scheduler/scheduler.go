@@ -0,0 +1,207 @@
+// Package scheduler runs recurring actions from simple interval or calendar
+// specs, such as "every 5m" or "daily at 02:00". The schedules of a Scheduler
+// share a single TimeoutQueue, so no further scheduling dependency is needed
+// and a single runner Go routine serves them all.
+package scheduler
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+)
+
+// ErrBadSpec is returned by Parse if the spec is not understood.
+var ErrBadSpec = errors.New("scheduler: bad spec")
+
+// Spec decides when a schedule next runs.
+type Spec interface {
+	// Next returns the first time after t that the schedule should run.
+	Next(t time.Time) time.Time
+}
+
+// Every runs at a fixed interval.
+type Every time.Duration
+
+// Next fulfills Spec.
+func (e Every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// Daily runs once a day at a time of day given as an offset from midnight in
+// the local time zone. The offset is a wall clock time, so on days when the
+// clocks change it still runs at the same time of day.
+type Daily time.Duration
+
+// Next fulfills Spec.
+func (d Daily) Next(t time.Time) time.Time {
+	y, m, day := t.Date()
+	next := d.on(y, m, day, t.Location())
+	if !next.After(t) {
+		next = d.on(y, m, day+1, t.Location())
+	}
+	return next
+}
+
+func (d Daily) on(y int, m time.Month, day int, loc *time.Location) time.Time {
+	h := time.Duration(d) / time.Hour
+	min := time.Duration(d) % time.Hour / time.Minute
+	sec := time.Duration(d) % time.Minute / time.Second
+	ns := time.Duration(d) % time.Second
+	return time.Date(y, m, day, int(h), int(min), int(sec), int(ns), loc)
+}
+
+// Parse a spec. The accepted forms are "every <duration>", where the duration
+// is in the format of time.ParseDuration, and "daily at HH:MM".
+func Parse(spec string) (Spec, error) {
+	fields := strings.Fields(strings.ToLower(spec))
+	switch {
+	case len(fields) == 2 && fields[0] == "every":
+		d, err := time.ParseDuration(fields[1])
+		if err != nil || d <= 0 {
+			return nil, ErrBadSpec
+		}
+		return Every(d), nil
+	case len(fields) == 3 && fields[0] == "daily" && fields[1] == "at":
+		t, err := time.Parse("15:04", fields[2])
+		if err != nil {
+			return nil, ErrBadSpec
+		}
+		return Daily(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute), nil
+	}
+	return nil, ErrBadSpec
+}
+
+// Scheduler holds a set of Entries so they can be stopped together.
+type Scheduler struct {
+	mux     sync.Mutex
+	entries map[*Entry]struct{}
+	// tq holds the next run of every Entry. Runs are added with Import to give
+	// each it's own delay, so the queue's timeout is never used.
+	tq *timeoutqueue.TimeoutQueue
+}
+
+// New returns an empty Scheduler.
+func New() *Scheduler {
+	return &Scheduler{
+		entries: make(map[*Entry]struct{}),
+		tq:      timeoutqueue.New(time.Hour, 0),
+	}
+}
+
+// Entry is a recurring action. It's Handle is for it's next run in the
+// Scheduler's queue.
+type Entry struct {
+	mux     sync.Mutex
+	s       *Scheduler
+	spec    Spec
+	action  func()
+	handle  timeoutqueue.Handle
+	next    time.Time
+	stopped bool
+}
+
+// Schedule parses spec and runs action according to it until the Entry or the
+// Scheduler is stopped. The action is called in it's own Go routine.
+func (s *Scheduler) Schedule(spec string, action func()) (*Entry, error) {
+	sp, err := Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	return s.ScheduleSpec(sp, action), nil
+}
+
+// ScheduleSpec is Schedule with a Spec that is already parsed or that is a
+// custom implementation.
+func (s *Scheduler) ScheduleSpec(spec Spec, action func()) *Entry {
+	e := &Entry{
+		s:      s,
+		spec:   spec,
+		action: action,
+	}
+	s.mux.Lock()
+	s.entries[e] = struct{}{}
+	s.mux.Unlock()
+
+	e.arm(time.Now())
+	return e
+}
+
+// arm schedules the next run. It must be called without the Entry's mux lock;
+// if the Entry was stopped in the meantime the run is canceled.
+func (e *Entry) arm(now time.Time) {
+	next := e.spec.Next(now)
+	e.mux.Lock()
+	e.next = next
+	e.mux.Unlock()
+	h := e.s.tq.Import(nil, []timeoutqueue.Entry{{
+		ID:        e,
+		Remaining: next.Sub(now),
+	}}, bind)[0]
+	e.mux.Lock()
+	if e.stopped {
+		h.Cancel()
+	} else {
+		e.handle = h
+	}
+	e.mux.Unlock()
+}
+
+// bind returns the action for the next run of an Entry, which is the Entry's
+// ID.
+func bind(interface{}) timeoutqueue.CorrelatedAction {
+	return runEntry
+}
+
+func runEntry(id interface{}) {
+	id.(*Entry).run()
+}
+
+func (e *Entry) run() {
+	e.mux.Lock()
+	stopped := e.stopped
+	e.mux.Unlock()
+	if stopped {
+		return
+	}
+	e.arm(time.Now())
+	e.action()
+}
+
+// Next returns the time the Entry will next run.
+func (e *Entry) Next() time.Time {
+	e.mux.Lock()
+	next := e.next
+	e.mux.Unlock()
+	return next
+}
+
+// Stop the Entry. An action that is already running will complete. The returned
+// bool is false if the Entry was already stopped.
+func (e *Entry) Stop() bool {
+	e.mux.Lock()
+	stopped := !e.stopped
+	e.stopped = true
+	e.handle.Cancel()
+	e.mux.Unlock()
+
+	e.s.mux.Lock()
+	delete(e.s.entries, e)
+	e.s.mux.Unlock()
+	return stopped
+}
+
+// Stop every Entry in the Scheduler.
+func (s *Scheduler) Stop() {
+	s.mux.Lock()
+	entries := make([]*Entry, 0, len(s.entries))
+	for e := range s.entries {
+		entries = append(entries, e)
+	}
+	s.mux.Unlock()
+	for _, e := range entries {
+		e.Stop()
+	}
+}
@@ -0,0 +1,83 @@
+//go:build !timeoutqueuesingle
+
+package scheduler_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/scheduler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule(t *testing.T) {
+	s := scheduler.New()
+	ch := make(chan bool, 10)
+	e, err := s.Schedule("every 2ms", func() {
+		ch <- true
+	})
+	assert.NoError(t, err)
+	assert.True(t, e.Next().After(time.Now()))
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ch:
+		case <-time.After(time.Millisecond * 50):
+			t.Fatal("schedule did not run")
+		}
+	}
+	s.Stop()
+	assert.False(t, e.Stop())
+	time.Sleep(time.Millisecond * 5)
+	for len(ch) > 0 {
+		<-ch
+	}
+	select {
+	case <-ch:
+		t.Error("ran after stop")
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	_, err = s.Schedule("sometimes", func() {})
+	assert.Equal(t, scheduler.ErrBadSpec, err)
+}
+
+func TestScheduleStopFromAction(t *testing.T) {
+	s := scheduler.New()
+	var runs int64
+	var e *scheduler.Entry
+	ready := make(chan bool)
+	// a spec that is always due runs the action as soon as possible
+	e = s.ScheduleSpec(scheduler.Every(0), func() {
+		<-ready
+		atomic.AddInt64(&runs, 1)
+		e.Stop()
+	})
+	close(ready)
+	time.Sleep(time.Millisecond * 10)
+	n := atomic.LoadInt64(&runs)
+	assert.True(t, n > 0)
+	time.Sleep(time.Millisecond * 20)
+	assert.Equal(t, n, atomic.LoadInt64(&runs), "ran after stop")
+	assert.False(t, e.Stop())
+}
+
+func TestScheduleShared(t *testing.T) {
+	s := scheduler.New()
+	defer s.Stop()
+	var fast, slow int64
+	s.ScheduleSpec(scheduler.Every(time.Millisecond*2), func() {
+		atomic.AddInt64(&fast, 1)
+	})
+	e := s.ScheduleSpec(scheduler.Every(time.Hour), func() {
+		atomic.AddInt64(&slow, 1)
+	})
+	// the entries share a queue but each keeps it's own interval
+	for i := 0; i < 100 && atomic.LoadInt64(&fast) < 3; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(t, atomic.LoadInt64(&fast) >= 3)
+	assert.Equal(t, int64(0), atomic.LoadInt64(&slow))
+	assert.True(t, e.Next().After(time.Now().Add(time.Minute*59)))
+}
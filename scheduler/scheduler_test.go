@@ -0,0 +1,49 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/scheduler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	s, err := scheduler.Parse("every 5m")
+	assert.NoError(t, err)
+	assert.Equal(t, scheduler.Every(time.Minute*5), s)
+
+	s, err = scheduler.Parse("Daily at 02:30")
+	assert.NoError(t, err)
+	assert.Equal(t, scheduler.Daily(time.Hour*2+time.Minute*30), s)
+
+	for _, bad := range []string{"", "every", "every -1s", "daily 02:00", "daily at 25:00", "hourly"} {
+		_, err = scheduler.Parse(bad)
+		assert.Equal(t, scheduler.ErrBadSpec, err, bad)
+	}
+}
+
+func TestDaily(t *testing.T) {
+	d := scheduler.Daily(time.Hour * 2)
+	before := time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2020, 1, 1, 2, 0, 0, 0, time.UTC), d.Next(before))
+	at := time.Date(2020, 1, 1, 2, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2020, 1, 2, 2, 0, 0, 0, time.UTC), d.Next(at))
+}
+
+func TestDailyDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	d := scheduler.Daily(time.Hour*3 + time.Minute*30)
+	// the clocks go forward at 2am on 8 March 2020 and back on 1 November
+	spring := time.Date(2020, 3, 8, 1, 0, 0, 0, ny)
+	assert.Equal(t, time.Date(2020, 3, 8, 3, 30, 0, 0, ny), d.Next(spring))
+	fall := time.Date(2020, 11, 1, 0, 0, 0, 0, ny)
+	assert.Equal(t, time.Date(2020, 11, 1, 3, 30, 0, 0, ny), d.Next(fall))
+	// the day after a change it's still the same wall clock time
+	next := d.Next(d.Next(spring))
+	assert.Equal(t, 3, next.Hour())
+	assert.Equal(t, 30, next.Minute())
+}
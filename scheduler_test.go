@@ -0,0 +1,41 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharded(t *testing.T) {
+	s := timeoutqueue.NewSharded(time.Hour, 10, 4)
+	assert.Len(t, s.Shards(), 4)
+	var fired int
+	var tkns []timeoutqueue.Token
+	for i := 0; i < 8; i++ {
+		tkns = append(tkns, s.Add(func() { fired++ }))
+	}
+	s.AddWithID("id", func(interface{}) { fired++ })
+	assert.Equal(t, 9, s.Len())
+	for _, tq := range s.Shards() {
+		assert.True(t, tq.Len() >= 2)
+	}
+
+	assert.True(t, tkns[3].Cancel())
+	assert.True(t, tkns[4].Reset())
+	s.SetTimeout(time.Minute)
+	assert.Equal(t, time.Minute, s.Timeout())
+	s.Flush()
+	assert.Equal(t, 8, fired)
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestTimeoutSchedulerFake(t *testing.T) {
+	var ts timeoutqueue.TimeoutScheduler = timeoutqueuetest.New(time.Second, 10)
+	var fired bool
+	ts.Add(func() { fired = true })
+	ts.(*timeoutqueuetest.Queue).Advance(time.Second)
+	assert.True(t, fired)
+}
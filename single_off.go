@@ -0,0 +1,11 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue
+
+import (
+	"sync"
+)
+
+const single = false
+
+type mutex = sync.Mutex
@@ -0,0 +1,11 @@
+//go:build timeoutqueuesingle
+
+package timeoutqueue
+
+const single = true
+
+// mutex does nothing, everything is called from the one Go routine.
+type mutex struct{}
+
+func (*mutex) Lock()   {}
+func (*mutex) Unlock() {}
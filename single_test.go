@@ -0,0 +1,57 @@
+//go:build timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSingle(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond, 10)
+	fired := 0
+	tq.Add(func() { fired++ })
+	assert.False(t, tq.IsRunning())
+	assert.Equal(t, 0, tq.Goroutines())
+
+	time.Sleep(time.Millisecond * 5)
+	assert.Equal(t, 0, fired)
+	assert.Equal(t, 1, tq.Tick())
+	// without an Executor the action was called directly
+	assert.Equal(t, 1, fired)
+
+	tq.SetTimeout(0)
+	tq.Add(func() { fired++ })
+	assert.Equal(t, 2, fired)
+	tq.Close()
+	assert.Equal(t, 0, tq.Goroutines())
+}
+
+func TestSingleAfterFunc(t *testing.T) {
+	tq := timeoutqueue.NewAfterFunc(time.Millisecond, 10)
+	fired := 0
+	tq.Add(func() { fired++ })
+	time.Sleep(time.Millisecond * 5)
+	// the timer is never armed, the queue is driven by Tick
+	assert.Equal(t, 0, fired)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, 1, fired)
+}
+
+func TestSingleAfterFuncSetTimeout(t *testing.T) {
+	tq := timeoutqueue.NewAfterFunc(time.Hour, 10)
+	fired := 0
+	tq.Add(func() { fired++ })
+	// shortening the timeout moves the head's deadline up, which must not arm
+	// the timer either
+	tq.SetTimeout(time.Millisecond)
+	tq.SetTimeoutForNew(time.Millisecond)
+	tq.Add(func() { fired++ })
+	time.Sleep(time.Millisecond * 5)
+	assert.Equal(t, 0, fired)
+	assert.Equal(t, 2, tq.Tick())
+	assert.Equal(t, 2, fired)
+}
@@ -0,0 +1,455 @@
+package timeoutqueue
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// Errors returned by WriteTo and ReadFrom.
+var (
+	ErrSnapshotID      = errors.New("timeoutqueue: correlation ID is not a string or []byte")
+	ErrNoRebind        = errors.New("timeoutqueue: ReadFrom requires SetRebind")
+	ErrNotEmpty        = errors.New("timeoutqueue: ReadFrom requires an empty queue")
+	ErrSnapshotIndex   = errors.New("timeoutqueue: snapshot node index out of range")
+	ErrSnapshotVersion = errors.New("timeoutqueue: unknown snapshot version")
+	ErrSnapshotKey     = errors.New("timeoutqueue: snapshot correlation ID too long")
+)
+
+// MaxSnapshotKey is the longest correlation ID WriteTo will write and ReadFrom
+// will read.
+const MaxSnapshotKey = 1 << 20
+
+// snapshotChunk limits how many nodes or entries are allocated ahead of reading
+// them, so a corrupt length can't allocate more than the snapshot holds.
+const snapshotChunk = 1 << 12
+
+// SetRebind sets the func ReadFrom uses to rebuild the action for each entry
+// from it's correlation ID. It is called with the queue locked, so it may not
+// call methods on the queue.
+func (tq *TimeoutQueue) SetRebind(rebind func(id interface{}) CorrelatedAction) {
+	tq.mux.Lock()
+	tq.rebind = rebind
+	tq.mux.Unlock()
+}
+
+// WriteTo fulfills io.WriterTo. It writes a compact binary snapshot of the
+// queue: a header with the SnapshotVersion and the time, the generation counter
+// of every node and the node index, remaining duration and correlation ID of
+// every pending entry, followed by the same for every paused entry. Every
+// pending entry must have a string or []byte correlation ID, otherwise
+// ErrSnapshotID is returned. The queue is locked while writing, so w should be buffered or fast.
+func (tq *TimeoutQueue) WriteTo(w io.Writer) (int64, error) {
+	sw := &snapshotWriter{w: bufio.NewWriter(w)}
+	tq.mux.Lock()
+	now := tq.clockNow()
+	sw.write(snapshotMagic[:])
+	sw.uvarint(SnapshotVersion)
+	sw.varint(now.UnixNano())
+	sw.uvarint(uint64(len(tq.nodes)))
+	for _, n := range tq.nodes {
+		sw.uvarint(uint64(n.actionID))
+	}
+	sw.uvarint(uint64(tq.pending - len(tq.paused)))
+	for cur := tq.head; cur != empty && sw.err == nil; cur = tq.nodes[cur].next {
+		sw.entry(cur, tq.nodes[cur].id, tq.deadline(cur).Sub(now))
+	}
+	// paused entries are written in index order so equal queues write equal
+	// snapshots
+	paused := tq.pausedNodes()
+	sw.uvarint(uint64(len(paused)))
+	for _, idx := range paused {
+		sw.entry(idx, tq.nodes[idx].id, tq.paused[idx])
+	}
+	tq.mux.Unlock()
+	if sw.err == nil {
+		sw.err = sw.w.Flush()
+	}
+	return sw.n, sw.err
+}
+
+// snapshotMagic starts every versioned snapshot. The original snapshots had no
+// header but could never start with a zero byte followed by 'T', so the two are
+// told apart by the first bytes.
+var snapshotMagic = [...]byte{0, 'T', 'Q', 'S'}
+
+// SnapshotVersion is the version of the snapshot format written by WriteTo.
+// ReadFrom can read every version up to this one.
+const SnapshotVersion = 2
+
+// Versions of the snapshot format:
+//   0: the generation counter of every node, then the node index, remaining
+//      duration and correlation ID of every pending entry. There is no header.
+//   1: snapshotMagic, the version and the time the snapshot was written in
+//      Unix nanoseconds, followed by the version 0 body. Remaining durations
+//      are relative to the time written, so time spent stopped counts against
+//      the entries.
+//   2: the version 1 snapshot followed by the count of paused entries and the
+//      node index, remaining duration and correlation ID of each. Version 1
+//      did not write paused entries, so they were lost.
+
+type snapshot struct {
+	// written is zero if the snapshot did not record it
+	written time.Time
+	nodes   []node
+	entries []snapshotEntry
+	paused  []snapshotEntry
+}
+
+type snapshotEntry struct {
+	idx       uint32
+	remaining time.Duration
+	id        string
+}
+
+// snapshot reads any version of the snapshot format and migrates it to the
+// current version.
+func (sr *snapshotReader) snapshot() *snapshot {
+	snap := &snapshot{}
+	version := sr.version()
+	switch {
+	case sr.err != nil:
+		return nil
+	case version == 0:
+		// version 0 did not record when it was written, so it is treated as
+		// having been written when it is read
+	case version <= 2:
+		snap.written = time.Unix(0, sr.varint())
+	default:
+		sr.err = ErrSnapshotVersion
+		return nil
+	}
+	sr.body(snap)
+	if version >= 2 {
+		snap.paused = sr.entries(snap, uint64(len(snap.nodes)-len(snap.entries)))
+	}
+	return snap
+}
+
+// version reads the header, if there is one, and returns the version.
+func (sr *snapshotReader) version() uint64 {
+	b := sr.byte()
+	if sr.err != nil || b != snapshotMagic[0] {
+		sr.unread(b)
+		return 0
+	}
+	b = sr.byte()
+	if sr.err != nil || b != snapshotMagic[1] {
+		// a version 0 snapshot of an empty queue
+		sr.unread(b)
+		sr.unread(snapshotMagic[0])
+		return 0
+	}
+	for _, m := range snapshotMagic[2:] {
+		if sr.byte() != m && sr.err == nil {
+			sr.err = ErrSnapshotVersion
+		}
+	}
+	return sr.uvarint()
+}
+
+// body reads the part of the snapshot that is the same in every version.
+func (sr *snapshotReader) body(snap *snapshot) {
+	ln := sr.uvarint()
+	if sr.err == nil && ln > MaxCapacity {
+		sr.err = ErrSnapshotIndex
+	}
+	if sr.err != nil {
+		return
+	}
+	snap.nodes = make([]node, 0, chunk(ln))
+	for i := uint64(0); i < ln && sr.err == nil; i++ {
+		snap.nodes = append(snap.nodes, node{
+			actionID: uint32(sr.uvarint()),
+		})
+	}
+	snap.entries = sr.entries(snap, ln)
+}
+
+// entries reads a count of entries followed by each entry, the count may be no
+// more than max.
+func (sr *snapshotReader) entries(snap *snapshot, max uint64) []snapshotEntry {
+	count := sr.uvarint()
+	if sr.err == nil && count > max {
+		sr.err = ErrSnapshotIndex
+	}
+	if sr.err != nil {
+		return nil
+	}
+	entries := make([]snapshotEntry, 0, chunk(count))
+	for i := uint64(0); i < count; i++ {
+		idx := sr.uvarint()
+		remaining := sr.varint()
+		keyLn := sr.uvarint()
+		if sr.err == nil && keyLn > MaxSnapshotKey {
+			sr.err = ErrSnapshotKey
+		}
+		key := sr.bytes(keyLn)
+		if sr.err == nil && (idx >= uint64(len(snap.nodes)) || snap.nodes[idx].action != nil) {
+			sr.err = ErrSnapshotIndex
+		}
+		if sr.err != nil {
+			return nil
+		}
+		// mark the node used to catch duplicate indexes
+		snap.nodes[idx].action = true
+		entries = append(entries, snapshotEntry{
+			idx:       uint32(idx),
+			remaining: time.Duration(remaining),
+			id:        string(key),
+		})
+	}
+	return entries
+}
+
+// chunk returns the capacity to allocate for a length read from a snapshot.
+func chunk(ln uint64) int {
+	if ln > snapshotChunk {
+		return snapshotChunk
+	}
+	return int(ln)
+}
+
+type snapshotWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (sw *snapshotWriter) write(b []byte) {
+	if sw.err != nil {
+		return
+	}
+	n, err := sw.w.Write(b)
+	sw.n += int64(n)
+	sw.err = err
+}
+
+// entry writes the node index, remaining duration and correlation ID of an
+// entry.
+func (sw *snapshotWriter) entry(idx uint32, id interface{}, remaining time.Duration) {
+	var key []byte
+	switch id := id.(type) {
+	case string:
+		key = []byte(id)
+	case []byte:
+		key = id
+	default:
+		sw.err = ErrSnapshotID
+	}
+	if len(key) > MaxSnapshotKey {
+		sw.err = ErrSnapshotKey
+	}
+	sw.uvarint(uint64(idx))
+	sw.varint(int64(remaining))
+	sw.uvarint(uint64(len(key)))
+	sw.write(key)
+}
+
+func (sw *snapshotWriter) uvarint(x uint64) {
+	sw.write(sw.buf[:binary.PutUvarint(sw.buf[:], x)])
+}
+
+func (sw *snapshotWriter) varint(x int64) {
+	sw.write(sw.buf[:binary.PutVarint(sw.buf[:], x)])
+}
+
+// ReadFrom fulfills io.ReaderFrom. It restores a snapshot written by WriteTo
+// into an empty queue, rebuilding each action with the func from SetRebind.
+// Entries keep the deadlines they had when the snapshot was written, so any
+// that passed while the snapshot was stored fire straight away, and their
+// correlation IDs are restored as strings. Paused entries are restored paused
+// with the duration they had remaining. Snapshots do not record classes or
+// AddPinned, so every entry is restored in class 0 and unpinned. Snapshots from
+// older versions are migrated as they are read. The nodes are restored at the
+// same indexes with the same generation counters, unless the queue had already
+// used a node further, and the queue keeps at least as many nodes as it had so
+// Handles taken before ReadFrom stay dead. A queue from NewFixed never grows;
+// it returns ErrFull if an entry's index is past it's capacity. Unless r is an
+// io.ByteReader, it is buffered and ReadFrom may read past the end of the
+// snapshot.
+func (tq *TimeoutQueue) ReadFrom(r io.Reader) (int64, error) {
+	sr := &snapshotReader{}
+	if br, ok := r.(byteReader); ok {
+		sr.r = br
+	} else {
+		sr.r = bufio.NewReader(r)
+	}
+
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	if tq.rebind == nil {
+		return 0, ErrNoRebind
+	}
+	if tq.head != empty || len(tq.paused) > 0 {
+		return 0, ErrNotEmpty
+	}
+
+	snap := sr.snapshot()
+	if sr.err != nil {
+		return sr.n, sr.err
+	}
+	now := tq.clockNow()
+	if snap.written.IsZero() {
+		snap.written = now
+	}
+	nodes := snap.nodes
+	entries := snap.entries
+	if tq.fixed && len(nodes) > len(tq.nodes) {
+		// unused nodes past the capacity can be dropped, entries can't
+		for _, es := range [][]snapshotEntry{entries, snap.paused} {
+			for _, e := range es {
+				if int(e.idx) >= len(tq.nodes) {
+					return sr.n, ErrFull
+				}
+			}
+		}
+		nodes = nodes[:len(tq.nodes)]
+	}
+	// the nodes are never shorter than before, and every generation counter is
+	// at least what it was, so no Handle taken before ReadFrom matches a node
+	if len(nodes) < len(tq.nodes) {
+		nodes = append(nodes, make([]node, len(tq.nodes)-len(nodes))...)
+	}
+	for i := range tq.nodes {
+		if gen := tq.nodes[i].actionID; nodes[i].actionID < gen {
+			nodes[i].actionID = gen
+		}
+	}
+
+	// replace the queue's nodes, keeping the generation counters from the
+	// snapshot and putting every unused node on the free list
+	tq.nodes = nodes
+	tq.head, tq.tail, tq.free = empty, empty, empty
+	tq.pending = 0
+	tq.paused = nil
+	tq.shift = 0
+	for _, e := range entries {
+		n := &tq.nodes[e.idx]
+		n.next = empty
+		n.prev = tq.tail
+		n.timeout = snap.written.Add(e.remaining)
+		n.action = tq.rebind(e.id)
+		n.id = e.id
+		tq.add(e.idx)
+		tq.emit(EventAdded, e.idx)
+		tq.pending++
+	}
+	for _, e := range snap.paused {
+		n := &tq.nodes[e.idx]
+		n.next, n.prev = empty, empty
+		n.action = tq.rebind(e.id)
+		n.id = e.id
+		if tq.paused == nil {
+			tq.paused = make(map[uint32]time.Duration)
+		}
+		tq.paused[e.idx] = e.remaining
+		tq.emit(EventAdded, e.idx)
+		tq.pending++
+	}
+	for i := len(tq.nodes) - 1; i >= 0; i-- {
+		if tq.nodes[i].action == nil {
+			tq.nodes[i].next = tq.free
+			tq.free = uint32(i)
+		}
+	}
+	// actions in flight from the old nodes are no longer tracked
+	if tq.landing != nil {
+		tq.landing.Broadcast()
+	}
+	tq.debugValidate()
+	tq.checkPressure()
+	tq.rearm()
+	return sr.n, nil
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// snapshotReader counts the bytes read so ReadFrom can return the size of the
+// snapshot.
+type snapshotReader struct {
+	r   byteReader
+	n   int64
+	err error
+	// back holds bytes that were unread, last first
+	back []byte
+}
+
+func (sr *snapshotReader) ReadByte() (byte, error) {
+	if ln := len(sr.back); ln > 0 {
+		b := sr.back[ln-1]
+		sr.back = sr.back[:ln-1]
+		return b, nil
+	}
+	b, err := sr.r.ReadByte()
+	if err == nil {
+		sr.n++
+	}
+	return b, err
+}
+
+func (sr *snapshotReader) byte() byte {
+	if sr.err != nil {
+		return 0
+	}
+	var b byte
+	b, sr.err = sr.ReadByte()
+	if sr.err == io.EOF && sr.n > 0 {
+		sr.err = io.ErrUnexpectedEOF
+	}
+	return b
+}
+
+// unread pushes back a byte read by byte, unless byte failed because there was
+// nothing to read.
+func (sr *snapshotReader) unread(b byte) {
+	if sr.err == nil {
+		sr.back = append(sr.back, b)
+	}
+}
+
+func (sr *snapshotReader) uvarint() uint64 {
+	if sr.err != nil {
+		return 0
+	}
+	var x uint64
+	x, sr.err = binary.ReadUvarint(sr)
+	sr.eof()
+	return x
+}
+
+func (sr *snapshotReader) varint() int64 {
+	if sr.err != nil {
+		return 0
+	}
+	var x int64
+	x, sr.err = binary.ReadVarint(sr)
+	sr.eof()
+	return x
+}
+
+func (sr *snapshotReader) bytes(ln uint64) []byte {
+	if sr.err != nil {
+		return nil
+	}
+	b := make([]byte, ln)
+	var n int
+	n, sr.err = io.ReadFull(sr.r, b)
+	sr.n += int64(n)
+	sr.eof()
+	return b
+}
+
+// eof converts a clean EOF part way through a snapshot into an
+// io.ErrUnexpectedEOF.
+func (sr *snapshotReader) eof() {
+	if sr.err == io.EOF {
+		sr.err = io.ErrUnexpectedEOF
+	}
+}
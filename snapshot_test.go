@@ -0,0 +1,233 @@
+package timeoutqueue_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	nop := func(interface{}) {}
+	tq.AddWithID("a", nop)
+	canceled := tq.AddWithID("x", nop)
+	clock.Advance(time.Millisecond * 400)
+	b := tq.AddWithID([]byte("b"), nop)
+	canceled.Cancel()
+
+	var buf bytes.Buffer
+	n, err := tq.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	restored := timeoutqueue.NewManual(time.Second, 0, clock)
+	restored.SetExecutor(timeoutqueue.Inline)
+	_, err = restored.ReadFrom(bytes.NewReader(buf.Bytes()))
+	assert.Equal(t, timeoutqueue.ErrNoRebind, err)
+
+	var fired []interface{}
+	restored.SetRebind(func(id interface{}) timeoutqueue.CorrelatedAction {
+		return func(id interface{}) { fired = append(fired, id) }
+	})
+	_, err = restored.ReadFrom(bytes.NewReader(buf.Bytes()[:n-1]))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	n2, err := restored.ReadFrom(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, n, n2)
+	assert.NoError(t, restored.Validate())
+	assert.Equal(t, 2, restored.Len())
+
+	_, err = restored.ReadFrom(bytes.NewReader(buf.Bytes()))
+	assert.Equal(t, timeoutqueue.ErrNotEmpty, err)
+
+	clock.Advance(time.Millisecond * 600)
+	assert.Equal(t, 1, restored.Tick())
+	assert.Equal(t, []interface{}{"a"}, fired)
+	restored.Flush()
+	assert.Equal(t, []interface{}{"a", "b"}, fired)
+	// writing a snapshot leaves the original queue untouched
+	assert.True(t, b.Cancel())
+
+	tq.Add(func() {})
+	_, err = tq.WriteTo(io.Discard)
+	assert.Equal(t, timeoutqueue.ErrSnapshotID, err)
+}
+
+func TestSnapshotVersions(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	var fired []interface{}
+	newQueue := func() *timeoutqueue.TimeoutQueue {
+		tq := timeoutqueue.NewManual(time.Second, 0, clock)
+		tq.SetExecutor(timeoutqueue.Inline)
+		tq.SetRebind(func(id interface{}) timeoutqueue.CorrelatedAction {
+			return func(id interface{}) { fired = append(fired, id) }
+		})
+		return tq
+	}
+
+	// version 0 had no header and was relative to when it was read: 2 nodes
+	// with generations 3 and 0, node 0 pending with 1s remaining and ID "a"
+	v0 := []byte{2, 3, 0, 1, 0}
+	v0 = binary.AppendVarint(v0, int64(time.Second))
+	v0 = append(v0, 1, 'a')
+	tq := newQueue()
+	n, err := tq.ReadFrom(bytes.NewReader(v0))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(v0)), n)
+	assert.Equal(t, 1, tq.Len())
+
+	// version 1 had no paused entries after the body
+	v1 := []byte{0, 'T', 'Q', 'S', 1}
+	v1 = binary.AppendVarint(v1, clock.Now().UnixNano())
+	v1 = append(v1, v0...)
+	tq = newQueue()
+	n, err = tq.ReadFrom(bytes.NewReader(v1))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(v1)), n)
+	assert.Equal(t, 1, tq.Len())
+
+	// an empty version 0 snapshot is only two bytes
+	tq = newQueue()
+	_, err = tq.ReadFrom(bytes.NewReader([]byte{0, 0}))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, tq.Len())
+
+	_, err = tq.ReadFrom(bytes.NewReader([]byte{0, 'T', 'Q', 'S', 100}))
+	assert.Equal(t, timeoutqueue.ErrSnapshotVersion, err)
+	_, err = tq.ReadFrom(bytes.NewReader(nil))
+	assert.Equal(t, io.EOF, err)
+
+	// time spent between writing and reading counts against the entries
+	src := newQueue()
+	src.AddWithID("b", func(interface{}) {})
+	var buf bytes.Buffer
+	_, err = src.WriteTo(&buf)
+	assert.NoError(t, err)
+	clock.Advance(time.Second * 2)
+	tq = newQueue()
+	_, err = tq.ReadFrom(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, []interface{}{"b"}, fired)
+}
+
+func TestSnapshotCorrupt(t *testing.T) {
+	tq := timeoutqueue.New(time.Second, 0)
+	tq.SetRebind(func(id interface{}) timeoutqueue.CorrelatedAction {
+		return func(interface{}) {}
+	})
+	// a huge node count with nothing behind it fails on the missing input
+	// instead of allocating the nodes up front
+	huge := binary.AppendUvarint(nil, timeoutqueue.MaxCapacity)
+	_, err := tq.ReadFrom(bytes.NewReader(huge))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// one node pending with a correlation ID longer than the limit
+	long := []byte{1, 0, 1, 0, 0}
+	long = binary.AppendUvarint(long, timeoutqueue.MaxSnapshotKey+1)
+	_, err = tq.ReadFrom(bytes.NewReader(long))
+	assert.Equal(t, timeoutqueue.ErrSnapshotKey, err)
+	assert.Equal(t, 0, tq.Len())
+
+	tq.AddWithID(string(make([]byte, timeoutqueue.MaxSnapshotKey+1)), func(interface{}) {})
+	_, err = tq.WriteTo(io.Discard)
+	assert.Equal(t, timeoutqueue.ErrSnapshotKey, err)
+	tq.Close()
+}
+
+func TestSnapshotPaused(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	var fired []interface{}
+	newQueue := func() *timeoutqueue.TimeoutQueue {
+		tq := timeoutqueue.NewManual(time.Second, 0, clock)
+		tq.SetExecutor(timeoutqueue.Inline)
+		tq.SetRebind(func(id interface{}) timeoutqueue.CorrelatedAction {
+			return func(id interface{}) { fired = append(fired, id) }
+		})
+		return tq
+	}
+	src := newQueue()
+	src.AddWithID("a", func(interface{}) {})
+	b := src.AddWithID("b", func(interface{}) {}).(timeoutqueue.Handle)
+	assert.True(t, b.Pause())
+
+	// a queue holding only a paused entry is not empty
+	_, err := src.ReadFrom(bytes.NewReader([]byte{0, 0}))
+	assert.Equal(t, timeoutqueue.ErrNotEmpty, err)
+
+	var buf bytes.Buffer
+	_, err = src.WriteTo(&buf)
+	assert.NoError(t, err)
+	tq := newQueue()
+	_, err = tq.ReadFrom(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, tq.Len())
+	assert.NoError(t, tq.Validate())
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, []interface{}{"a"}, fired)
+	assert.Equal(t, 1, tq.Len())
+	tq.Flush()
+	assert.Equal(t, []interface{}{"a", "b"}, fired)
+}
+
+func TestSnapshotStaleHandles(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	rebind := func(id interface{}) timeoutqueue.CorrelatedAction {
+		return func(interface{}) {}
+	}
+	src := timeoutqueue.NewManual(time.Second, 0, clock)
+	src.SetRebind(rebind)
+	src.AddWithID("a", func(interface{}) {})
+	var buf bytes.Buffer
+	_, err := src.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	tq := timeoutqueue.NewManual(time.Second, 0, clock)
+	tq.SetRebind(rebind)
+	var hs []timeoutqueue.Handle
+	for i := 0; i < 5; i++ {
+		hs = append(hs, tq.Add(func() {}).(timeoutqueue.Handle))
+	}
+	tq.Flush()
+	_, err = tq.ReadFrom(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, tq.Len())
+	for _, h := range hs {
+		assert.False(t, h.Cancel())
+		assert.False(t, h.Reset())
+	}
+	assert.Equal(t, 1, tq.Len())
+	assert.NoError(t, tq.Validate())
+}
+
+func TestSnapshotFixed(t *testing.T) {
+	rebind := func(id interface{}) timeoutqueue.CorrelatedAction {
+		return func(interface{}) {}
+	}
+	src := timeoutqueue.New(time.Hour, 0)
+	src.SetRebind(rebind)
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		src.AddWithID(id, func(interface{}) {})
+	}
+	var buf bytes.Buffer
+	_, err := src.WriteTo(&buf)
+	assert.NoError(t, err)
+	src.Close()
+
+	tq := timeoutqueue.NewFixed(time.Hour, 2, timeoutqueue.RejectWhenFull)
+	tq.SetRebind(rebind)
+	_, err = tq.ReadFrom(&buf)
+	assert.Equal(t, timeoutqueue.ErrFull, err)
+	assert.Equal(t, 0, tq.Len())
+	assert.Equal(t, 2, tq.Cap())
+	tq.Close()
+}
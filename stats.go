@@ -0,0 +1,130 @@
+package timeoutqueue
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// HistogramBuckets is the number of buckets in a Histogram.
+const HistogramBuckets = 15
+
+// DriftBuckets are the upper bounds of the buckets in a Histogram. Anything
+// greater than the last bound is counted in the final bucket.
+var DriftBuckets = [HistogramBuckets - 1]time.Duration{
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// Histogram of durations. Counts[i] is the number of samples no greater than
+// DriftBuckets[i] and greater than the bound before it, the final count holds
+// everything beyond the last bound.
+type Histogram struct {
+	Counts [HistogramBuckets]uint64
+	Sum    time.Duration
+}
+
+// Count returns the total number of samples.
+func (h Histogram) Count() uint64 {
+	var c uint64
+	for _, n := range h.Counts {
+		c += n
+	}
+	return c
+}
+
+// Mean returns the mean of the samples or zero if there are none.
+func (h Histogram) Mean() time.Duration {
+	c := h.Count()
+	if c == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(c)
+}
+
+type histogram struct {
+	counts [HistogramBuckets]uint64
+	sum    uint64
+}
+
+func (h *histogram) record(d time.Duration) {
+	i := 0
+	for i < len(DriftBuckets) && d > DriftBuckets[i] {
+		i++
+	}
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddUint64(&h.sum, uint64(d))
+}
+
+func (h *histogram) load() Histogram {
+	var out Histogram
+	for i := range h.counts {
+		out.Counts[i] = atomic.LoadUint64(&h.counts[i])
+	}
+	out.Sum = time.Duration(atomic.LoadUint64(&h.sum))
+	return out
+}
+
+// Stats are counters of the queue's activity since it was created. They are
+// maintained with atomic operations so reading them never blocks the queue,
+// but as each counter is read independently they may be slightly out of step
+// with each other.
+type Stats struct {
+	Added    uint64
+	Fired    uint64
+	Canceled uint64
+	Reset    uint64
+	Grew     uint64
+	Late     uint64
+	Rejected uint64
+	// Pending is the number of TimeoutActions in the queue.
+	Pending uint64
+	// Executing is the number of TimeoutActions that fired and are still
+	// running and MaxExecuting the most there have been at once. If
+	// MaxExecuting keeps climbing the actions are slower than the rate they
+	// fire and an Executor with a bounded worker pool may be needed. Actions
+	// called by Flush or Close are not included.
+	Executing    uint64
+	MaxExecuting uint64
+	// Drift is how late TimeoutActions were dispatched relative to their
+	// deadline. Actions called by Flush are not included.
+	Drift Histogram
+}
+
+// Stats returns the queue's counters without taking the lock.
+func (tq *TimeoutQueue) Stats() Stats {
+	s := Stats{
+		Added:    atomic.LoadUint64(&tq.counters[EventAdded]),
+		Fired:    atomic.LoadUint64(&tq.counters[EventFired]),
+		Canceled: atomic.LoadUint64(&tq.counters[EventCanceled]),
+		Reset:    atomic.LoadUint64(&tq.counters[EventReset]),
+		Grew:     atomic.LoadUint64(&tq.counters[EventGrew]),
+		Late:     atomic.LoadUint64(&tq.counters[EventLate]),
+		Rejected: atomic.LoadUint64(&tq.counters[EventRejected]),
+		Drift:    tq.drift.load(),
+
+		Executing:    atomic.LoadUint64(&tq.executing),
+		MaxExecuting: atomic.LoadUint64(&tq.maxExecuting),
+	}
+	if done := s.Fired + s.Canceled + s.Late; s.Added > done {
+		s.Pending = s.Added - done
+	}
+	return s
+}
+
+// Len returns the number of TimeoutActions in the queue without taking the
+// lock.
+func (tq *TimeoutQueue) Len() int {
+	return int(tq.Stats().Pending)
+}
@@ -0,0 +1,70 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeout"
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 1)
+	ch := make(chan int)
+
+	tkn := tq.Add(getAction(ch, 1))
+	tq.Add(getAction(ch, 2))
+	tq.Add(getAction(ch, 3))
+	assert.Equal(t, 3, tq.Len())
+	assert.True(t, tkn.Reset())
+	assert.True(t, tkn.Cancel())
+	assert.Equal(t, 2, tq.Len())
+
+	assert.NoError(t, timeout.After(20, func() {
+		<-ch
+		<-ch
+	}))
+	s := tq.Stats()
+	assert.Equal(t, uint64(2), s.Drift.Count())
+	s.Drift = timeoutqueue.Histogram{}
+	// the actions may not have returned yet, see TestStatsExecuting
+	s.Executing, s.MaxExecuting = 0, 0
+	assert.Equal(t, timeoutqueue.Stats{
+		Added:    3,
+		Fired:    2,
+		Canceled: 1,
+		Reset:    1,
+		Grew:     2,
+	}, s)
+	assert.Equal(t, 0, tq.Len())
+}
+
+func TestStatsExecuting(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond, 10)
+	release := make(chan struct{})
+	done := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		tq.Add(func() {
+			<-release
+			done <- 1
+		})
+	}
+	assert.NoError(t, timeout.After(50, func() {
+		for tq.Stats().Executing < 3 {
+			time.Sleep(time.Millisecond)
+		}
+	}))
+	close(release)
+	assert.NoError(t, timeout.After(50, func() {
+		<-done
+		<-done
+		<-done
+		for tq.Stats().Executing > 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}))
+	assert.Equal(t, uint64(3), tq.Stats().MaxExecuting)
+}
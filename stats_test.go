@@ -0,0 +1,30 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrift(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	tq.SetExecutor(timeoutqueue.ExecutorFunc(func(action func()) {
+		action()
+	}))
+
+	tq.Add(func() {})
+	tq.Add(func() {})
+	tq.Add(func() {})
+	clock.Advance(time.Second + time.Millisecond*3)
+	assert.Equal(t, 3, tq.Tick())
+
+	h := tq.Stats().Drift
+	assert.Equal(t, uint64(3), h.Count())
+	assert.Equal(t, uint64(3), h.Counts[6])
+	assert.Equal(t, time.Millisecond*3, h.Mean())
+	assert.Equal(t, time.Duration(0), timeoutqueue.Histogram{}.Mean())
+}
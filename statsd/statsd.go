@@ -0,0 +1,132 @@
+// Package statsd periodically reports the Stats of a TimeoutQueue to a statsd
+// or DogStatsD server.
+package statsd
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+)
+
+// Reporter sends a TimeoutQueue's Stats to an io.Writer in the statsd line
+// format. Pending is sent as a gauge, the counters are sent as the change since
+// the last report and the mean drift is sent as a timer in milliseconds.
+type Reporter struct {
+	tq     *timeoutqueue.TimeoutQueue
+	w      io.Writer
+	prefix string
+	tags   string
+
+	mux  sync.Mutex
+	prev timeoutqueue.Stats
+	buf  bytes.Buffer
+	stop chan struct{}
+}
+
+// New returns a Reporter for tq that writes to w. Metric names begin with
+// prefix followed by a dot. If any tags are given they are appended in the
+// DogStatsD format.
+func New(tq *timeoutqueue.TimeoutQueue, w io.Writer, prefix string, tags ...string) *Reporter {
+	r := &Reporter{
+		tq:     tq,
+		w:      w,
+		prefix: prefix,
+	}
+	if len(tags) > 0 {
+		r.tags = "|#" + strings.Join(tags, ",")
+	}
+	if r.prefix != "" && !strings.HasSuffix(r.prefix, ".") {
+		r.prefix += "."
+	}
+	return r
+}
+
+// Dial returns a Reporter that sends over UDP to addr.
+func Dial(tq *timeoutqueue.TimeoutQueue, addr, prefix string, tags ...string) (*Reporter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return New(tq, conn, prefix, tags...), nil
+}
+
+// Report sends the current Stats. All the metrics are sent in a single write.
+func (r *Reporter) Report() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	s := r.tq.Stats()
+	r.buf.Reset()
+	r.metric("pending", s.Pending, "g")
+	r.metric("added", s.Added-r.prev.Added, "c")
+	r.metric("fired", s.Fired-r.prev.Fired, "c")
+	r.metric("canceled", s.Canceled-r.prev.Canceled, "c")
+	r.metric("reset", s.Reset-r.prev.Reset, "c")
+	r.metric("grew", s.Grew-r.prev.Grew, "c")
+	r.metric("late", s.Late-r.prev.Late, "c")
+	if n := s.Drift.Count() - r.prev.Drift.Count(); n > 0 {
+		mean := (s.Drift.Sum - r.prev.Drift.Sum) / time.Duration(n)
+		r.buf.WriteString(r.prefix)
+		r.buf.WriteString("drift:")
+		r.buf.WriteString(strconv.FormatFloat(mean.Seconds()*1000, 'f', -1, 64))
+		r.buf.WriteString("|ms")
+		r.buf.WriteString(r.tags)
+		r.buf.WriteByte('\n')
+	}
+	r.prev = s
+
+	_, err := r.w.Write(bytes.TrimSuffix(r.buf.Bytes(), []byte{'\n'}))
+	return err
+}
+
+func (r *Reporter) metric(name string, value uint64, kind string) {
+	r.buf.WriteString(r.prefix)
+	r.buf.WriteString(name)
+	r.buf.WriteByte(':')
+	r.buf.WriteString(strconv.FormatUint(value, 10))
+	r.buf.WriteByte('|')
+	r.buf.WriteString(kind)
+	r.buf.WriteString(r.tags)
+	r.buf.WriteByte('\n')
+}
+
+// Start calls Report every interval in it's own Go routine until Stop is
+// called. Errors from Report are ignored, as is normal for statsd over UDP.
+func (r *Reporter) Start(interval time.Duration) {
+	stop := make(chan struct{})
+	r.mux.Lock()
+	if r.stop != nil {
+		close(r.stop)
+	}
+	r.stop = stop
+	r.mux.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.Report()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends reporting started by Start.
+func (r *Reporter) Stop() {
+	r.mux.Lock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	r.mux.Unlock()
+}
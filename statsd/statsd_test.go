@@ -0,0 +1,60 @@
+package statsd_test
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/statsd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	tq := timeoutqueue.New(time.Second, 10)
+	buf := &bytes.Buffer{}
+	r := statsd.New(tq, buf, "tq", "env:test")
+
+	tq.Add(func() {})
+	tq.Add(func() {}).Cancel()
+	assert.NoError(t, r.Report())
+	assert.Equal(t, strings.Join([]string{
+		"tq.pending:1|g|#env:test",
+		"tq.added:2|c|#env:test",
+		"tq.fired:0|c|#env:test",
+		"tq.canceled:1|c|#env:test",
+		"tq.reset:0|c|#env:test",
+		"tq.grew:0|c|#env:test",
+		"tq.late:0|c|#env:test",
+	}, "\n"), buf.String())
+
+	buf.Reset()
+	tq.Add(func() {})
+	assert.NoError(t, r.Report())
+	assert.Contains(t, buf.String(), "tq.added:1|c|#env:test\n")
+	tq.Flush()
+}
+
+func TestDial(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	tq := timeoutqueue.New(time.Second, 10)
+	r, err := statsd.Dial(tq, conn.LocalAddr().String(), "tq")
+	if !assert.NoError(t, err) {
+		return
+	}
+	r.Start(time.Millisecond)
+	defer r.Stop()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(buf[:n]), "tq.pending:0|g\n"))
+}
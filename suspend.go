@@ -0,0 +1,42 @@
+package timeoutqueue
+
+// Suspend freezes the queue's notion of time. Nothing fires while the queue is
+// suspended and every action keeps the duration it had remaining; actions added
+// or reset while suspended get the full timeout from the moment of suspension.
+// This is different from a stall, after which actions are overdue, and is
+// intended for uses like checkpoint and restore. The returned bool is false if
+// the queue was already suspended.
+func (tq *TimeoutQueue) Suspend() bool {
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	if tq.suspended {
+		return false
+	}
+	tq.suspendedAt = tq.now()
+	tq.suspended = true
+	return true
+}
+
+// Resume a suspended queue, moving every deadline forward by the time it was
+// suspended so the remaining durations are measured from now. The returned bool
+// is false if the queue was not suspended.
+func (tq *TimeoutQueue) Resume() bool {
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	if !tq.suspended {
+		return false
+	}
+	tq.suspended = false
+	tq.shift += tq.refreshNow().Sub(tq.suspendedAt)
+	tq.publishNext()
+	tq.rearm()
+	return true
+}
+
+// Suspended returns true if the queue is suspended.
+func (tq *TimeoutQueue) Suspended() bool {
+	tq.mux.Lock()
+	suspended := tq.suspended
+	tq.mux.Unlock()
+	return suspended
+}
@@ -0,0 +1,26 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuspendRunner(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*10, 10)
+	ch := make(chan int, 1)
+	tq.Add(func() { ch <- 1 })
+	tq.Suspend()
+	time.Sleep(time.Millisecond * 30)
+	assert.Len(t, ch, 0)
+	tq.Resume()
+	select {
+	case <-ch:
+	case <-time.After(time.Millisecond * 200):
+		t.Error("action did not fire after Resume")
+	}
+}
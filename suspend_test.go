@@ -0,0 +1,30 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuspend(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	var fired []int
+	q.Add(func() { fired = append(fired, 1) })
+	q.Advance(time.Millisecond * 600)
+
+	assert.False(t, q.Resume())
+	assert.True(t, q.Suspend())
+	assert.False(t, q.Suspend())
+	assert.True(t, q.Suspended())
+	q.Add(func() { fired = append(fired, 2) })
+	assert.Equal(t, 0, q.Advance(time.Hour))
+
+	assert.True(t, q.Resume())
+	assert.False(t, q.Suspended())
+	assert.Equal(t, 0, q.Advance(time.Millisecond*300))
+	assert.Equal(t, 1, q.Advance(time.Millisecond*100))
+	assert.Equal(t, 1, q.Advance(time.Millisecond*600))
+	assert.Equal(t, []int{1, 2}, fired)
+}
@@ -0,0 +1,57 @@
+//go:build go1.25 && !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSynctest(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		tq := timeoutqueue.New(time.Hour, 10)
+		start := time.Now()
+		var fired []time.Duration
+		tq.Add(func() { fired = append(fired, time.Since(start)) })
+		tkn := tq.Add(func() { fired = append(fired, time.Since(start)) })
+		time.Sleep(time.Minute * 30)
+		tkn.Reset()
+		tq.SetTimeout(time.Minute)
+
+		time.Sleep(time.Hour * 2)
+		synctest.Wait()
+		// the first was already past it's new deadline when the timeout changed
+		assert.Equal(t, []time.Duration{time.Minute * 30, time.Minute * 31}, fired)
+
+		tq.SetLinger(time.Hour)
+		tq.Add(func() {})
+		tq.Close()
+		assert.Equal(t, 0, tq.Goroutines())
+	})
+}
+
+func TestSynctestShared(t *testing.T) {
+	// a queue outlives the bubbles it is used in
+	tq := timeoutqueue.New(time.Second, 10)
+	for i := 0; i < 2; i++ {
+		synctest.Test(t, func(t *testing.T) {
+			ch := make(chan int, 1)
+			tq.Add(func() { ch <- 1 })
+			time.Sleep(time.Second)
+			synctest.Wait()
+			assert.Len(t, ch, 1)
+		})
+	}
+	ch := make(chan int, 1)
+	tq.SetTimeout(time.Millisecond)
+	tq.Add(func() { ch <- 1 })
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("action did not fire outside the bubble")
+	}
+}
@@ -0,0 +1,125 @@
+package timeoutqueue
+
+import (
+	"errors"
+)
+
+// ErrTags is returned by AddTagged for a new tag once the queue has MaxTags.
+var ErrTags = errors.New("timeoutqueue: too many tags")
+
+// MaxTags is the most distinct tags a queue can track.
+const MaxTags = 1<<16 - 1
+
+// TagStats are the counters of a single tag, see AddTagged.
+type TagStats struct {
+	Pending  int
+	Added    uint64
+	Fired    uint64
+	Canceled uint64
+	Reset    uint64
+	Late     uint64
+}
+
+func (ts *TagStats) count(et EventType) {
+	switch et {
+	case EventFired:
+		ts.Fired++
+	case EventCanceled:
+		ts.Canceled++
+	case EventReset:
+		ts.Reset++
+	case EventLate:
+		ts.Late++
+	}
+}
+
+type tags struct {
+	ids   map[interface{}]uint16
+	names []interface{}
+	stats []TagStats
+}
+
+// AddTagged adds a TimeoutAction with a tag, usually a short string or an int
+// naming the subsystem that added it, so subsystems sharing one queue can be
+// measured with StatsByTag and torn down with CancelByTag. The tag must be
+// comparable and is kept for the life of the queue. If the tag is new and the
+// queue already has MaxTags tags, the action is not added and ErrTags is
+// returned.
+func (tq *TimeoutQueue) AddTagged(tag interface{}, action TimeoutAction) (Token, error) {
+	tq.mux.Lock()
+	if tq.tags == nil {
+		tq.tags = &tags{
+			ids: make(map[interface{}]uint16),
+		}
+	}
+	ts := tq.tags
+	idx, ok := ts.ids[tag]
+	if !ok {
+		if len(ts.names) == MaxTags {
+			tq.mux.Unlock()
+			return nil, ErrTags
+		}
+		ts.names = append(ts.names, tag)
+		ts.stats = append(ts.stats, TagStats{})
+		idx = uint16(len(ts.names))
+		ts.ids[tag] = idx
+	}
+	ts.stats[idx-1].Added++
+	if tq.timeout <= 0 || tq.closed || (tq.fixed && tq.free == empty) {
+		return tq.addNode(action, tq.hookID(), 0, false), nil
+	}
+	t := tq.insert(action, tq.hookID(), tq.deadlineAfter(tq.timeout))
+	tq.nodes[t.nodeIdx].tag = idx
+	ts.stats[idx-1].Pending++
+	tq.rearm()
+	tq.mux.Unlock()
+	return t, nil
+}
+
+// StatsByTag returns the counters for every tag the queue has seen. Actions
+// that are dispatched immediately, or do not fit in a full queue from
+// NewFixed, are counted as added but are not otherwise tracked.
+func (tq *TimeoutQueue) StatsByTag() map[interface{}]TagStats {
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	out := make(map[interface{}]TagStats)
+	if tq.tags == nil {
+		return out
+	}
+	for i, tag := range tq.tags.names {
+		out[tag] = tq.tags.stats[i]
+	}
+	return out
+}
+
+// CancelByTag cancels everything in the queue with the tag, including paused
+// actions, and returns the number of TimeoutActions canceled.
+func (tq *TimeoutQueue) CancelByTag(tag interface{}) int {
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	if tq.tags == nil {
+		return 0
+	}
+	idx, ok := tq.tags.ids[tag]
+	if !ok {
+		return 0
+	}
+	var canceled int
+	for cur := tq.head; cur != empty; {
+		next := tq.nodes[cur].next
+		if tq.nodes[cur].tag == idx {
+			tq.emit(EventCanceled, cur)
+			tq.freeNode(cur)
+			canceled++
+		}
+		cur = next
+	}
+	for cur := range tq.paused {
+		if tq.nodes[cur].tag == idx {
+			tq.emit(EventCanceled, cur)
+			tq.freeNode(cur)
+			canceled++
+		}
+	}
+	return canceled
+}
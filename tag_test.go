@@ -0,0 +1,56 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTags(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	nop := func() {}
+	hs, err := q.AddTagged("handshake", nop)
+	assert.NoError(t, err)
+	q.AddTagged("handshake", nop)
+	session, _ := q.AddTagged("session", nop)
+	session.Pause()
+	q.AddTagged("session", nop)
+	q.AddTagged(1, nop)
+	q.Add(nop)
+
+	assert.True(t, hs.Reset())
+	assert.Equal(t, 2, q.CancelByTag("session"))
+	assert.Equal(t, 0, q.CancelByTag("session"))
+	assert.Equal(t, 0, q.CancelByTag("none"))
+	assert.Equal(t, 4, q.Advance(time.Second))
+
+	assert.Equal(t, map[interface{}]timeoutqueue.TagStats{
+		"handshake": {Added: 2, Fired: 2, Reset: 1},
+		"session":   {Added: 2, Canceled: 2},
+		1:           {Added: 1, Fired: 1},
+	}, q.StatsByTag())
+	assert.NoError(t, q.Validate())
+}
+
+func TestTooManyTags(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	nop := func() {}
+	for i := 0; i < timeoutqueue.MaxTags; i++ {
+		tkn, err := q.AddTagged(i, nop)
+		assert.NoError(t, err)
+		tkn.Cancel()
+	}
+	tkn, err := q.AddTagged("one more", nop)
+	assert.Nil(t, tkn)
+	assert.Equal(t, timeoutqueue.ErrTags, err)
+	assert.Equal(t, 0, q.Len())
+
+	// tags the queue already has can still be used
+	tkn, err = q.AddTagged(1, nop)
+	assert.NoError(t, err)
+	assert.True(t, tkn.Cancel())
+	assert.Len(t, q.StatsByTag(), timeoutqueue.MaxTags)
+}
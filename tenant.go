@@ -0,0 +1,183 @@
+package timeoutqueue
+
+import (
+	"errors"
+)
+
+// Errors returned by AddForTenant.
+var (
+	ErrQuota   = errors.New("timeoutqueue: tenant is at it's limit")
+	ErrTenants = errors.New("timeoutqueue: too many tenants")
+)
+
+// MaxTenants is the most distinct tenants a queue can track.
+const MaxTenants = 1<<16 - 1
+
+// TenantStats are the counters of a single tenant, see AddForTenant.
+type TenantStats struct {
+	// Limit is the most TimeoutActions the tenant may have pending, zero is no
+	// limit.
+	Limit    int
+	Pending  int
+	Added    uint64
+	Fired    uint64
+	Canceled uint64
+	Reset    uint64
+	Late     uint64
+	// Rejected counts the actions not added because the tenant was at it's
+	// limit.
+	Rejected uint64
+}
+
+type tenant struct {
+	TenantStats
+	id interface{}
+	// limited is true once SetTenantLimit is called for the tenant, after which
+	// the default no longer applies
+	limited bool
+}
+
+func (tn *tenant) count(et EventType) {
+	switch et {
+	case EventFired:
+		tn.Fired++
+	case EventCanceled:
+		tn.Canceled++
+	case EventReset:
+		tn.Reset++
+	case EventLate:
+		tn.Late++
+	}
+}
+
+type tenants struct {
+	ids  map[interface{}]uint16
+	list []*tenant
+	// limit applies to every tenant without it's own
+	limit int
+}
+
+// tenantIdx requires a mux lock. It returns the index of the tenant for id plus
+// one, creating the tenant if needed.
+func (tq *TimeoutQueue) tenantIdx(id interface{}) (uint16, error) {
+	if tq.tenants == nil {
+		tq.tenants = &tenants{
+			ids: make(map[interface{}]uint16),
+		}
+	}
+	ts := tq.tenants
+	if idx, ok := ts.ids[id]; ok {
+		return idx, nil
+	}
+	if len(ts.list) == MaxTenants {
+		return 0, ErrTenants
+	}
+	tn := &tenant{id: id}
+	tn.Limit = ts.limit
+	ts.list = append(ts.list, tn)
+	idx := uint16(len(ts.list))
+	ts.ids[id] = idx
+	return idx, nil
+}
+
+// AddForTenant adds a TimeoutAction on behalf of a tenant, such as a peer or a
+// customer, so one tenant can not fill a shared queue. If the tenant already
+// has as many actions pending as it's limit the action is not added and
+// ErrQuota is returned. The tenant must be comparable; it is kept for the life
+// of the queue so it's counters can be read with TenantStats. Actions that are
+// dispatched immediately, or do not fit in a full queue from NewFixed, are
+// counted as added but are not otherwise tracked.
+func (tq *TimeoutQueue) AddForTenant(tenant interface{}, action TimeoutAction) (Token, error) {
+	tq.mux.Lock()
+	idx, err := tq.tenantIdx(tenant)
+	if err != nil {
+		tq.mux.Unlock()
+		return nil, err
+	}
+	tn := tq.tenants.list[idx-1]
+	if tn.Limit > 0 && tn.Pending >= tn.Limit {
+		tn.Rejected++
+		tq.emitID(EventRejected, tenant)
+		tq.mux.Unlock()
+		return nil, ErrQuota
+	}
+	tn.Added++
+	if tq.timeout <= 0 || tq.closed || (tq.fixed && tq.free == empty) {
+		return tq.addNode(action, tq.hookID(), 0, false), nil
+	}
+	t := tq.insert(action, tq.hookID(), tq.deadlineAfter(tq.timeout))
+	tq.nodes[t.nodeIdx].tenant = idx
+	tn.Pending++
+	tq.rearm()
+	tq.mux.Unlock()
+	return t, nil
+}
+
+// SetTenantLimit sets how many TimeoutActions the tenant may have pending. A
+// limit of zero or less removes the limit.
+func (tq *TimeoutQueue) SetTenantLimit(tenant interface{}, limit int) error {
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	idx, err := tq.tenantIdx(tenant)
+	if err != nil {
+		return err
+	}
+	tn := tq.tenants.list[idx-1]
+	tn.limited = true
+	if limit < 0 {
+		limit = 0
+	}
+	tn.Limit = limit
+	return nil
+}
+
+// SetDefaultTenantLimit sets the limit for every tenant that has not had one
+// set by SetTenantLimit, including those added in future.
+func (tq *TimeoutQueue) SetDefaultTenantLimit(limit int) {
+	if limit < 0 {
+		limit = 0
+	}
+	tq.mux.Lock()
+	if tq.tenants == nil {
+		tq.tenants = &tenants{
+			ids: make(map[interface{}]uint16),
+		}
+	}
+	tq.tenants.limit = limit
+	for _, tn := range tq.tenants.list {
+		if !tn.limited {
+			tn.Limit = tq.tenants.limit
+		}
+	}
+	tq.mux.Unlock()
+}
+
+// TenantStats returns the counters for a tenant. The returned bool is false if
+// the queue has never seen the tenant.
+func (tq *TimeoutQueue) TenantStats(tenant interface{}) (TenantStats, bool) {
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	if tq.tenants == nil {
+		return TenantStats{}, false
+	}
+	idx, ok := tq.tenants.ids[tenant]
+	if !ok {
+		return TenantStats{}, false
+	}
+	return tq.tenants.list[idx-1].TenantStats, true
+}
+
+// Tenants returns every tenant the queue has seen, in the order they were first
+// seen.
+func (tq *TimeoutQueue) Tenants() []interface{} {
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	if tq.tenants == nil {
+		return nil
+	}
+	out := make([]interface{}, len(tq.tenants.list))
+	for i, tn := range tq.tenants.list {
+		out[i] = tn.id
+	}
+	return out
+}
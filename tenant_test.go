@@ -0,0 +1,55 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenants(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	q.SetDefaultTenantLimit(2)
+	assert.NoError(t, q.SetTenantLimit("big", 0))
+	nop := func() {}
+
+	a, err := q.AddForTenant("small", nop)
+	assert.NoError(t, err)
+	_, err = q.AddForTenant("small", nop)
+	assert.NoError(t, err)
+	_, err = q.AddForTenant("small", nop)
+	assert.Equal(t, timeoutqueue.ErrQuota, err)
+	for i := 0; i < 3; i++ {
+		_, err = q.AddForTenant("big", nop)
+		assert.NoError(t, err)
+	}
+
+	assert.True(t, a.Reset())
+	assert.True(t, a.Cancel())
+	_, err = q.AddForTenant("small", nop)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, q.Advance(time.Second))
+
+	small, ok := q.TenantStats("small")
+	assert.True(t, ok)
+	assert.Equal(t, timeoutqueue.TenantStats{
+		Limit:    2,
+		Pending:  0,
+		Added:    3,
+		Fired:    2,
+		Canceled: 1,
+		Reset:    1,
+		Rejected: 1,
+	}, small)
+	big, _ := q.TenantStats("big")
+	assert.Equal(t, uint64(3), big.Fired)
+	assert.Equal(t, 0, big.Pending)
+	assert.Equal(t, 0, big.Limit)
+
+	_, ok = q.TenantStats("none")
+	assert.False(t, ok)
+	assert.Equal(t, []interface{}{"big", "small"}, q.Tenants())
+	assert.NoError(t, q.Validate())
+}
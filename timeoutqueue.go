@@ -2,82 +2,338 @@
 // constant period of time. It generates almost no garbage (only when it has to
 // grow it's internal slice). It is threadsafe. It runs a Go routine only when
 // there are timeout actions in the queue.
+//
+// The runner only sleeps on timers and channels and reads the time with
+// time.Now, so a queue created inside a testing/synctest bubble runs on the
+// bubble's virtual time and a test can sleep past any timeout instantly.
+//
+// For WASM and other targets without threads, building with the
+// timeoutqueuesingle tag drops the mutex and never starts a Go routine. Every
+// queue behaves as if it came from NewManual and must be driven by calling Tick,
+// and actions are called directly when no Executor is set. The queue must then
+// only be used from one Go routine. The RetryScheduler, NATKeepalive,
+// PTOManager and WindowCounter, and the scheduler package, keep queues of their
+// own that nothing ticks, so they do not fire with the tag. CancelWhenCollected
+// and the parent cancellation of WithTimeoutCtx call the queue from Go routines
+// the runtime starts, so they are not safe to use with the tag.
 package timeoutqueue
 
 import (
+	"errors"
 	"sync"
 	"time"
 )
 
 // TimeoutAction is what is called when a timeout occures. It will be called in
-// it's own Go routine unless it is invoked from Flush.
+// it's own Go routine unless it is invoked from Flush or an Executor is set. An
+// action may call methods on the queue that fired it, so it can add itself again,
+// reset it's own Token or cancel others; only Close may not be called from an
+// action.
 type TimeoutAction func()
 
+// CorrelatedAction is called like a TimeoutAction but receives the correlation
+// ID it was added with.
+type CorrelatedAction func(id interface{})
+
+// TimedAction is called like a TimeoutAction but receives the time it was
+// scheduled to fire and the time it was actually fired, so it can decide how
+// to behave if it is running late.
+type TimedAction func(scheduled, fired time.Time)
+
 const empty = ^uint32(0)
 
+// MaxCapacity is the largest number of TimeoutActions a queue can hold.
+const MaxCapacity = 1<<32 - 2
+
+// Errors returned by NewChecked.
+var (
+	ErrNegativeTimeout  = errors.New("timeoutqueue: negative timeout")
+	ErrNegativeCapacity = errors.New("timeoutqueue: negative capacity")
+	ErrCapacity         = errors.New("timeoutqueue: capacity exceeds MaxCapacity")
+)
+
 type node struct {
 	next, prev uint32
-	timeout    time.Time
+	// timeout is the deadline less the queue's shift while the node is in the
+	// list, see deadline
+	timeout time.Time
 	// actionID is incremented each time the node is reused to prevent a previous
 	// cancel from working on a later action
 	actionID uint32
-	action   TimeoutAction
+	// pinned nodes are not moved by SetTimeout
+	pinned bool
+	// class is the duration class, see NewClasses
+	class uint8
+	// tenant is one more than the index of the node's tenant or zero, see
+	// AddForTenant
+	tenant uint16
+	// inflight counts the actions fired from the node that have not returned,
+	// see CancelAndWait
+	inflight uint32
+	// tag is one more than the index of the node's tag or zero, see AddTagged
+	tag uint16
+	// action is a TimeoutAction, CorrelatedAction, TimedAction, ReasonAction or
+	// Expirable
+	action interface{}
+	id     interface{}
+}
+
+func (n node) call(fired time.Time, reason Reason) {
+	switch action := n.action.(type) {
+	case TimeoutAction:
+		action()
+	case CorrelatedAction:
+		action(n.id)
+	case TimedAction:
+		action(n.timeout, fired)
+	case ReasonAction:
+		action(reason)
+	case Expirable:
+		action.OnTimeout()
+	}
 }
 
 // TimeoutQueue manages a queue of TimeoutActions that may be canceled before
 // they timeout. The timeout duration is constant within a queue.
 type TimeoutQueue struct {
+	// counters are first to guarentee 64 bit alignment for atomic operations,
+	// see Stats
+	counters [eventTypes]uint64
+	drift    histogram
+	// executing is the number of fired actions running and maxExecuting the
+	// most there have been at once
+	executing    uint64
+	maxExecuting uint64
+	// goroutines is the number of Go routines started by the queue, see
+	// Goroutines
+	goroutines int64
+	// next is the deadline of the head in Unix nanoseconds or zero if the list
+	// is empty, see NextDeadline
+	next    int64
 	timeout time.Duration
 	running uint16
+	// sleepUntil is when the runner will next wake, see rearm. It is zero
+	// while the runner is awake.
+	sleepUntil time.Time
 	// nodes in use form a doubly linked list
 	head uint32
 	tail uint32
 	// free nodes form a singly linked list
-	free  uint32
-	nodes []node
-	mux   sync.Mutex
+	free       uint32
+	nodes      []node
+	subs       []*Subscription
+	hook       func() interface{}
+	rebind     func(id interface{}) CorrelatedAction
+	dispatcher dispatcher
+	// coarse clock, see SetCoarseClock
+	coarse    time.Duration
+	cachedNow time.Time
+	clock     Clock
+	manual    bool
+	// afterFunc is set by NewAfterFunc and timer is it's timer
+	afterFunc bool
+	timer     *time.Timer
+	// late policy, see SetLateThreshold
+	lateThreshold time.Duration
+	// pending is the number of nodes in use, see SetThreshold
+	pending int
+	// pinned is the number of nodes in use that are pinned, see AddPinned
+	pinned int
+	// shift is added to the timeout of every node in the list to get it's
+	// deadline, so moving every deadline is constant time
+	shift    time.Duration
+	pressure pressure
+	closed   bool
+	// suspended is set between Suspend and Resume
+	suspended   bool
+	suspendedAt time.Time
+	// discontinuity policy, see SetDiscontinuityPolicy
+	discontinuity discontinuity
+	// adaptive timeout, see SetAdaptive
+	adaptive adaptive
+	// resolution deadlines are rounded up to, see SetResolution
+	resolution time.Duration
+	// classes are the fixed durations of classes 1 and up and classTail the
+	// last node inserted for every class, see NewClasses
+	classes   []time.Duration
+	classTail []uint32
+	// linger is how long the runner waits for more once the queue is empty
+	linger time.Duration
+	// fixed queues never grow, when they are full the full policy applies,
+	// see NewFixed
+	fixed bool
+	full  FullPolicy
+	// listenerID is the ID of the last FireListener added
+	listenerID uint64
+	// tenants are created by AddForTenant
+	tenants *tenants
+	// tags are created by AddTagged
+	tags *tags
+	// paused is the remaining duration of every paused node, see Pause
+	paused map[uint32]time.Duration
+	// blackouts are the windows in which nothing fires, sorted by start, and
+	// lastBlackout is the end of the last one that held anything back, see
+	// AddBlackout
+	blackouts    []blackout
+	lastBlackout time.Time
+	// audit holds the most recent Events, see SetAuditSize
+	audit eventRing
+	// landing is created by the first CancelAndWait and is signaled when the
+	// last in flight action from a node returns
+	landing *sync.Cond
+	// wake is closed to interrupt sleeping runners and exited is signaled
+	// when the last Go routine started by the queue returns, see Close
+	wake   chan struct{}
+	exited *sync.Cond
+	mux    mutex
 }
 
 // New returns a TimeoutQueue. This is the point at which timeout is set and
 // cannot be changed. The capacity determines the capacity of the internal
 // slice. The queue will grow in size as need, but will not shrink. Providing
 // enough initial capacity will reduce the copy cost of growing the internal
-// slice.
+// slice. A negative capacity is treated as zero. A timeout of zero or less
+// dispatches actions immediately, see Timeout.
 func New(timeout time.Duration, capacity int) *TimeoutQueue {
+	if capacity < 0 {
+		capacity = 0
+	}
 	return &TimeoutQueue{
 		timeout: timeout,
 		head:    empty,
 		tail:    empty,
 		free:    empty,
 		nodes:   make([]node, 0, capacity),
+		manual:  single,
 	}
 }
 
-func (tq *TimeoutQueue) run(id uint16) {
-	if id == 1 {
-		time.Sleep(tq.timeout)
+// NewChecked returns a TimeoutQueue like New but validates the arguments rather
+// than adjusting them. Immediate dispatch can still be used by calling New or
+// SetTimeout with a timeout of zero.
+func NewChecked(timeout time.Duration, capacity int) (*TimeoutQueue, error) {
+	if timeout < 0 {
+		return nil, ErrNegativeTimeout
+	}
+	if capacity < 0 {
+		return nil, ErrNegativeCapacity
+	}
+	if uint64(capacity) > MaxCapacity {
+		return nil, ErrCapacity
+	}
+	return New(timeout, capacity), nil
+}
+
+// SetCapacity grows the queue so it can hold capacity TimeoutActions without
+// growing again, so the copy happens at a moment of the caller's choosing rather
+// than while adding under load. It is the only way a queue from NewFixed grows.
+// The queue never shrinks, so a capacity no greater than the current one does
+// nothing. It returns ErrNegativeCapacity or ErrCapacity for a capacity it can
+// not hold.
+func (tq *TimeoutQueue) SetCapacity(capacity int) error {
+	if capacity < 0 {
+		return ErrNegativeCapacity
 	}
+	if uint64(capacity) > MaxCapacity {
+		return ErrCapacity
+	}
+	tq.mux.Lock()
+	if capacity > cap(tq.nodes) {
+		nodes := make([]node, len(tq.nodes), capacity)
+		copy(nodes, tq.nodes)
+		tq.nodes = nodes
+		tq.emit(EventGrew, empty)
+		if tq.fixed {
+			tq.linkFree(capacity)
+		}
+	}
+	tq.mux.Unlock()
+	return nil
+}
+
+// Cap returns the number of TimeoutActions the queue can hold before it grows.
+func (tq *TimeoutQueue) Cap() int {
+	tq.mux.Lock()
+	c := cap(tq.nodes)
+	tq.mux.Unlock()
+	return c
+}
+
+func (tq *TimeoutQueue) run(id uint16) {
+	// woke is when the runner expected to wake from it's last sleep
+	var woke time.Time
+	// lingerUntil is when the runner will exit if the queue stays empty
+	var lingerUntil time.Time
 	for {
 		tq.mux.Lock()
 		if id != tq.running {
-			// another thread has taken over
+			// the queue was closed while the runner was asleep
+			tq.runnerExited()
+			tq.mux.Unlock()
 			return
 		}
-		if tq.head == empty {
+		tq.sleepUntil = time.Time{}
+		now := tq.refreshNow()
+		if !woke.IsZero() {
+			drop := tq.checkDiscontinuity(now.Sub(woke), now)
+			woke = time.Time{}
+			if drop != nil {
+				tq.mux.Unlock()
+				drop()
+				continue
+			}
+		}
+		if tq.head == empty || tq.suspended {
+			if d := tq.lingerFor(now, &lingerUntil); d > 0 {
+				woke = now.Add(d)
+				tq.sleepUntil = woke
+				tq.sleep(d)
+				continue
+			}
 			tq.running = 0
+			tq.runnerExited()
 			tq.mux.Unlock()
 			return
 		}
-		n := tq.nodes[tq.head]
-		if d := n.timeout.Sub(time.Now()); d > 0 {
-			tq.mux.Unlock()
-			time.Sleep(d)
+		lingerUntil = time.Time{}
+		d := tq.deadline(tq.head).Sub(now)
+		if d <= 0 {
+			if end := tq.blackoutEnd(now); !end.IsZero() {
+				d = end.Sub(now)
+			}
+		}
+		if d > 0 {
+			d = tq.sleepFor(d)
+			woke = now.Add(d)
+			tq.sleepUntil = woke
+			tq.sleep(d)
 			continue
 		}
-		tq.freeNode(tq.head)
+		tq.fire(-d)
+	}
+}
+
+// fire requires a mux lock and will unlock it when done. It removes the head of
+// the list, which is late by the given duration, and dispatches it. The
+// returned bool indicates if the TimeoutAction was called.
+func (tq *TimeoutQueue) fire(late time.Duration) bool {
+	idx := tq.head
+	n := tq.nodeAt(idx)
+	d := tq.dispatcher
+	tq.drift.record(late)
+	if tq.lateThreshold > 0 && late-tq.excused(n.timeout) > tq.lateThreshold {
+		tq.emit(EventLate, idx)
+		tq.freeNode(idx)
 		tq.mux.Unlock()
-		go n.action()
+		d.dispatchLate(n.id, late)
+		return false
 	}
+	tq.emit(EventFired, idx)
+	tq.freeNode(idx)
+	tq.nodes[idx].inflight++
+	tq.mux.Unlock()
+	d.dispatchFired(tq, idx, n, n.timeout.Add(late), ReasonTimeout)
+	return true
 }
 
 /* IMPORTANT NOTE */
@@ -87,6 +343,7 @@ func (tq *TimeoutQueue) run(id uint16) {
 func (tq *TimeoutQueue) add(nodeIdx uint32) {
 	if tq.head == empty {
 		tq.head = nodeIdx
+		tq.publishNext()
 	} else {
 		tq.nodes[tq.tail].next = nodeIdx
 	}
@@ -97,6 +354,7 @@ func (tq *TimeoutQueue) remove(nodeIdx uint32) {
 	n := tq.nodes[nodeIdx]
 	if n.prev == empty {
 		tq.head = n.next
+		tq.publishNext()
 	} else {
 		tq.nodes[n.prev].next = n.next
 	}
@@ -108,139 +366,623 @@ func (tq *TimeoutQueue) remove(nodeIdx uint32) {
 }
 
 func (tq *TimeoutQueue) freeNode(nodeIdx uint32) {
-	tq.remove(nodeIdx)
+	if tq.isPaused(nodeIdx) {
+		delete(tq.paused, nodeIdx)
+	} else {
+		tq.remove(nodeIdx)
+	}
 	tq.nodes[nodeIdx].next = tq.free
 	tq.nodes[nodeIdx].actionID++
+	if tq.nodes[nodeIdx].pinned {
+		tq.nodes[nodeIdx].pinned = false
+		tq.pinned--
+	}
+	if tq.nodes[nodeIdx].tenant != 0 {
+		tq.tenants.list[tq.nodes[nodeIdx].tenant-1].Pending--
+		tq.nodes[nodeIdx].tenant = 0
+	}
+	if tq.nodes[nodeIdx].tag != 0 {
+		tq.tags.stats[tq.nodes[nodeIdx].tag-1].Pending--
+		tq.nodes[nodeIdx].tag = 0
+	}
 	tq.nodes[nodeIdx].action = nil
+	tq.nodes[nodeIdx].id = nil
 	tq.free = nodeIdx
+	tq.pending--
+	tq.debugValidate()
+	tq.checkPressure()
 }
 
 // Add takes a TimeoutAction and adds it to the queue. The TimeoutAction will be
 // called after the TimeoutQueue's timeout duration unless modified by a Token
 // method.
 func (tq *TimeoutQueue) Add(action TimeoutAction) Token {
-	timeout := time.Now().Add(tq.timeout)
-	t := token{
-		tq: tq,
+	tq.mux.Lock()
+	return tq.addAction(action, tq.hookID())
+}
+
+// AddHandle adds a TimeoutAction to the queue the same as Add, but returns the
+// concrete Handle. Storing a Handle rather than a Token avoids the allocation
+// from converting it to an interface, which matters on hot paths.
+func (tq *TimeoutQueue) AddHandle(action TimeoutAction) Handle {
+	tq.mux.Lock()
+	return tq.addAction(action, tq.hookID())
+}
+
+// AddInto adds a TimeoutAction to the queue and stores it's Handle in h, so a
+// long lived struct can keep reusing the same Handle field. If h still refers
+// to a pending action in tq, that action is canceled under the same lock, so
+// rescheduling never leaves the old action behind. It takes a *Handle rather
+// than a *Token as storing into a Token would allocate.
+func (tq *TimeoutQueue) AddInto(h *Handle, action TimeoutAction) {
+	if h.tq != nil && h.tq != tq {
+		h.Cancel()
+	}
+	tq.mux.Lock()
+	if h.tq == tq {
+		n := tq.nodes[h.nodeIdx]
+		if n.action != nil && n.actionID == h.actionID {
+			tq.emit(EventCanceled, h.nodeIdx)
+			tq.freeNode(h.nodeIdx)
+		}
+	}
+	*h = tq.addAction(action, tq.hookID())
+}
+
+// AddTimed adds a TimedAction to the queue.
+func (tq *TimeoutQueue) AddTimed(action TimedAction) Token {
+	tq.mux.Lock()
+	return tq.addAction(action, tq.hookID())
+}
+
+// AddWithID adds a CorrelatedAction to the queue. The id is passed to the action
+// when it is called and is included in any Events for it. This allows timeout
+// driven work to be tied back to the request that caused it.
+func (tq *TimeoutQueue) AddWithID(id interface{}, action CorrelatedAction) Token {
+	tq.mux.Lock()
+	return tq.addAction(action, id)
+}
+
+// SetCorrelationHook sets a hook that is called by Add to capture a correlation
+// ID, for instance from goroutine local state. The hook is called with the queue
+// locked, so it may not call methods on the queue or its Tokens. Passing nil
+// removes the hook.
+func (tq *TimeoutQueue) SetCorrelationHook(hook func() interface{}) {
+	tq.mux.Lock()
+	tq.hook = hook
+	tq.mux.Unlock()
+}
+
+// hookID requires a mux lock. It returns the correlation ID from the hook, if
+// one is set.
+func (tq *TimeoutQueue) hookID() interface{} {
+	if tq.hook == nil {
+		return nil
 	}
+	return tq.hook()
+}
+
+// addAction requires a mux lock and will unlock it when done.
+func (tq *TimeoutQueue) addAction(action, id interface{}) Handle {
+	return tq.addNode(action, id, 0, false)
+}
 
+// addNode requires a mux lock and will unlock it when done. A pinned node is
+// not moved by SetTimeout, nor is any node with a class other than 0.
+func (tq *TimeoutQueue) addNode(action, id interface{}, class uint8, pinned bool) Handle {
+	timeout := tq.classTimeout(class)
+	if timeout <= 0 || tq.closed {
+		// immediate dispatch, there is nothing to wait for so the action never
+		// enters the queue
+		tq.emitID(EventAdded, id)
+		tq.emitID(EventFired, id)
+		d, now, reason := tq.dispatcher, tq.clockNow(), tq.immediateReason()
+		tq.mux.Unlock()
+		d.dispatch(tq, node{action: action, id: id, timeout: now}, now, reason)
+		return Handle{}
+	}
+	if tq.fixed && tq.free == empty {
+		return tq.addFull(action, id, class, pinned)
+	}
+	t := tq.insertClass(action, id, class, tq.deadlineAfter(timeout))
+	if pinned || class != 0 {
+		tq.pin(t.nodeIdx)
+	}
+	tq.rearm()
+	tq.mux.Unlock()
+	return t
+}
+
+// AddBatch adds all the actions to the queue under a single lock and with a
+// single timestamp, so they all share the same deadline. The Handles are
+// appended to handles in the same order as the actions, passing a slice with
+// enough capacity avoids any allocation. SetCoarseClock gives concurrent calls
+// to Add a similar saving.
+func (tq *TimeoutQueue) AddBatch(handles []Handle, actions ...TimeoutAction) []Handle {
 	tq.mux.Lock()
+	id := tq.hookID()
+	if tq.timeout <= 0 || tq.closed {
+		for range actions {
+			tq.emitID(EventAdded, id)
+			tq.emitID(EventFired, id)
+			handles = append(handles, Handle{})
+		}
+		d, now, reason := tq.dispatcher, tq.clockNow(), tq.immediateReason()
+		tq.mux.Unlock()
+		for _, action := range actions {
+			d.dispatch(tq, node{action: action, id: id, timeout: now}, now, reason)
+		}
+		return handles
+	}
+	timeout := tq.deadlineAfter(tq.timeout)
+	for _, action := range actions {
+		if tq.fixed && tq.free == empty {
+			tq.emitID(EventRejected, id)
+			handles = append(handles, Handle{})
+			continue
+		}
+		handles = append(handles, tq.insert(action, id, timeout))
+	}
+	if len(actions) > 0 {
+		tq.rearm()
+	}
+	tq.mux.Unlock()
+	return handles
+}
+
+// insert requires a mux lock. It places the action in a node in deadline
+// order, which is almost always the end of the list.
+func (tq *TimeoutQueue) insert(action, id interface{}, timeout time.Time) Handle {
+	return tq.insertClass(action, id, 0, timeout)
+}
+
+// insertClass requires a mux lock. It is the same as insert but sets the
+// node's class.
+func (tq *TimeoutQueue) insertClass(action, id interface{}, class uint8, timeout time.Time) Handle {
+	t := Handle{
+		tq: tq,
+	}
+	// the node stores the deadline less the shift, see deadline
+	timeout = timeout.Add(-tq.shift)
+
 	if tq.free == empty {
 		t.nodeIdx = uint32(len(tq.nodes))
+		grow := len(tq.nodes) == cap(tq.nodes)
 		tq.nodes = append(tq.nodes, node{
-			next:    empty,
-			prev:    tq.tail,
 			timeout: timeout,
+			class:   class,
 			action:  action,
+			id:      id,
 		})
+		if grow {
+			tq.emit(EventGrew, empty)
+		}
 	} else {
 		t.nodeIdx, tq.free = tq.free, tq.nodes[tq.free].next
-		tq.nodes[t.nodeIdx].next = empty
-		tq.nodes[t.nodeIdx].prev = tq.tail
 		tq.nodes[t.nodeIdx].timeout = timeout
+		tq.nodes[t.nodeIdx].class = class
 		tq.nodes[t.nodeIdx].action = action
+		tq.nodes[t.nodeIdx].id = id
 		t.actionID = tq.nodes[t.nodeIdx].actionID
 	}
-	tq.add(t.nodeIdx)
+	tq.linkClass(t.nodeIdx)
+	tq.emit(EventAdded, t.nodeIdx)
+	tq.pending++
+	tq.debugValidate()
+	tq.checkPressure()
+	return t
+}
+
+// link requires a mux lock. It links a node that is not in the list after the
+// last node that times out no later than it. Deadlines only go backwards after
+// SetTimeoutForNew decreases the timeout or with a coarse clock, so the search
+// from the tail is usually a single comparison.
+func (tq *TimeoutQueue) link(nodeIdx uint32) {
+	timeout := tq.nodes[nodeIdx].timeout
+	prev := tq.tail
+	for prev != empty && tq.nodes[prev].timeout.After(timeout) {
+		prev = tq.nodes[prev].prev
+	}
+	if prev == tq.tail {
+		tq.nodes[nodeIdx].next = empty
+		tq.nodes[nodeIdx].prev = tq.tail
+		tq.add(nodeIdx)
+		return
+	}
+	next := tq.head
+	if prev != empty {
+		next = tq.nodes[prev].next
+	}
+	tq.insertBefore(nodeIdx, next)
+}
+
+// insertBefore requires a mux lock. It links a node that is not in the list in
+// front of next.
+func (tq *TimeoutQueue) insertBefore(nodeIdx, next uint32) {
+	prev := tq.nodes[next].prev
+	tq.nodes[nodeIdx].prev = prev
+	tq.nodes[nodeIdx].next = next
+	tq.nodes[next].prev = nodeIdx
+	if prev == empty {
+		tq.head = nodeIdx
+		tq.publishNext()
+	} else {
+		tq.nodes[prev].next = nodeIdx
+	}
+}
+
+// rearm requires a mux lock. It is called after nodes are added to the list and
+// makes sure a runner will wake in time for the head, starting one if none is
+// running and waking one sleeping past the head's deadline.
+func (tq *TimeoutQueue) rearm() {
+	if tq.manual || tq.suspended || tq.head == empty {
+		return
+	}
+	if tq.afterFunc {
+		tq.arm()
+		return
+	}
 	if tq.running == 0 {
-		tq.running = 1
-		go tq.run(1)
+		tq.startRunner()
+	} else if tq.deadline(tq.head).Before(tq.sleepUntil) {
+		tq.wakeRunner()
 	}
-	tq.mux.Unlock()
+}
 
-	return t
+// startRunner requires a mux lock.
+func (tq *TimeoutQueue) startRunner() {
+	if tq.running == 0 && !tq.manual {
+		tq.running = 1
+		tq.goRun(1)
+	}
 }
 
-// Timeout duration before the TimeoutAction is called.
+// Timeout duration before the TimeoutAction is called. If the timeout is zero or
+// negative, actions are dispatched immediately when they are added and the
+// returned Token will always fail to Cancel or Reset.
 func (tq *TimeoutQueue) Timeout() time.Duration {
-	return tq.timeout
+	tq.mux.Lock()
+	timeout := tq.timeout
+	tq.mux.Unlock()
+	return timeout
 }
 
 // SetTimeout changes the timeout duration of the queue. Everything in the queue
 // will have it's timeout updated relative to when it was was added or reset. So
 // if the timeout is reset from 5ms to 10ms and there is a TimeoutAction in the
 // queueadded 3ms ago, it will go from expiring 2ms in the future to 7ms in the
-// future.
+// future. If the new timeout is zero or negative, everything in the queue will
+// be called on the next sweep. Actions added with AddPinned are not changed.
+// Moving the deadlines takes constant time, no matter how much is in the queue,
+// unless it holds pinned actions or actions in a class other than 0. Those are
+// put back in order in one pass over the queue, so SetTimeout then takes time
+// in proportion to everything in the queue. A shorter timeout wakes the runner
+// so it sleeps until the new head deadline; no new runner is started, so the
+// timeout can be tuned often.
 func (tq *TimeoutQueue) SetTimeout(timeout time.Duration) {
 	tq.mux.Lock()
 	d := timeout - tq.timeout
 	tq.timeout = timeout
 
 	if tq.head != empty {
-		for cur := tq.head; cur != empty; cur = tq.nodes[cur].next {
-			tq.nodes[cur].timeout = tq.nodes[cur].timeout.Add(d)
+		tq.shift += d
+		if tq.pinned > 0 && d != 0 {
+			tq.relinkPinned(d)
 		}
+		tq.publishNext()
 		if d < 0 {
-			tq.running++
-			go tq.run(tq.running)
+			tq.wakeRunner()
 		}
 	}
 
 	tq.mux.Unlock()
 }
 
+// SetTimeoutForNew changes the timeout duration of the queue without changing
+// the deadline of anything already in it, unlike SetTimeout. Only actions
+// added or reset after the call use the new timeout.
+func (tq *TimeoutQueue) SetTimeoutForNew(timeout time.Duration) {
+	tq.mux.Lock()
+	tq.timeout = timeout
+	tq.mux.Unlock()
+}
+
 // Flush calls the TimeoutAction on everything in the queue. Actions are not
 // called in Go routines so that when Flush returns all Actions are complete.
+// The queue is unlocked while each action is called, so the actions may call
+// methods on the queue and it's Tokens. Only as many actions are called as were
+// in the queue when Flush started, so an action that adds itself again is left
+// in the queue.
 func (tq *TimeoutQueue) Flush() {
 	tq.mux.Lock()
-	tq.running = ^uint16(0)
+	tq.flush(ReasonFlush)
+	tq.mux.Unlock()
+}
 
-	for {
-		if tq.head == empty {
-			break
+// flush requires a mux lock, which it releases while calling each action.
+func (tq *TimeoutQueue) flush(reason Reason) {
+	// the runner is left asleep, when it wakes it finds the queue empty or
+	// whatever the actions added
+	now := tq.clockNow()
+	for count := tq.pending; count > 0; count-- {
+		idx := tq.head
+		if idx == empty {
+			for p := range tq.paused {
+				idx = p
+				break
+			}
+			if idx == empty {
+				return
+			}
 		}
-		n := tq.nodes[tq.head]
-		tq.freeNode(tq.head)
-		n.action()
+		n, d := tq.nodeAt(idx), tq.dispatcher
+		tq.emit(EventFired, idx)
+		tq.freeNode(idx)
+		tq.nodes[idx].inflight++
+		tq.mux.Unlock()
+		d.call(n, now, reason)
+		tq.mux.Lock()
+		tq.land(idx)
 	}
+}
 
-	tq.running = 0
+// FlushWhere calls the TimeoutAction on everything in the queue for which
+// filter returns true; everything else remains scheduled. As with Flush, the
+// actions are not called in Go routines, may call methods on the queue and
+// paused actions are included, after the rest. The filter is called on
+// everything before any action is, with the queue locked, so it may not call
+// methods on the queue or its Tokens. An action that is canceled by an earlier
+// one is not called.
+func (tq *TimeoutQueue) FlushWhere(filter func(Token) bool) {
+	tq.mux.Lock()
+	var matched []Handle
+	for cur := tq.head; cur != empty; cur = tq.nodes[cur].next {
+		if t := tq.token(cur); filter(t) {
+			matched = append(matched, t)
+		}
+	}
+	for _, cur := range tq.pausedNodes() {
+		if t := tq.token(cur); filter(t) {
+			matched = append(matched, t)
+		}
+	}
+	now := tq.clockNow()
+	for _, t := range matched {
+		if int(t.nodeIdx) >= len(tq.nodes) {
+			continue
+		}
+		n := tq.nodeAt(t.nodeIdx)
+		if n.action == nil || n.actionID != t.actionID {
+			continue
+		}
+		d := tq.dispatcher
+		tq.emit(EventFired, t.nodeIdx)
+		tq.freeNode(t.nodeIdx)
+		tq.nodes[t.nodeIdx].inflight++
+		tq.mux.Unlock()
+		d.call(n, now, ReasonFlush)
+		tq.mux.Lock()
+		tq.land(t.nodeIdx)
+	}
 	tq.mux.Unlock()
-	return
 }
 
-type token struct {
+// CancelIf cancels everything in the queue for which filter returns true,
+// including paused actions, and returns the number of TimeoutActions canceled.
+// The filter is called with the queue locked, so it may not call methods on the
+// queue or its Tokens.
+func (tq *TimeoutQueue) CancelIf(filter func(Token) bool) int {
+	var canceled int
+	tq.mux.Lock()
+	for cur := tq.head; cur != empty; {
+		next := tq.nodes[cur].next
+		if filter(tq.token(cur)) {
+			tq.emit(EventCanceled, cur)
+			tq.freeNode(cur)
+			canceled++
+		}
+		cur = next
+	}
+	for _, cur := range tq.pausedNodes() {
+		if filter(tq.token(cur)) {
+			tq.emit(EventCanceled, cur)
+			tq.freeNode(cur)
+			canceled++
+		}
+	}
+	tq.mux.Unlock()
+	return canceled
+}
+
+// CancelID cancels everything in the queue with the correlation ID id,
+// including paused actions, and returns the number of TimeoutActions canceled.
+// The id must be comparable.
+func (tq *TimeoutQueue) CancelID(id interface{}) int {
+	var canceled int
+	tq.mux.Lock()
+	for cur := tq.head; cur != empty; {
+		next := tq.nodes[cur].next
+		if tq.nodes[cur].id == id {
+			tq.emit(EventCanceled, cur)
+			tq.freeNode(cur)
+			canceled++
+		}
+		cur = next
+	}
+	for cur := range tq.paused {
+		if tq.nodes[cur].id == id {
+			tq.emit(EventCanceled, cur)
+			tq.freeNode(cur)
+			canceled++
+		}
+	}
+	tq.mux.Unlock()
+	return canceled
+}
+
+// CountWhere returns the number of TimeoutActions in the queue for which filter
+// returns true, including paused actions, the same as Len. The filter is called
+// with the queue locked, so it may not call methods on the queue or its Tokens.
+func (tq *TimeoutQueue) CountWhere(filter func(Token) bool) int {
+	var count int
+	tq.mux.Lock()
+	for cur := tq.head; cur != empty; cur = tq.nodes[cur].next {
+		if filter(tq.token(cur)) {
+			count++
+		}
+	}
+	for _, cur := range tq.pausedNodes() {
+		if filter(tq.token(cur)) {
+			count++
+		}
+	}
+	tq.mux.Unlock()
+	return count
+}
+
+// OldestWhere returns the Token closest to timing out for which filter returns
+// true along with the time at which it will timeout. Paused actions never time
+// out, so they are not included. If nothing matches, the returned bool is
+// false. The filter is called with the queue locked, so it may not call methods
+// on the queue or its Tokens.
+func (tq *TimeoutQueue) OldestWhere(filter func(Token) bool) (Token, time.Time, bool) {
+	tq.mux.Lock()
+	defer tq.mux.Unlock()
+	for cur := tq.head; cur != empty; cur = tq.nodes[cur].next {
+		if t := tq.token(cur); filter(t) {
+			return t, tq.deadline(cur), true
+		}
+	}
+	return nil, time.Time{}, false
+}
+
+// token returns the Handle for the action currently held by the node.
+func (tq *TimeoutQueue) token(nodeIdx uint32) Handle {
+	return Handle{
+		tq:       tq,
+		nodeIdx:  nodeIdx,
+		actionID: tq.nodes[nodeIdx].actionID,
+	}
+}
+
+// Handle is the concrete Token returned by AddHandle. It is comparable and can
+// be stored by value. The zero value is not associated with any action; Cancel
+// and Reset on it return false.
+type Handle struct {
 	tq       *TimeoutQueue
 	nodeIdx  uint32
 	actionID uint32
 }
 
-func (t token) Cancel() bool {
+// Cancel fulfills Token.
+func (t Handle) Cancel() bool {
+	if t.tq == nil {
+		return false
+	}
 	t.tq.mux.Lock()
 	n := t.tq.nodes[t.nodeIdx]
 	remove := n.action != nil && n.actionID == t.actionID
 	if remove {
+		t.tq.emit(EventCanceled, t.nodeIdx)
 		t.tq.freeNode(t.nodeIdx)
 	}
 	t.tq.mux.Unlock()
 	return remove
 }
 
-func (t token) Reset() bool {
-	timeout := time.Now().Add(t.tq.timeout)
+// take cancels the action and returns it's correlation ID.
+func (t Handle) take() (interface{}, bool) {
+	if t.tq == nil {
+		return nil, false
+	}
+	t.tq.mux.Lock()
+	n := t.tq.nodes[t.nodeIdx]
+	ok := n.action != nil && n.actionID == t.actionID
+	if ok {
+		t.tq.emit(EventCanceled, t.nodeIdx)
+		t.tq.freeNode(t.nodeIdx)
+	}
+	t.tq.mux.Unlock()
+	return n.id, ok
+}
 
+// live reports whether the action is still in the queue.
+func (t Handle) live() bool {
+	if t.tq == nil {
+		return false
+	}
 	t.tq.mux.Lock()
+	n := t.tq.nodes[t.nodeIdx]
+	live := n.action != nil && n.actionID == t.actionID
+	t.tq.mux.Unlock()
+	return live
+}
+
+// Reset fulfills Token.
+func (t Handle) Reset() bool {
+	_, ok := t.reset(time.Time{})
+	return ok
+}
+
+// ResetRemaining is the same as Reset, but also returns how long the action had
+// remaining before it was reset. That is never less than zero, even if the
+// action was overdue and about to fire. For a paused action it is the duration
+// it had remaining when it was paused.
+func (t Handle) ResetRemaining() (time.Duration, bool) {
+	return t.reset(time.Time{})
+}
+
+// ResetIfBefore fulfills Token.
+func (t Handle) ResetIfBefore(before time.Time) bool {
+	_, ok := t.reset(before)
+	return ok
+}
 
+// reset the timeout, unless before is set and the deadline is not before it,
+// returning how long was remaining.
+func (t Handle) reset(before time.Time) (time.Duration, bool) {
+	if t.tq == nil {
+		return 0, false
+	}
+	t.tq.mux.Lock()
 	n := t.tq.nodes[t.nodeIdx]
 	if n.action == nil || n.actionID != t.actionID {
 		t.tq.mux.Unlock()
-		return false
+		return 0, false
+	}
+	now := t.tq.now()
+	if remaining, ok := t.tq.paused[t.nodeIdx]; ok {
+		// a paused node keeps it's place out of the queue
+		if !before.IsZero() && !now.Add(remaining).Before(before) {
+			t.tq.mux.Unlock()
+			return 0, false
+		}
+		t.tq.paused[t.nodeIdx] = t.tq.classTimeout(n.class)
+		t.tq.emit(EventReset, t.nodeIdx)
+		t.tq.mux.Unlock()
+		return remaining, true
+	}
+	deadline := t.tq.deadline(t.nodeIdx)
+	if !before.IsZero() && !deadline.Before(before) {
+		t.tq.mux.Unlock()
+		return 0, false
+	}
+	remaining := deadline.Sub(now)
+	if remaining < 0 {
+		remaining = 0
 	}
-	n.timeout = timeout
-
 	t.tq.remove(t.nodeIdx)
-
-	// add to end of list
-	n.next = empty
-	n.prev = t.tq.tail
-	t.tq.nodes[t.nodeIdx] = n
-	t.tq.add(t.nodeIdx)
+	t.tq.setDeadline(t.nodeIdx, t.tq.deadlineAfter(t.tq.classTimeout(n.class)))
+	t.tq.linkClass(t.nodeIdx)
+	t.tq.rearm()
+	t.tq.debugValidate()
+	t.tq.emit(EventReset, t.nodeIdx)
 
 	t.tq.mux.Unlock()
-	return true
+	return remaining, true
 }
 
-func (token) private() {}
+func (Handle) private() {}
 
 // Token represents a TimeoutAction that was registered.
 type Token interface {
@@ -255,4 +997,25 @@ type Token interface {
 	// TimeoutAction was either previously canceled or the TimeoutAction has
 	// already run.
 	Reset() bool
+	// ResetIfBefore is the same as Reset but only resets the timeout if the
+	// current deadline is before the given time, otherwise it returns false.
+	// Passing the time Reset would set the deadline to only ever extends it.
+	ResetIfBefore(time.Time) bool
+	// ResetRemaining is the same as Reset but also returns the duration that
+	// was remaining before the reset.
+	ResetRemaining() (time.Duration, bool)
+	// CancelAndWait is the same as Cancel, but if the TimeoutAction is running
+	// it waits for it to return. It must not be called from the TimeoutAction
+	// itself.
+	CancelAndWait() bool
+	// MoveTo removes the TimeoutAction from it's queue and adds it to another,
+	// see Handle.MoveTo.
+	MoveTo(other *TimeoutQueue) (Handle, bool)
+	// Pause takes the TimeoutAction out of the queue, keeping the duration it
+	// has remaining until Resume puts it back, see Handle.Pause.
+	Pause() bool
+	Resume() bool
+	// MarshalText encodes the Token so it can be stored and later bound to the
+	// queue again, see Handle.MarshalText and TimeoutQueue.Bind.
+	MarshalText() ([]byte, error)
 }
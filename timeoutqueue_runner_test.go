@@ -0,0 +1,288 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeout"
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddBatch(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
+	ch := make(chan int, 3)
+
+	handles := make([]timeoutqueue.Handle, 0, 3)
+	handles = tq.AddBatch(handles, getAction(ch, 1), getAction(ch, 2), getAction(ch, 3))
+	assert.Len(t, handles, 3)
+
+	_, first, _ := tq.OldestWhere(func(t timeoutqueue.Token) bool {
+		return t == handles[0]
+	})
+	_, last, _ := tq.OldestWhere(func(t timeoutqueue.Token) bool {
+		return t == handles[2]
+	})
+	assert.Equal(t, first, last)
+
+	assert.True(t, handles[1].Cancel())
+	assert.NoError(t, timeout.After(20, func() {
+		assert.Equal(t, 4, <-ch+<-ch)
+	}))
+	assert.Len(t, tq.AddBatch(nil), 0)
+}
+
+func TestAddTimed(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
+	ch := make(chan [2]time.Time)
+
+	added := time.Now()
+	tq.AddTimed(func(scheduled, fired time.Time) {
+		ch <- [2]time.Time{scheduled, fired}
+	})
+	assert.NoError(t, timeout.After(20, func() {
+		times := <-ch
+		assert.False(t, times[0].Before(added.Add(time.Millisecond*5)))
+		assert.False(t, times[1].Before(times[0]))
+	}))
+
+	tq.AddTimed(func(scheduled, fired time.Time) {
+		ch <- [2]time.Time{scheduled, fired}
+	})
+	go tq.Flush()
+	times := <-ch
+	assert.True(t, times[1].Before(times[0]))
+}
+
+func TestAddWithID(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
+	sub := tq.Subscribe(10)
+	defer sub.Close()
+	ch := make(chan interface{})
+
+	tq.AddWithID("request-1", func(id interface{}) {
+		ch <- id
+	})
+	assert.Equal(t, "request-1", (<-sub.C).ID)
+	assert.NoError(t, timeout.After(20, func() {
+		assert.Equal(t, "request-1", <-ch)
+	}))
+	assert.Equal(t, "request-1", (<-sub.C).ID)
+}
+
+func TestCancelIf(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
+	ch := make(chan int, 3)
+
+	canceled := map[timeoutqueue.Token]bool{
+		tq.Add(getAction(ch, 1)): true,
+		tq.Add(getAction(ch, 2)): false,
+		tq.Add(getAction(ch, 3)): true,
+	}
+
+	assert.Equal(t, 2, tq.CancelIf(func(t timeoutqueue.Token) bool {
+		return canceled[t]
+	}))
+	assert.NoError(t, timeout.After(10, func() {
+		assert.Equal(t, 2, <-ch)
+	}))
+	assert.Error(t, timeout.After(5, ch))
+}
+
+func TestCoarseClock(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
+	tq.SetCoarseClock(time.Second)
+	ch := make(chan int, 3)
+
+	tq.Add(getAction(ch, 1))
+	// the runner is active so these use the cached time
+	tkns := []timeoutqueue.Token{
+		tq.Add(getAction(ch, 2)),
+		tq.Add(getAction(ch, 3)),
+	}
+	deadline := func(tkn timeoutqueue.Token) time.Time {
+		_, d, ok := tq.OldestWhere(func(t timeoutqueue.Token) bool {
+			return t == tkn
+		})
+		assert.True(t, ok)
+		return d
+	}
+	assert.Equal(t, deadline(tkns[0]), deadline(tkns[1]))
+
+	assert.NoError(t, timeout.After(20, func() {
+		// deadlines are identical so the order cannot be guarenteed
+		sum := <-ch + <-ch + <-ch
+		assert.Equal(t, 6, sum)
+	}))
+}
+
+func TestDecreaseSetTimeout(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*10, 10)
+	ch := make(chan int)
+
+	tq.Add(getAction(ch, 0))
+	tq.Add(getAction(ch, 1))
+	tq.Add(getAction(ch, 2))
+
+	// make sure there is some delay
+	select {
+	case <-ch:
+		t.Error("too soon")
+	case <-time.After(time.Millisecond * 5):
+	}
+
+	// Should cause the entire queue to drain
+	tq.SetTimeout(time.Millisecond * 5)
+	assert.NoError(t, timeout.After(3, func() {
+		// Cannot guarentee the order that the values will come through
+		var expected [3]bool
+		expected[<-ch] = true
+		expected[<-ch] = true
+		expected[<-ch] = true
+		assert.True(t, expected[0])
+		assert.True(t, expected[1])
+		assert.True(t, expected[2])
+	}))
+
+	tq.Add(getAction(ch, 4))
+	assert.NoError(t, timeout.After(6, func() {
+		assert.Equal(t, 4, <-ch)
+	}))
+}
+
+func TestFlushWhere(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
+	ch := make(chan int, 3)
+
+	tq.Add(getAction(ch, 1))
+	keep := tq.Add(getAction(ch, 2))
+	tq.Add(getAction(ch, 3))
+
+	tq.FlushWhere(func(t timeoutqueue.Token) bool {
+		return t != keep
+	})
+	assert.Equal(t, 1, <-ch)
+	assert.Equal(t, 3, <-ch)
+
+	// the remaining action still times out normally
+	assert.NoError(t, timeout.After(20, func() {
+		assert.Equal(t, 2, <-ch)
+	}))
+	assert.False(t, keep.Cancel())
+}
+
+func TestIncreaseSetTimeout(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
+	ch := make(chan int)
+
+	tq.Add(getAction(ch, 1))
+	tq.Add(getAction(ch, 2))
+	tq.Add(getAction(ch, 3))
+
+	tq.SetTimeout(time.Millisecond * 10)
+
+	// make sure there is some delay
+	select {
+	case <-ch:
+		t.Error("too soon")
+	case <-time.After(time.Millisecond * 5):
+	}
+
+	assert.NoError(t, timeout.After(7, func() {
+		assert.Equal(t, 1, <-ch)
+	}))
+}
+
+func TestReset(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*6, 2)
+	ch := make(chan int)
+
+	tokens := []timeoutqueue.Token{
+		tq.Add(getAction(ch, 1)),
+		tq.Add(getAction(ch, 2)),
+		tq.Add(getAction(ch, 3)),
+	}
+
+	time.Sleep(time.Millisecond)
+	assert.True(t, tokens[1].Reset())
+	assert.NoError(t, timeout.After(10, func() {
+		assert.Equal(t, 1, <-ch)
+		assert.Equal(t, 3, <-ch)
+		assert.Equal(t, 2, <-ch)
+	}))
+
+	tokens = []timeoutqueue.Token{
+		tq.Add(getAction(ch, 1)),
+		tq.Add(getAction(ch, 2)),
+		tq.Add(getAction(ch, 3)),
+	}
+
+	time.Sleep(time.Millisecond)
+	assert.True(t, tokens[1].Reset())
+	assert.True(t, tokens[0].Reset())
+	assert.True(t, tokens[1].Cancel())
+	assert.False(t, tokens[1].Reset())
+	tq.Add(getAction(ch, 4))
+	assert.NoError(t, timeout.After(10, func() {
+		assert.Equal(t, 3, <-ch)
+		assert.Equal(t, 1, <-ch)
+		assert.Equal(t, 4, <-ch)
+	}))
+}
+
+func TestSetTimeoutForNewRunner(t *testing.T) {
+	tq := timeoutqueue.New(time.Second, 10)
+	ch := make(chan int, 2)
+	tq.Add(getAction(ch, 1))
+	// give the runner time to go to sleep for the first action
+	time.Sleep(time.Millisecond * 5)
+	tq.SetTimeoutForNew(time.Millisecond * 5)
+	tq.Add(getAction(ch, 2))
+
+	select {
+	case i := <-ch:
+		assert.Equal(t, 2, i)
+	case <-time.After(time.Millisecond * 200):
+		t.Error("runner did not wake for the shorter timeout")
+	}
+}
+
+func TestTimeoutQueue(t *testing.T) {
+	d := time.Millisecond * 5
+	tq := timeoutqueue.New(d, 10)
+
+	assert.Equal(t, d, tq.Timeout())
+
+	ch := make(chan int)
+	tq.Add(getAction(ch, 1))
+	assert.NoError(t, timeout.After(20, ch))
+
+	token1 := tq.Add(func() {
+		t.Error("This should be canceled")
+	})
+	token2 := tq.Add(getAction(ch, 2))
+	assert.True(t, token1.Cancel())
+	assert.NoError(t, timeout.After(20, ch))
+	assert.False(t, token2.Cancel())
+}
+
+func TestZeroTimeout(t *testing.T) {
+	tq := timeoutqueue.New(0, 10)
+	ch := make(chan int)
+
+	tkn := tq.Add(getAction(ch, 1))
+	assert.NoError(t, timeout.After(5, func() {
+		assert.Equal(t, 1, <-ch)
+	}))
+	assert.False(t, tkn.Cancel())
+	assert.False(t, tkn.Reset())
+
+	tq.SetTimeout(time.Millisecond * 50)
+	tq.Add(getAction(ch, 2))
+	tq.SetTimeout(-time.Millisecond)
+	assert.NoError(t, timeout.After(5, func() {
+		assert.Equal(t, 2, <-ch)
+	}))
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/dist-ribut-us/timeout"
 	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,136 +22,253 @@ func getAction(ch chan<- int, i int) func() {
 	}
 }
 
-func TestTimeoutQueue(t *testing.T) {
-	d := time.Millisecond * 5
-	tq := timeoutqueue.New(d, 10)
-
-	assert.Equal(t, d, tq.Timeout())
-
+func TestFlush(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
 	ch := make(chan int)
+
 	tq.Add(getAction(ch, 1))
-	assert.NoError(t, timeout.After(20, ch))
+	tq.Add(getAction(ch, 2))
+	tq.Add(getAction(ch, 3))
 
-	token1 := tq.Add(func() {
-		t.Error("This should be canceled")
-	})
-	token2 := tq.Add(getAction(ch, 2))
-	assert.True(t, token1.Cancel())
-	assert.NoError(t, timeout.After(20, ch))
-	assert.False(t, token2.Cancel())
+	done := make(chan bool)
+	go func() {
+		assert.Equal(t, 1, <-ch)
+		assert.Equal(t, 2, <-ch)
+		assert.Equal(t, 3, <-ch)
+		done <- true
+	}()
+
+	tq.Flush()
+	assert.NoError(t, timeout.After(1, done))
+	// make sure nothing else sends on ch
+	assert.Error(t, timeout.After(5, ch))
 }
 
-func TestReset(t *testing.T) {
-	tq := timeoutqueue.New(time.Millisecond*6, 2)
-	ch := make(chan int)
+func TestCancelID(t *testing.T) {
+	tq := timeoutqueue.NewManual(time.Second, 10, nil)
+	nop := func(interface{}) {}
+	tq.AddWithID("a", nop)
+	tq.AddWithID("b", nop)
+	tq.AddWithID("a", nop)
+	tq.AddWithID([]byte("a"), nop)
 
-	tokens := []timeoutqueue.Token{
-		tq.Add(getAction(ch, 1)),
-		tq.Add(getAction(ch, 2)),
-		tq.Add(getAction(ch, 3)),
-	}
+	assert.Equal(t, 2, tq.CancelID("a"))
+	assert.Equal(t, 0, tq.CancelID("a"))
+	assert.Equal(t, 2, tq.Len())
+}
 
-	time.Sleep(time.Millisecond)
-	assert.True(t, tokens[1].Reset())
-	assert.NoError(t, timeout.After(10, func() {
-		assert.Equal(t, 1, <-ch)
-		assert.Equal(t, 3, <-ch)
-		assert.Equal(t, 2, <-ch)
-	}))
+func TestCountWhere(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
+	action := func() {}
 
-	tokens = []timeoutqueue.Token{
-		tq.Add(getAction(ch, 1)),
-		tq.Add(getAction(ch, 2)),
-		tq.Add(getAction(ch, 3)),
+	handshakes := map[timeoutqueue.Token]bool{}
+	tq.Add(action)
+	handshakes[tq.Add(action)] = true
+	handshakes[tq.Add(action)] = true
+	isHandshake := func(t timeoutqueue.Token) bool {
+		return handshakes[t]
 	}
 
-	time.Sleep(time.Millisecond)
-	assert.True(t, tokens[1].Reset())
-	assert.True(t, tokens[0].Reset())
-	assert.True(t, tokens[1].Cancel())
-	assert.False(t, tokens[1].Reset())
-	tq.Add(getAction(ch, 4))
-	assert.NoError(t, timeout.After(10, func() {
-		assert.Equal(t, 3, <-ch)
-		assert.Equal(t, 1, <-ch)
-		assert.Equal(t, 4, <-ch)
-	}))
+	assert.Equal(t, 2, tq.CountWhere(isHandshake))
+	tkn, deadline, ok := tq.OldestWhere(isHandshake)
+	assert.True(t, ok)
+	assert.True(t, handshakes[tkn])
+	assert.True(t, deadline.After(time.Now()))
+
+	assert.True(t, tkn.Cancel())
+	assert.Equal(t, 1, tq.CountWhere(isHandshake))
+	next, _, ok := tq.OldestWhere(isHandshake)
+	assert.True(t, ok)
+	assert.NotEqual(t, tkn, next)
+
+	_, _, ok = tq.OldestWhere(func(timeoutqueue.Token) bool { return false })
+	assert.False(t, ok)
+	tq.Flush()
 }
 
-func TestDecreaseSetTimeout(t *testing.T) {
-	tq := timeoutqueue.New(time.Millisecond*10, 10)
-	ch := make(chan int)
+func TestCorrelationHook(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond*5, 10)
+	sub := tq.Subscribe(10)
+	defer sub.Close()
 
-	tq.Add(getAction(ch, 0))
-	tq.Add(getAction(ch, 1))
-	tq.Add(getAction(ch, 2))
+	tq.SetCorrelationHook(func() interface{} {
+		return 42
+	})
+	tq.Add(func() {}).Cancel()
+	assert.Equal(t, 42, (<-sub.C).ID)
+	assert.Equal(t, 42, (<-sub.C).ID)
 
-	// make sure there is some delay
-	select {
-	case <-ch:
-		t.Error("too soon")
-	case <-time.After(time.Millisecond * 5):
-	}
+	tq.SetCorrelationHook(nil)
+	tq.Add(func() {}).Cancel()
+	assert.Nil(t, (<-sub.C).ID)
+}
 
-	// Should cause the entire queue to drain
-	tq.SetTimeout(time.Millisecond * 5)
-	assert.NoError(t, timeout.After(3, func() {
-		// Cannot guarentee the order that the values will come through
-		var expected [3]bool
-		expected[<-ch] = true
-		expected[<-ch] = true
-		expected[<-ch] = true
-		assert.True(t, expected[0])
-		assert.True(t, expected[1])
-		assert.True(t, expected[2])
-	}))
+func TestAddHandle(t *testing.T) {
+	tq := timeoutqueue.New(time.Second, 10)
+	action := func() {}
 
-	tq.Add(getAction(ch, 4))
-	assert.NoError(t, timeout.After(6, func() {
-		assert.Equal(t, 4, <-ch)
-	}))
+	// warm up the queue so the internal slice doesn't grow
+	tq.AddHandle(action).Cancel()
+	allocs := testing.AllocsPerRun(100, func() {
+		h := tq.AddHandle(action)
+		h.Reset()
+		h.Cancel()
+	})
+	assert.Equal(t, 0.0, allocs)
+
+	h := tq.AddHandle(action)
+	var tkn timeoutqueue.Token = h
+	assert.True(t, tkn.Cancel())
+	assert.False(t, h.Cancel())
+
+	var zero timeoutqueue.Handle
+	assert.False(t, zero.Cancel())
+	assert.False(t, zero.Reset())
 }
 
-func TestIncreaseSetTimeout(t *testing.T) {
-	tq := timeoutqueue.New(time.Millisecond*5, 10)
-	ch := make(chan int)
+func TestNewChecked(t *testing.T) {
+	tq, err := timeoutqueue.NewChecked(time.Millisecond, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Millisecond, tq.Timeout())
 
-	tq.Add(getAction(ch, 1))
-	tq.Add(getAction(ch, 2))
-	tq.Add(getAction(ch, 3))
+	_, err = timeoutqueue.NewChecked(-time.Millisecond, 10)
+	assert.Equal(t, timeoutqueue.ErrNegativeTimeout, err)
+	_, err = timeoutqueue.NewChecked(time.Millisecond, -1)
+	assert.Equal(t, timeoutqueue.ErrNegativeCapacity, err)
+
+	// New adjusts a negative capacity rather than panicking
+	tq = timeoutqueue.New(time.Millisecond, -1)
+	assert.True(t, tq.Add(func() {}).Cancel())
+}
+
+func TestSetTimeoutForNew(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	tq.SetExecutor(timeoutqueue.Inline)
 
-	tq.SetTimeout(time.Millisecond * 10)
+	var fired []int
+	tq.Add(func() { fired = append(fired, 1) })
+	tq.SetTimeoutForNew(time.Millisecond * 100)
+	assert.Equal(t, time.Millisecond*100, tq.Timeout())
+	tq.Add(func() { fired = append(fired, 2) })
+	tkn := tq.Add(func() { fired = append(fired, 3) })
+	tq.SetTimeoutForNew(time.Millisecond * 500)
+	tkn.Reset()
+	assert.NoError(t, tq.Validate())
 
-	// make sure there is some delay
-	select {
-	case <-ch:
-		t.Error("too soon")
-	case <-time.After(time.Millisecond * 5):
+	clock.Advance(time.Millisecond * 100)
+	assert.Equal(t, 1, tq.Tick())
+	clock.Advance(time.Millisecond * 400)
+	assert.Equal(t, 1, tq.Tick())
+	clock.Advance(time.Millisecond * 500)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, []int{2, 3, 1}, fired)
+}
+
+func TestSetTimeoutShift(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	tq.SetExecutor(timeoutqueue.Inline)
+
+	var fired []int
+	var deadlines []time.Time
+	tq.AddTimed(func(deadline, _ time.Time) {
+		fired = append(fired, 1)
+		deadlines = append(deadlines, deadline)
+	})
+	tq.AddPinned(func() { fired = append(fired, 2) })
+	tq.SetTimeout(time.Millisecond * 200)
+	tq.Add(func() { fired = append(fired, 3) })
+	tq.SetTimeout(time.Millisecond * 400)
+	assert.NoError(t, tq.Validate())
+
+	entries := tq.Export()
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, time.Millisecond*400, entries[0].Remaining)
+		assert.Equal(t, time.Millisecond*400, entries[1].Remaining)
+		assert.Equal(t, time.Second, entries[2].Remaining)
 	}
+	start := clock.Now()
+	clock.Advance(time.Millisecond * 400)
+	assert.Equal(t, 2, tq.Tick())
+	clock.Advance(time.Millisecond * 600)
+	assert.Equal(t, 1, tq.Tick())
+	assert.Equal(t, []int{1, 3, 2}, fired)
+	assert.Equal(t, []time.Time{start.Add(time.Millisecond * 400)}, deadlines)
+}
 
-	assert.NoError(t, timeout.After(7, func() {
-		assert.Equal(t, 1, <-ch)
-	}))
+func TestResetIfBefore(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	start := clock.Now()
+	tkn := tq.Add(func() {})
+
+	clock.Advance(time.Millisecond * 500)
+	// the deadline is start+1s, which is not before itself
+	assert.False(t, tkn.ResetIfBefore(start.Add(time.Second)))
+	assert.True(t, tkn.ResetIfBefore(clock.Now().Add(time.Second)))
+
+	// the deadline is now 1.5s after start
+	clock.Advance(time.Millisecond * 999)
+	assert.Equal(t, 0, tq.Tick())
+	assert.True(t, tkn.Cancel())
+	assert.False(t, tkn.ResetIfBefore(clock.Now().Add(time.Hour)))
 }
 
-func TestFlush(t *testing.T) {
-	tq := timeoutqueue.New(time.Millisecond*5, 10)
-	ch := make(chan int)
+func TestResetRemaining(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	tkn := tq.Add(func() {})
 
-	tq.Add(getAction(ch, 1))
-	tq.Add(getAction(ch, 2))
-	tq.Add(getAction(ch, 3))
+	clock.Advance(time.Millisecond * 300)
+	remaining, ok := tkn.ResetRemaining()
+	assert.True(t, ok)
+	assert.Equal(t, time.Millisecond*700, remaining)
 
-	done := make(chan bool)
-	go func() {
-		assert.Equal(t, 1, <-ch)
-		assert.Equal(t, 2, <-ch)
-		assert.Equal(t, 3, <-ch)
-		done <- true
-	}()
+	clock.Advance(time.Millisecond * 1200)
+	remaining, ok = tkn.ResetRemaining()
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), remaining)
 
+	tkn.Cancel()
+	_, ok = tkn.ResetRemaining()
+	assert.False(t, ok)
+}
+
+func TestSetCapacity(t *testing.T) {
+	tq := timeoutqueue.New(time.Second, 2)
+	tq.Add(func() {})
+	assert.Equal(t, 2, tq.Cap())
+	assert.Equal(t, timeoutqueue.ErrNegativeCapacity, tq.SetCapacity(-1))
+	assert.NoError(t, tq.SetCapacity(1))
+	assert.Equal(t, 2, tq.Cap())
+
+	assert.NoError(t, tq.SetCapacity(100))
+	assert.Equal(t, 100, tq.Cap())
+	assert.Equal(t, 1, tq.Len())
+	grew := tq.Stats().Grew
+	for i := 0; i < 99; i++ {
+		tq.Add(func() {})
+	}
+	assert.Equal(t, grew, tq.Stats().Grew)
+	assert.NoError(t, tq.Validate())
 	tq.Flush()
-	assert.NoError(t, timeout.After(1, done))
-	// make sure nothing else sends on ch
-	assert.Error(t, timeout.After(5, ch))
+}
+
+func TestAddInto(t *testing.T) {
+	tq := timeoutqueue.NewManual(time.Second, 2, nil)
+	other := timeoutqueue.NewManual(time.Second, 2, nil)
+	action := func() {}
+	var h timeoutqueue.Handle
+	other.AddInto(&h, action)
+	assert.Equal(t, 1, other.Len())
+
+	tq.AddInto(&h, action)
+	assert.Equal(t, 0, other.Len())
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		tq.AddInto(&h, action)
+	}))
+	assert.Equal(t, 1, tq.Len())
+	assert.True(t, h.Cancel())
+	assert.NoError(t, tq.Validate())
 }
@@ -0,0 +1,73 @@
+package timeoutqueuetest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+)
+
+// FireCounter counts the TimeoutActions that fire on a queue, so a test can wait
+// for the queue to make progress instead of sleeping. An action is counted once
+// it has returned.
+type FireCounter struct {
+	mux    sync.Mutex
+	cond   *sync.Cond
+	n      int
+	remove func()
+}
+
+// CountFires returns a FireCounter for tq, counting from now. It works with any
+// TimeoutQueue, not just a Queue from this package.
+func CountFires(tq *timeoutqueue.TimeoutQueue) *FireCounter {
+	fc := &FireCounter{}
+	fc.cond = sync.NewCond(&fc.mux)
+	fc.remove = tq.AddFireListener(func(interface{}, time.Time, time.Time, timeoutqueue.Reason) {
+		fc.mux.Lock()
+		fc.n++
+		fc.cond.Broadcast()
+		fc.mux.Unlock()
+	})
+	return fc
+}
+
+// Count returns the number of actions that have fired.
+func (fc *FireCounter) Count() int {
+	fc.mux.Lock()
+	n := fc.n
+	fc.mux.Unlock()
+	return n
+}
+
+// WaitForFires blocks until at least n actions have fired since the
+// FireCounter was created or the timeout elapses. It returns false on timeout.
+func (fc *FireCounter) WaitForFires(n int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	t := time.AfterFunc(timeout, func() {
+		fc.mux.Lock()
+		fc.cond.Broadcast()
+		fc.mux.Unlock()
+	})
+	defer t.Stop()
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+	for fc.n < n && time.Now().Before(deadline) {
+		fc.cond.Wait()
+	}
+	return fc.n >= n
+}
+
+// Stop counting.
+func (fc *FireCounter) Stop() {
+	fc.remove()
+}
+
+// WaitForFires blocks until n actions have fired on tq or the timeout elapses
+// and returns false on timeout. Only actions that fire after it is called are
+// counted; to also count actions that fire before, such as while the test is
+// still adding, call CountFires first.
+func WaitForFires(tq *timeoutqueue.TimeoutQueue, n int, timeout time.Duration) bool {
+	fc := CountFires(tq)
+	defer fc.Stop()
+	return fc.WaitForFires(n, timeout)
+}
@@ -0,0 +1,28 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueuetest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForFires(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond, 10)
+	fc := timeoutqueuetest.CountFires(tq)
+	for i := 0; i < 3; i++ {
+		tq.Add(func() {})
+	}
+	assert.True(t, fc.WaitForFires(3, time.Second))
+	assert.False(t, fc.WaitForFires(4, time.Millisecond*10))
+	assert.Equal(t, 3, fc.Count())
+	fc.Stop()
+
+	tq.SetTimeout(time.Hour)
+	tq.Add(func() {})
+	assert.False(t, timeoutqueuetest.WaitForFires(tq, 1, time.Millisecond*10))
+}
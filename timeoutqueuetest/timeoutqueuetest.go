@@ -0,0 +1,119 @@
+// Package timeoutqueuetest provides a deterministic TimeoutQueue for tests. The
+// queue's clock only moves when the test moves it and TimeoutActions are called
+// synchronously, so tests do not need to rely on time.Sleep.
+package timeoutqueuetest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+)
+
+// Clock is a timeoutqueue.Clock that only changes when it is told to.
+type Clock struct {
+	mux sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a Clock set to now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{
+		now: now,
+	}
+}
+
+// Now fulfills timeoutqueue.Clock.
+func (c *Clock) Now() time.Time {
+	c.mux.Lock()
+	now := c.now
+	c.mux.Unlock()
+	return now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mux.Lock()
+	c.now = c.now.Add(d)
+	c.mux.Unlock()
+}
+
+// Set the clock to now.
+func (c *Clock) Set(now time.Time) {
+	c.mux.Lock()
+	c.now = now
+	c.mux.Unlock()
+}
+
+// Queue wraps a manual TimeoutQueue with a Clock. TimeoutActions are called
+// synchronously from Sweep and Advance and the order they fire in is recorded.
+type Queue struct {
+	*timeoutqueue.TimeoutQueue
+	Clock *Clock
+
+	mux   sync.Mutex
+	fired []timeoutqueue.Event
+}
+
+// New returns a Queue with the given timeout and capacity. It's Clock starts at
+// the current time.
+func New(timeout time.Duration, capacity int) *Queue {
+	clock := NewClock(time.Now())
+	q := &Queue{
+		TimeoutQueue: timeoutqueue.NewManual(timeout, capacity, clock),
+		Clock:        clock,
+	}
+	q.SetExecutor(timeoutqueue.Inline)
+	q.AddFireListener(q.record)
+	return q
+}
+
+func (q *Queue) record(id interface{}, deadline, fired time.Time, reason timeoutqueue.Reason) {
+	q.mux.Lock()
+	q.fired = append(q.fired, timeoutqueue.Event{
+		Type: timeoutqueue.EventFired,
+		ID:   id,
+		Time: fired,
+	})
+	q.mux.Unlock()
+}
+
+// Sweep calls every TimeoutAction whose deadline has passed and returns the
+// number called.
+func (q *Queue) Sweep() int {
+	return q.Tick()
+}
+
+// Advance moves the Clock forward by d then calls Sweep.
+func (q *Queue) Advance(d time.Duration) int {
+	q.Clock.Advance(d)
+	return q.Sweep()
+}
+
+// Fired returns the Fired Events, in order, since the last call to Fired. They
+// are recorded by a FireListener, which is not given the Token, so each Event
+// has the correlation ID and the time it fired but no Token.
+func (q *Queue) Fired() []timeoutqueue.Event {
+	q.mux.Lock()
+	fired := q.fired
+	q.fired = nil
+	q.mux.Unlock()
+	return fired
+}
+
+// TB is the part of testing.TB used by the Queue.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertPending reports an error to t if the number of TimeoutActions in the
+// queue is not expected.
+func (q *Queue) AssertPending(t TB, expected int) bool {
+	t.Helper()
+	if l := q.Len(); l != expected {
+		t.Errorf("expected %d pending, got %d", expected, l)
+		return false
+	}
+	return true
+}
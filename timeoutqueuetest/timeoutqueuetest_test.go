@@ -0,0 +1,59 @@
+package timeoutqueuetest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	var calls []int
+
+	q.AddWithID("a", func(id interface{}) { calls = append(calls, 1) })
+	q.Clock.Advance(time.Millisecond * 500)
+	b := q.AddWithID("b", func(id interface{}) { calls = append(calls, 2) })
+	q.AddWithID("c", func(id interface{}) { calls = append(calls, 3) })
+	q.AssertPending(t, 3)
+
+	assert.Equal(t, 0, q.Sweep())
+	assert.Equal(t, 1, q.Advance(time.Millisecond*500))
+	assert.Equal(t, []int{1}, calls)
+	q.AssertPending(t, 2)
+
+	assert.True(t, b.Reset())
+	assert.Equal(t, 1, q.Advance(time.Millisecond*500))
+	assert.Equal(t, []int{1, 3}, calls)
+	assert.Equal(t, 1, q.Advance(time.Millisecond*500))
+	assert.Equal(t, []int{1, 3, 2}, calls)
+	q.AssertPending(t, 0)
+
+	fired := q.Fired()
+	if assert.Len(t, fired, 3) {
+		assert.Equal(t, "a", fired[0].ID)
+		assert.Equal(t, "c", fired[1].ID)
+		assert.Equal(t, "b", fired[2].ID)
+		assert.Equal(t, q.Clock.Now(), fired[2].Time)
+	}
+	assert.Len(t, q.Fired(), 0)
+}
+
+type recorder struct {
+	errors int
+}
+
+func (r *recorder) Helper() {}
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors++
+}
+
+func TestAssertPending(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	r := &recorder{}
+	q.Add(func() {})
+	assert.True(t, q.AssertPending(r, 1))
+	assert.False(t, q.AssertPending(r, 0))
+	assert.Equal(t, 1, r.errors)
+}
@@ -0,0 +1,45 @@
+package timeoutqueue
+
+// TTLBuffer holds values for the queue's timeout. Values that are not taken in
+// time are handed to a drop callback, which suits buffers such as packet
+// reassembly where stale fragments must be discarded without a timer per
+// packet.
+type TTLBuffer struct {
+	tq     *TimeoutQueue
+	onDrop func(interface{})
+	drop   CorrelatedAction
+}
+
+// NewTTLBuffer returns a TTLBuffer on the queue. If onDrop is not nil it is
+// called with each value that expires.
+func (tq *TimeoutQueue) NewTTLBuffer(onDrop func(interface{})) *TTLBuffer {
+	b := &TTLBuffer{
+		tq:     tq,
+		onDrop: onDrop,
+	}
+	b.drop = b.dropped
+	return b
+}
+
+func (b *TTLBuffer) dropped(v interface{}) {
+	if b.onDrop != nil {
+		b.onDrop(v)
+	}
+}
+
+// Put a value in the buffer. The returned Handle is used to Take it back out.
+func (b *TTLBuffer) Put(v interface{}) Handle {
+	b.tq.mux.Lock()
+	return b.tq.addAction(b.drop, v)
+}
+
+// Take removes the value from the buffer. The returned bool is false if the
+// value was already taken or dropped.
+func (b *TTLBuffer) Take(h Handle) (interface{}, bool) {
+	return h.take()
+}
+
+// Touch extends the value's time in the buffer by resetting it's timeout.
+func (b *TTLBuffer) Touch(h Handle) bool {
+	return h.Reset()
+}
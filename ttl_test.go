@@ -0,0 +1,37 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLBuffer(t *testing.T) {
+	q := timeoutqueuetest.New(time.Second, 10)
+	var dropped [][]byte
+	b := q.NewTTLBuffer(func(v interface{}) {
+		dropped = append(dropped, v.([]byte))
+	})
+
+	first := b.Put([]byte("first"))
+	second := b.Put([]byte("second"))
+	q.Advance(time.Millisecond * 500)
+	third := b.Put([]byte("third"))
+
+	v, ok := b.Take(first)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("first"), v)
+	_, ok = b.Take(first)
+	assert.False(t, ok)
+	assert.True(t, b.Touch(third))
+
+	q.Advance(time.Millisecond * 600)
+	assert.Equal(t, [][]byte{[]byte("second")}, dropped)
+	_, ok = b.Take(second)
+	assert.False(t, ok)
+
+	q.Advance(time.Second)
+	assert.Equal(t, [][]byte{[]byte("second"), []byte("third")}, dropped)
+}
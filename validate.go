@@ -0,0 +1,99 @@
+package timeoutqueue
+
+import (
+	"fmt"
+)
+
+// Validate checks the integrity of the queue's internal linked lists. It
+// returns an error describing the first problem found or nil if the queue is
+// consistent. It is intended for tests and debugging; building with the
+// timeoutqueuedebug tag runs the same checks after every mutation and panics if
+// they fail.
+func (tq *TimeoutQueue) Validate() error {
+	tq.mux.Lock()
+	err := tq.validate()
+	tq.mux.Unlock()
+	return err
+}
+
+// validate requires a mux lock.
+func (tq *TimeoutQueue) validate() error {
+	ln := uint32(len(tq.nodes))
+	if (tq.head == empty) != (tq.tail == empty) {
+		return fmt.Errorf("timeoutqueue: head is %d but tail is %d", tq.head, tq.tail)
+	}
+
+	inUse := make([]bool, ln)
+	var used int
+	prev := empty
+	for cur := tq.head; cur != empty; cur = tq.nodes[cur].next {
+		if cur >= ln {
+			return fmt.Errorf("timeoutqueue: node %d out of range", cur)
+		}
+		if inUse[cur] {
+			return fmt.Errorf("timeoutqueue: cycle at node %d", cur)
+		}
+		inUse[cur] = true
+		n := tq.nodes[cur]
+		if n.prev != prev {
+			return fmt.Errorf("timeoutqueue: node %d prev is %d, expected %d", cur, n.prev, prev)
+		}
+		if n.action == nil {
+			return fmt.Errorf("timeoutqueue: node %d in use has no action", cur)
+		}
+		if prev != empty && tq.nodes[prev].timeout.After(n.timeout) {
+			return fmt.Errorf("timeoutqueue: node %d times out before the node ahead of it", cur)
+		}
+		prev = cur
+		used++
+	}
+	if prev != tq.tail {
+		return fmt.Errorf("timeoutqueue: list ends at %d but tail is %d", prev, tq.tail)
+	}
+	for idx := range tq.paused {
+		if idx >= ln {
+			return fmt.Errorf("timeoutqueue: paused node %d out of range", idx)
+		}
+		if inUse[idx] {
+			return fmt.Errorf("timeoutqueue: node %d is both paused and in the list", idx)
+		}
+		if tq.nodes[idx].action == nil {
+			return fmt.Errorf("timeoutqueue: paused node %d has no action", idx)
+		}
+		inUse[idx] = true
+		used++
+	}
+	if used != tq.pending {
+		return fmt.Errorf("timeoutqueue: %d nodes in use but pending is %d", used, tq.pending)
+	}
+
+	free := 0
+	for cur := tq.free; cur != empty; cur = tq.nodes[cur].next {
+		if cur >= ln {
+			return fmt.Errorf("timeoutqueue: free node %d out of range", cur)
+		}
+		if inUse[cur] {
+			return fmt.Errorf("timeoutqueue: node %d is both in use and free", cur)
+		}
+		inUse[cur] = true
+		if tq.nodes[cur].action != nil {
+			return fmt.Errorf("timeoutqueue: free node %d has an action", cur)
+		}
+		free++
+	}
+	if used+free != len(tq.nodes) {
+		return fmt.Errorf("timeoutqueue: %d nodes are neither in use nor free", len(tq.nodes)-used-free)
+	}
+	return nil
+}
+
+// debugValidate requires a mux lock. It is a no-op unless built with the
+// timeoutqueuedebug tag.
+func (tq *TimeoutQueue) debugValidate() {
+	if !debug {
+		return
+	}
+	if err := tq.validate(); err != nil {
+		panic(err)
+	}
+}
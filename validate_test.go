@@ -0,0 +1,46 @@
+package timeoutqueue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tq := New(time.Second, 10)
+	action := func() {}
+	assert.NoError(t, tq.Validate())
+
+	tkns := []Token{tq.Add(action), tq.Add(action), tq.Add(action)}
+	assert.NoError(t, tq.Validate())
+	tkns[1].Reset()
+	assert.NoError(t, tq.Validate())
+	tkns[2].Cancel()
+	assert.NoError(t, tq.Validate())
+
+	tq.nodes[tq.tail].prev = empty
+	assert.Error(t, tq.Validate())
+	tq.nodes[tq.tail].prev = tq.head
+
+	tq.nodes[tq.free].action = TimeoutAction(action)
+	assert.Error(t, tq.Validate())
+	tq.nodes[tq.free].action = nil
+
+	tq.pending++
+	assert.Error(t, tq.Validate())
+	tq.pending--
+
+	tq.nodes[tq.head].timeout = tq.nodes[tq.tail].timeout.Add(time.Second)
+	assert.Error(t, tq.Validate())
+	tq.nodes[tq.head].timeout = tq.nodes[tq.tail].timeout
+	assert.NoError(t, tq.Validate())
+
+	free := tq.free
+	tq.free = tq.head
+	assert.Error(t, tq.Validate())
+	// leave the queue valid so the runner does not trip over it in a debug build
+	tq.free = free
+	assert.NoError(t, tq.Validate())
+	tq.Flush()
+}
@@ -0,0 +1,34 @@
+package timeoutqueue
+
+import (
+	"sync"
+)
+
+// CancelAndWait fulfills Token. When Cancel returns false the action may be
+// running in another Go routine; CancelAndWait then blocks until it returns, so
+// it is safe to free anything the action uses. That includes actions called by
+// Flush, FlushWhere or Close from another Go routine. An action must not call it
+// on it's own Token. If a newer action reusing the same slot in the queue is also
+// running, CancelAndWait may wait for that as well.
+func (t Handle) CancelAndWait() bool {
+	if t.tq == nil {
+		return false
+	}
+	tq := t.tq
+	tq.mux.Lock()
+	n := tq.nodes[t.nodeIdx]
+	if n.action != nil && n.actionID == t.actionID {
+		tq.emit(EventCanceled, t.nodeIdx)
+		tq.freeNode(t.nodeIdx)
+		tq.mux.Unlock()
+		return true
+	}
+	if tq.landing == nil {
+		tq.landing = sync.NewCond(&tq.mux)
+	}
+	for int(t.nodeIdx) < len(tq.nodes) && tq.nodes[t.nodeIdx].inflight > 0 {
+		tq.landing.Wait()
+	}
+	tq.mux.Unlock()
+	return false
+}
@@ -0,0 +1,33 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCancelAndWait(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond, 10)
+	started := make(chan bool)
+	var done int32
+	tkn := tq.Add(func() {
+		close(started)
+		time.Sleep(time.Millisecond * 20)
+		atomic.StoreInt32(&done, 1)
+	})
+	<-started
+	assert.False(t, tkn.CancelAndWait())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&done))
+
+	// once the action has returned it does not block
+	assert.False(t, tkn.CancelAndWait())
+
+	tkn = tq.Add(func() {})
+	tq.SetTimeout(time.Hour)
+	assert.True(t, tkn.CancelAndWait())
+}
@@ -0,0 +1,146 @@
+package timeoutqueue
+
+import (
+	"sync"
+	"time"
+)
+
+// Watchdog calls an action if it is not petted in time. It is a thin layer over
+// Token.Reset that also keeps track of how close it came to firing.
+type Watchdog struct {
+	mux    sync.Mutex
+	tq     *TimeoutQueue
+	action TimeoutAction
+	handle Handle
+	// gen counts the timeouts added by arm, live is set while the latest has
+	// not fired
+	gen      uint64
+	live     bool
+	lastPet  time.Time
+	nearMiss time.Duration
+	stopped  bool
+	stats    WatchdogStats
+}
+
+// WatchdogStats describe the history of a Watchdog. A near miss is a Pet that
+// came with less than the near miss margin remaining, see SetNearMiss.
+type WatchdogStats struct {
+	Pets       uint64
+	Fires      uint64
+	NearMisses uint64
+	// MinMargin is the least time that was remaining at any Pet.
+	MinMargin time.Duration
+}
+
+// NewWatchdog returns a Watchdog on it's own queue that calls action if Pet is
+// not called within timeout.
+func NewWatchdog(timeout time.Duration, action TimeoutAction) *Watchdog {
+	return New(timeout, 1).NewWatchdog(action)
+}
+
+// NewWatchdog returns a Watchdog that shares the queue and it's timeout.
+func (tq *TimeoutQueue) NewWatchdog(action TimeoutAction) *Watchdog {
+	w := &Watchdog{
+		tq:       tq,
+		action:   action,
+		nearMiss: tq.Timeout() / 10,
+		stats: WatchdogStats{
+			MinMargin: tq.Timeout(),
+		},
+	}
+	w.arm()
+	return w
+}
+
+// arm adds a new timeout to the queue. It must be called without the Watchdog's
+// mux lock as the action may be called from within Add.
+func (w *Watchdog) arm() {
+	w.mux.Lock()
+	w.gen++
+	gen := w.gen
+	w.live = true
+	w.lastPet = time.Now()
+	w.mux.Unlock()
+	h := w.tq.AddHandle(func() { w.fire(gen) })
+	w.mux.Lock()
+	if gen == w.gen && !w.stopped {
+		w.handle = h
+	} else {
+		h.Cancel()
+	}
+	w.mux.Unlock()
+}
+
+// fire is called when the timeout added by arm with the given gen fires. Only
+// the latest timeout clears the handle, so one that fires as Pet arms another
+// does not lose it.
+func (w *Watchdog) fire(gen uint64) {
+	w.mux.Lock()
+	if w.stopped {
+		w.mux.Unlock()
+		return
+	}
+	if gen == w.gen {
+		w.handle = Handle{}
+		w.live = false
+	}
+	w.stats.Fires++
+	w.mux.Unlock()
+	w.action()
+}
+
+// Pet the Watchdog, restarting it's timeout. If the Watchdog had already fired
+// it is armed again and false is returned. After Stop, Pet does nothing and
+// returns false.
+func (w *Watchdog) Pet() bool {
+	w.mux.Lock()
+	if w.stopped {
+		w.mux.Unlock()
+		return false
+	}
+	if !w.handle.Reset() {
+		w.mux.Unlock()
+		w.arm()
+		return false
+	}
+	now := time.Now()
+	margin := w.tq.Timeout() - now.Sub(w.lastPet)
+	w.lastPet = now
+	w.stats.Pets++
+	if margin < w.stats.MinMargin {
+		w.stats.MinMargin = margin
+	}
+	if margin < w.nearMiss {
+		w.stats.NearMisses++
+	}
+	w.mux.Unlock()
+	return true
+}
+
+// Stop the Watchdog. The returned bool indicates if it was stopped before it
+// fired.
+func (w *Watchdog) Stop() bool {
+	w.mux.Lock()
+	stopped := w.live && !w.stopped
+	w.stopped = true
+	w.live = false
+	w.handle.Cancel()
+	w.mux.Unlock()
+	return stopped
+}
+
+// SetNearMiss sets the margin below which a Pet counts as a near miss. The
+// default is a tenth of the timeout.
+func (w *Watchdog) SetNearMiss(margin time.Duration) {
+	w.mux.Lock()
+	w.nearMiss = margin
+	w.mux.Unlock()
+}
+
+// Stats returns the Watchdog's history.
+func (w *Watchdog) Stats() WatchdogStats {
+	w.mux.Lock()
+	s := w.stats
+	w.mux.Unlock()
+	return s
+}
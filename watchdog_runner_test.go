@@ -0,0 +1,38 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeout"
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchdog(t *testing.T) {
+	ch := make(chan bool, 1)
+	w := timeoutqueue.NewWatchdog(time.Millisecond*10, func() {
+		ch <- true
+	})
+	w.SetNearMiss(time.Millisecond * 8)
+
+	for i := 0; i < 3; i++ {
+		time.Sleep(time.Millisecond * 3)
+		assert.True(t, w.Pet())
+	}
+	s := w.Stats()
+	assert.Equal(t, uint64(3), s.Pets)
+	assert.Equal(t, uint64(3), s.NearMisses)
+	assert.True(t, s.MinMargin <= time.Millisecond*7)
+
+	assert.NoError(t, timeout.After(30, ch))
+	assert.Equal(t, uint64(1), w.Stats().Fires)
+
+	// petting after it fires arms it again
+	assert.False(t, w.Pet())
+	assert.True(t, w.Stop())
+	assert.False(t, w.Pet())
+	assert.False(t, w.Stop())
+}
@@ -0,0 +1,60 @@
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/dist-ribut-us/timeoutqueue/timeoutqueuetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchdogPetWhileFiring(t *testing.T) {
+	clock := timeoutqueuetest.NewClock(time.Now())
+	tq := timeoutqueue.NewManual(time.Second, 10, clock)
+	// hold the fired action so Pet can race it
+	var held []func()
+	tq.SetExecutor(timeoutqueue.ExecutorFunc(func(action func()) {
+		held = append(held, action)
+	}))
+	fired := 0
+	w := tq.NewWatchdog(func() { fired++ })
+	clock.Advance(time.Second)
+	assert.Equal(t, 1, tq.Tick())
+
+	assert.False(t, w.Pet())
+	for _, action := range held {
+		action()
+	}
+	assert.Equal(t, 1, fired)
+	assert.True(t, w.Stop())
+	assert.Equal(t, 0, tq.Len())
+
+	// an action that fires after Stop is not called
+	held = nil
+	w = tq.NewWatchdog(func() { fired++ })
+	clock.Advance(time.Second)
+	assert.Equal(t, 1, tq.Tick())
+	assert.True(t, w.Stop())
+	for _, action := range held {
+		action()
+	}
+	assert.Equal(t, 1, fired)
+}
+
+func TestWatchdogImmediate(t *testing.T) {
+	// with no timeout the action is called from within Add
+	tq := timeoutqueue.New(0, 1)
+	tq.SetExecutor(timeoutqueue.Inline)
+	var w *timeoutqueue.Watchdog
+	var fires uint64
+	w = tq.NewWatchdog(func() {
+		if w != nil {
+			fires = w.Stats().Fires
+		}
+	})
+	assert.Equal(t, uint64(1), w.Stats().Fires)
+	assert.False(t, w.Pet())
+	assert.Equal(t, uint64(2), fires)
+	assert.False(t, w.Stop())
+}
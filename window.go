@@ -0,0 +1,47 @@
+package timeoutqueue
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WindowCounter counts events over a sliding window. Each increment is undone
+// by the queue once the window has passed. Nodes are reused and the decrements
+// run Inline, so once the queue has grown to it's working size counting does
+// not allocate.
+type WindowCounter struct {
+	count int64
+	tq    *TimeoutQueue
+	dec   TimeoutAction
+}
+
+// NewWindowCounter returns a WindowCounter over the window. The capacity is the
+// expected number of increments within one window.
+func NewWindowCounter(window time.Duration, capacity int) *WindowCounter {
+	w := &WindowCounter{
+		tq: New(window, capacity),
+	}
+	w.tq.SetExecutor(Inline)
+	w.dec = w.decrement
+	return w
+}
+
+func (w *WindowCounter) decrement() {
+	atomic.AddInt64(&w.count, -1)
+}
+
+// Inc counts one event.
+func (w *WindowCounter) Inc() {
+	atomic.AddInt64(&w.count, 1)
+	w.tq.AddHandle(w.dec)
+}
+
+// Count returns the number of events within the window.
+func (w *WindowCounter) Count() int {
+	return int(atomic.LoadInt64(&w.count))
+}
+
+// Window returns the duration of the window.
+func (w *WindowCounter) Window() time.Duration {
+	return w.tq.Timeout()
+}
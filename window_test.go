@@ -0,0 +1,45 @@
+//go:build !timeoutqueuesingle
+
+package timeoutqueue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dist-ribut-us/timeoutqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowCounter(t *testing.T) {
+	w := timeoutqueue.NewWindowCounter(time.Millisecond*40, 10)
+	assert.Equal(t, time.Millisecond*40, w.Window())
+
+	w.Inc()
+	w.Inc()
+	assert.Equal(t, 2, w.Count())
+	time.Sleep(time.Millisecond * 20)
+	w.Inc()
+	assert.Equal(t, 3, w.Count())
+
+	time.Sleep(time.Millisecond * 30)
+	assert.Equal(t, 1, w.Count())
+	time.Sleep(time.Millisecond * 30)
+	assert.Equal(t, 0, w.Count())
+
+	allocs := testing.AllocsPerRun(100, w.Inc)
+	assert.Equal(t, 0.0, allocs)
+}
+
+func TestInline(t *testing.T) {
+	tq := timeoutqueue.New(time.Millisecond, 10)
+	tq.SetExecutor(timeoutqueue.Inline)
+	done := make(chan bool, 1)
+	tq.Add(func() {
+		done <- true
+	})
+	select {
+	case <-done:
+	case <-time.After(time.Millisecond * 20):
+		t.Error("action did not run")
+	}
+}